@@ -2,20 +2,47 @@ package app
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"comments-system/internal/config"
+	"comments-system/internal/domain"
+	"comments-system/internal/events"
+	auth_h "comments-system/internal/http-server/handler/auth"
 	comments_h "comments-system/internal/http-server/handler/comments"
+	live_h "comments-system/internal/http-server/handler/live"
+	notifications_h "comments-system/internal/http-server/handler/notifications"
+	sites_h "comments-system/internal/http-server/handler/sites"
+	webhooks_h "comments-system/internal/http-server/handler/webhooks"
+	"comments-system/internal/http-server/middleware"
 	"comments-system/internal/http-server/router"
+	"comments-system/internal/notifications"
+	"comments-system/internal/outbox"
+	"comments-system/internal/ratelimit"
 	comments_postgres "comments-system/internal/repository/comments/postgres"
+	notifications_postgres "comments-system/internal/repository/notifications/postgres"
+	outbox_postgres "comments-system/internal/repository/outbox/postgres"
+	sites_postgres "comments-system/internal/repository/sites/postgres"
+	spam_postgres "comments-system/internal/repository/spam/postgres"
+	users_postgres "comments-system/internal/repository/users/postgres"
+	webhooks_postgres "comments-system/internal/repository/webhooks/postgres"
+	"comments-system/internal/spam"
 	comments_uc "comments-system/internal/usecase/comments"
+	notifications_uc "comments-system/internal/usecase/notifications"
+	sites_uc "comments-system/internal/usecase/sites"
+	users_uc "comments-system/internal/usecase/users"
+	webhooks_uc "comments-system/internal/usecase/webhooks"
+	"comments-system/internal/webhooks"
 
+	"github.com/nats-io/nats.go"
 	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/redis"
 	"github.com/wb-go/wbf/zlog"
 )
 
@@ -24,6 +51,10 @@ type App struct {
 	server *http.Server
 	logger *zlog.Zerolog
 	db     *dbpg.DB
+	redis  *redis.Client
+	nats   *nats.Conn
+	// workers run in the background until the app stops.
+	workers []func(ctx context.Context)
 }
 
 func NewApp(cfg *config.Config, logger *zlog.Zerolog) (*App, error) {
@@ -40,17 +71,102 @@ func NewApp(cfg *config.Config, logger *zlog.Zerolog) (*App, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	commentsRepo := comments_postgres.NewCommentsRepository(db, retries)
+	commentsRepo := comments_postgres.NewCommentsRepository(db, retries, cfg.Search.Language)
+	sitesRepo := sites_postgres.NewSitesRepository(db, retries)
+	usersRepo := users_postgres.NewUsersRepository(db, retries)
+	spamRepo := spam_postgres.NewSpamRepository(db, retries)
+	webhooksRepo := webhooks_postgres.NewWebhooksRepository(db, retries)
+	notificationsRepo := notifications_postgres.NewNotificationsRepository(db, retries)
 
-	commentsUsecase := comments_uc.NewCommentsUsecase(commentsRepo, logger)
+	commentsPolicy := comments_uc.NewPolicy(cfg.Comments.EditWindow)
+	commentsFlags := comments_uc.FlagSettings{
+		Threshold: cfg.Comments.FlagThreshold,
+		Action:    comments_uc.FlagAction(cfg.Comments.FlagAction),
+	}
+	commentsTree := comments_uc.TreeLimits{
+		DefaultDepth:    cfg.Comments.DefaultMaxDepth,
+		DefaultChildren: cfg.Comments.DefaultMaxChildren,
+		MaxDepth:        cfg.Comments.MaxDepth,
+		MaxChildren:     cfg.Comments.MaxChildren,
+	}
+	limitStore, redisClient, err := newRateLimitStore(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	commentsLimits := comments_uc.RateLimits{
+		Store:     limitStore,
+		PerUser:   domain.RateLimit{Limit: cfg.RateLimit.PerUser, Window: cfg.RateLimit.Window},
+		PerThread: domain.RateLimit{Limit: cfg.RateLimit.PerThread, Window: cfg.RateLimit.Window},
+	}
+	commentsIPLimiter := ratelimit.NewIPLimiter(limitStore, "comments",
+		domain.RateLimit{Limit: cfg.RateLimit.PerIP, Window: cfg.RateLimit.Window}, cfg.RateLimit.TrustProxy)
+	flagsIPLimiter := ratelimit.NewIPLimiter(limitStore, "flags",
+		domain.RateLimit{Limit: cfg.RateLimit.FlagsPerIP, Window: cfg.RateLimit.Window}, cfg.RateLimit.TrustProxy)
 
-	commentsHandler := comments_h.NewCommentsHandler(commentsUsecase, logger)
+	// Events are stored by the repositories along with the changes. The relay
+	// publishes each of them once, from whichever instance takes it, to
+	// webhooks, to notifications and to the broker; every instance follows
+	// all of them to its own live streams.
+	eventBus := events.NewBus(cfg.Events.ReplaySize)
+	eventPublishers := outbox.Publishers{webhooks.NewDispatcher(webhooksRepo), notifications.NewNotifier(notificationsRepo)}
+	broker, natsConn, err := newEventBroker(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	if broker != nil {
+		eventPublishers = append(eventPublishers, broker)
+	}
+	outboxRepo := outbox_postgres.NewOutboxRepository(db, retries)
+	eventRelay := outbox.NewRelay(outboxRepo, eventPublishers,
+		cfg.Outbox.PollInterval, cfg.Outbox.BatchSize, retries, cfg.Outbox.Retention, logger)
+	eventTail := outbox.NewTail(outboxRepo, eventBus, cfg.Outbox.PollInterval, cfg.Outbox.BatchSize, retries, logger)
+
+	commentsUsecase := comments_uc.NewCommentsUsecase(commentsRepo, comments_uc.DeleteMode(cfg.Comments.DeleteMode), commentsPolicy, commentsFlags, commentsTree,
+		newSpamPipeline(cfg, commentsRepo, spamRepo), commentsLimits, outbox.Wakers{eventRelay, eventTail}, logger)
+
+	sitesUsecase := sites_uc.NewSitesUsecase(sitesRepo, logger)
+	webhooksUsecase := webhooks_uc.NewWebhooksUsecase(webhooksRepo, logger)
+	emailEnabled := cfg.SMTP.Host != ""
+	mailer := notifications.NewSMTPMailer(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From, cfg.SMTP.Timeout)
+	notificationsUsecase := notifications_uc.NewNotificationsUsecase(notificationsRepo, mailer, emailEnabled, logger)
+
+	jwtSecret := []byte(cfg.Auth.JWTSecret)
+	if len(jwtSecret) == 0 {
+		// Tokens signed with a random secret stop working on restart.
+		logger.Warn().Msg("AUTH_JWT_SECRET is not set, using a random secret")
+		jwtSecret = make([]byte, 32)
+		if _, err := rand.Read(jwtSecret); err != nil {
+			return nil, fmt.Errorf("failed to generate JWT secret: %w", err)
+		}
+	}
+	usersUsecase := users_uc.NewUsersUsecase(usersRepo, jwtSecret, cfg.Auth.TokenTTL, cfg.Auth.SessionTTL, logger)
+
+	commentsHandler := comments_h.NewCommentsHandler(commentsUsecase, eventBus, logger)
+	sitesHandler := sites_h.NewSitesHandler(sitesUsecase, logger)
+	authHandler := auth_h.NewAuthHandler(usersUsecase, cfg.Auth.SecureCookies, logger)
+	liveHub := live_h.NewHub(eventBus, logger)
+	liveHandler := live_h.NewLiveHandler(liveHub, commentsUsecase, commentsIPLimiter, logger)
+	webhooksHandler := webhooks_h.NewWebhooksHandler(webhooksUsecase, logger)
+	notificationsHandler := notifications_h.NewNotificationsHandler(notificationsUsecase, logger)
+
+	webhookWorker := webhooks.NewWorker(webhooksRepo, webhooks.NewClient(cfg.Webhooks.Timeout, cfg.Webhooks.AllowPrivate),
+		retries, cfg.Webhooks.PollInterval, cfg.Webhooks.BatchSize, logger)
 
 	h := &router.Handler{
-		CommentsHandler: commentsHandler,
+		CommentsHandler:      commentsHandler,
+		SitesHandler:         sitesHandler,
+		AuthHandler:          authHandler,
+		LiveHandler:          liveHandler,
+		WebhooksHandler:      webhooksHandler,
+		NotificationsHandler: notificationsHandler,
 	}
 
-	mux := router.SetupRouter(h)
+	mux := router.SetupRouter(h, cfg.Admin.Token,
+		middleware.TenantMiddleware(sitesUsecase),
+		middleware.AuthMiddleware(usersUsecase, auth_h.SessionCookie),
+		middleware.ClientIPMiddleware(commentsIPLimiter),
+		middleware.RateLimitMiddleware(commentsIPLimiter),
+		middleware.RateLimitMiddleware(flagsIPLimiter))
 
 	server := &http.Server{
 		Addr:         ":" + cfg.Server.Addr,
@@ -59,12 +175,32 @@ func NewApp(cfg *config.Config, logger *zlog.Zerolog) (*App, error) {
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
+	// Shutdown waits for active requests, so event streams are ended first;
+	// it does not track WebSocket connections, which are closed with it.
+	server.RegisterOnShutdown(eventBus.Close)
+	server.RegisterOnShutdown(liveHub.Close)
+
+	workers := []func(ctx context.Context){
+		eventRelay.Run,
+		eventTail.Run,
+		liveHub.Run,
+		webhookWorker.Run,
+	}
+	// Without an SMTP server notifications stay in the inbox.
+	if emailEnabled {
+		emailSender := notifications.NewEmailSender(notificationsRepo, mailer,
+			cfg.Notifications.EmailInterval, cfg.Notifications.DigestInterval, cfg.Notifications.EmailBatchSize, logger)
+		workers = append(workers, emailSender.Run)
+	}
 
 	return &App{
-		cfg:    cfg,
-		server: server,
-		logger: logger,
-		db:     db,
+		cfg:     cfg,
+		server:  server,
+		logger:  logger,
+		db:      db,
+		redis:   redisClient,
+		nats:    natsConn,
+		workers: workers,
 	}, nil
 }
 
@@ -75,6 +211,9 @@ func (a *App) Run() error {
 	defer cancel()
 
 	go a.handleSignals(cancel)
+	for _, run := range a.workers {
+		go run(ctx)
+	}
 
 	serverErr := make(chan error, 1)
 	go func() {
@@ -98,6 +237,12 @@ func (a *App) Run() error {
 		}
 
 		a.db.Master.Close()
+		if a.redis != nil {
+			a.redis.Close()
+		}
+		if a.nats != nil {
+			a.nats.Close()
+		}
 		a.logger.Info().Msg("Server stopped gracefully")
 		return nil
 	}
@@ -111,3 +256,82 @@ func (a *App) handleSignals(cancel context.CancelFunc) {
 	a.logger.Info().Str("signal", sig.String()).Msg("Received signal")
 	cancel()
 }
+
+// Weights of the spam checks with the default scores: a banned word rejects
+// a comment on its own, while too many links, a repeated text or a
+// classifier fairly sure of spam queue it and reject it only together.
+const (
+	spamLinksWeight       = 0.6
+	spamDuplicateWeight   = 0.6
+	spamBannedWordsWeight = 1.0
+	spamBayesWeight       = 1.0
+	spamDuplicateLimit    = 20
+)
+
+// newSpamPipeline builds the spam checks configured for new comments. A
+// disabled pipeline has no checks and accepts everything.
+func newSpamPipeline(cfg *config.Config, commentsRepo *comments_postgres.CommentsRepository, spamRepo *spam_postgres.SpamRepository) *spam.Pipeline {
+	pipeline := spam.NewPipeline(cfg.Spam.QueueScore, cfg.Spam.RejectScore)
+	if !cfg.Spam.Enabled {
+		return pipeline
+	}
+
+	if len(cfg.Spam.BannedWords) > 0 {
+		pipeline.Add(spam.NewBannedWordsChecker(cfg.Spam.BannedWords), spamBannedWordsWeight)
+	}
+	pipeline.Add(spam.NewLinkChecker(cfg.Spam.MaxLinks), spamLinksWeight)
+	if cfg.Spam.DuplicateWindow > 0 {
+		pipeline.Add(spam.NewDuplicateChecker(commentsRepo, cfg.Spam.DuplicateWindow, spamDuplicateLimit), spamDuplicateWeight)
+	}
+	pipeline.Add(spam.NewBayesClassifier(spamRepo, cfg.Spam.BayesMinDocs), spamBayesWeight)
+
+	return pipeline
+}
+
+type rateLimitStore interface {
+	Take(ctx context.Context, key string, limit domain.RateLimit) (domain.RateLimitStatus, error)
+}
+
+// newRateLimitStore opens the store of the rate limits and, for the Redis
+// store, the client to close on shutdown. Limits kept in memory apply to each
+// instance of the service on its own, those kept in Redis to all of them
+// together.
+func newRateLimitStore(cfg *config.Config, logger *zlog.Zerolog) (rateLimitStore, *redis.Client, error) {
+	if cfg.RateLimit.Store != "redis" {
+		return ratelimit.NewMemoryStore(), nil, nil
+	}
+
+	client := redis.New(cfg.RedisAddr(), cfg.Redis.Password, cfg.Redis.DB)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	logger.Info().Str("addr", cfg.RedisAddr()).Msg("Rate limits are kept in Redis")
+	return ratelimit.NewRedisStore(client), client, nil
+}
+
+// newEventBroker connects to the message broker comment events are published
+// to, if one is configured, and returns its connection to close on shutdown.
+func newEventBroker(cfg *config.Config, logger *zlog.Zerolog) (outbox.EventPublisher, *nats.Conn, error) {
+	if cfg.Events.Broker != "nats" {
+		return nil, nil, nil
+	}
+
+	conn, err := nats.Connect(cfg.NATS.URL, nats.Name("comments-system"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	publisher, err := events.NewNATSPublisher(conn, cfg.NATS.Stream, cfg.NATS.Subject)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	logger.Info().Str("url", cfg.NATS.URL).Str("stream", cfg.NATS.Stream).Msg("Comment events are published to NATS")
+	return publisher, conn, nil
+}
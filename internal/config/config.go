@@ -29,6 +29,99 @@ type Config struct {
 		ShutdownTimeout time.Duration `env:"SERVER_SHUTDOWN_TIMEOUT" validate:"required"`
 	}
 
+	Comments struct {
+		DeleteMode    string        `env:"COMMENTS_DELETE_MODE" env-default:"cascade" validate:"oneof=cascade tombstone"`
+		EditWindow    time.Duration `env:"COMMENTS_EDIT_WINDOW" env-default:"15m" validate:"gte=0"`
+		FlagThreshold int           `env:"COMMENTS_FLAG_THRESHOLD" env-default:"3" validate:"gte=0"`
+		FlagAction    string        `env:"COMMENTS_FLAG_ACTION" env-default:"queue" validate:"oneof=queue hide"`
+
+		DefaultMaxDepth    int `env:"COMMENTS_DEFAULT_MAX_DEPTH" env-default:"5" validate:"gte=0"`
+		DefaultMaxChildren int `env:"COMMENTS_DEFAULT_MAX_CHILDREN" env-default:"50" validate:"gte=0"`
+		MaxDepth           int `env:"COMMENTS_MAX_DEPTH" env-default:"20" validate:"gte=0"`
+		MaxChildren        int `env:"COMMENTS_MAX_CHILDREN" env-default:"200" validate:"gte=0"`
+	}
+
+	Spam struct {
+		Enabled         bool          `env:"SPAM_ENABLED" env-default:"true"`
+		QueueScore      float64       `env:"SPAM_QUEUE_SCORE" env-default:"0.5" validate:"gt=0"`
+		RejectScore     float64       `env:"SPAM_REJECT_SCORE" env-default:"1" validate:"gtefield=QueueScore"`
+		MaxLinks        int           `env:"SPAM_MAX_LINKS" env-default:"3" validate:"gte=0"`
+		BannedWords     []string      `env:"SPAM_BANNED_WORDS" env-separator:","`
+		DuplicateWindow time.Duration `env:"SPAM_DUPLICATE_WINDOW" env-default:"24h" validate:"gte=0"`
+		BayesMinDocs    int           `env:"SPAM_BAYES_MIN_DOCS" env-default:"20" validate:"gte=1"`
+	}
+
+	RateLimit struct {
+		Store      string        `env:"RATE_LIMIT_STORE" env-default:"memory" validate:"oneof=memory redis"`
+		PerIP      int           `env:"RATE_LIMIT_COMMENTS_PER_IP" env-default:"10" validate:"gte=0"`
+		PerUser    int           `env:"RATE_LIMIT_COMMENTS_PER_USER" env-default:"10" validate:"gte=0"`
+		PerThread  int           `env:"RATE_LIMIT_COMMENTS_PER_THREAD" env-default:"60" validate:"gte=0"`
+		FlagsPerIP int           `env:"RATE_LIMIT_FLAGS_PER_IP" env-default:"10" validate:"gte=0"`
+		Window     time.Duration `env:"RATE_LIMIT_WINDOW" env-default:"1m" validate:"gt=0"`
+		TrustProxy bool          `env:"RATE_LIMIT_TRUST_PROXY" env-default:"false"`
+	}
+
+	Redis struct {
+		Host     string `env:"REDIS_HOST" env-default:"localhost"`
+		Port     int    `env:"REDIS_PORT" env-default:"6379"`
+		Password string `env:"REDIS_PASSWORD"`
+		DB       int    `env:"REDIS_DB" env-default:"0"`
+	}
+
+	Events struct {
+		ReplaySize int    `env:"EVENTS_REPLAY_SIZE" env-default:"1000" validate:"gte=1"`
+		Broker     string `env:"EVENTS_BROKER" env-default:"none" validate:"oneof=none nats"`
+	}
+
+	Outbox struct {
+		PollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" env-default:"1s" validate:"gt=0"`
+		BatchSize    int           `env:"OUTBOX_BATCH_SIZE" env-default:"100" validate:"gte=1"`
+		Retention    time.Duration `env:"OUTBOX_RETENTION" env-default:"24h" validate:"gte=0"`
+	}
+
+	NATS struct {
+		URL     string `env:"NATS_URL" env-default:"nats://localhost:4222"`
+		Stream  string `env:"NATS_STREAM" env-default:"COMMENTS"`
+		Subject string `env:"NATS_SUBJECT" env-default:"comments.events"`
+	}
+
+	Webhooks struct {
+		PollInterval time.Duration `env:"WEBHOOKS_POLL_INTERVAL" env-default:"1s" validate:"gt=0"`
+		Timeout      time.Duration `env:"WEBHOOKS_TIMEOUT" env-default:"10s" validate:"gt=0"`
+		BatchSize    int           `env:"WEBHOOKS_BATCH_SIZE" env-default:"20" validate:"gte=1"`
+		AllowPrivate bool          `env:"WEBHOOKS_ALLOW_PRIVATE" env-default:"false"`
+	}
+
+	Notifications struct {
+		EmailInterval  time.Duration `env:"NOTIFICATIONS_EMAIL_INTERVAL" env-default:"1m" validate:"gt=0"`
+		DigestInterval time.Duration `env:"NOTIFICATIONS_DIGEST_INTERVAL" env-default:"24h" validate:"gt=0"`
+		EmailBatchSize int           `env:"NOTIFICATIONS_EMAIL_BATCH_SIZE" env-default:"20" validate:"gte=1"`
+	}
+
+	SMTP struct {
+		Host     string        `env:"SMTP_HOST"`
+		Port     int           `env:"SMTP_PORT" env-default:"587" validate:"gte=1,lte=65535"`
+		Username string        `env:"SMTP_USERNAME"`
+		Password string        `env:"SMTP_PASSWORD"`
+		From     string        `env:"SMTP_FROM" env-default:"CommentTree <noreply@localhost>"`
+		Timeout  time.Duration `env:"SMTP_TIMEOUT" env-default:"30s" validate:"gt=0"`
+	}
+
+	Search struct {
+		Language string `env:"SEARCH_LANGUAGE" env-default:"russian" validate:"oneof=russian english simple"`
+	}
+
+	Admin struct {
+		Token string `env:"ADMIN_TOKEN"`
+	}
+
+	Auth struct {
+		JWTSecret     string        `env:"AUTH_JWT_SECRET"`
+		TokenTTL      time.Duration `env:"AUTH_TOKEN_TTL" env-default:"1h" validate:"gt=0"`
+		SessionTTL    time.Duration `env:"AUTH_SESSION_TTL" env-default:"720h" validate:"gt=0"`
+		SecureCookies bool          `env:"AUTH_SECURE_COOKIES" env-default:"false"`
+	}
+
 	Retries struct {
 		Attempts int     `env:"RETRIES_ATTEMPTS" validate:"required"`
 		DelayMs  int     `env:"RETRIES_DELAY_MS" validate:"required"`
@@ -57,6 +150,10 @@ func (c *Config) DBDSN() string {
 		c.DB.User, c.DB.Pass, c.DB.Host, c.DB.Port, c.DB.DBName)
 }
 
+func (c *Config) RedisAddr() string {
+	return fmt.Sprintf("%s:%d", c.Redis.Host, c.Redis.Port)
+}
+
 func (c *Config) DefaultRetryStrategy() retry.Strategy {
 	return retry.Strategy{
 		Attempts: c.Retries.Attempts,
@@ -1,22 +1,262 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// DefaultThreadKey is the thread of comments posted without naming one.
+const DefaultThreadKey = "default"
+
+// DeletedPlaceholder replaces the content and author of soft-deleted comments
+// wherever they are shown.
+const DeletedPlaceholder = "[deleted]"
+
+// CommentStatus is where a comment is in moderation.
+type CommentStatus string
+
+const (
+	// StatusPending comments wait for a moderator and are shown only to
+	// their author and moderators.
+	StatusPending CommentStatus = "pending"
+	// StatusApproved comments are shown to everyone.
+	StatusApproved CommentStatus = "approved"
+	// StatusRejected and StatusSpam comments are shown only to moderators.
+	StatusRejected CommentStatus = "rejected"
+	StatusSpam     CommentStatus = "spam"
+)
+
+// IsValid reports whether s is one of the known statuses.
+func (s CommentStatus) IsValid() bool {
+	switch s {
+	case StatusPending, StatusApproved, StatusRejected, StatusSpam:
+		return true
+	}
+	return false
+}
 
 type Comment struct {
-	ID        int
-	ParentID  *int
+	ID       int
+	ParentID *int
+	// RootID is the top-level comment of the thread; a root is its own root.
+	RootID    int
+	ThreadKey string
 	Content   string
 	Author    string
+	Status    CommentStatus
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt *time.Time
 	Children  []Comment
+
+	// UserID is the registered user who posted the comment. It is nil for
+	// guest comments.
+	UserID *int
+	// ManageToken lets the guest who posted the comment edit and delete it.
+	// It is set only on the comment returned by its creation; afterwards only
+	// ManageTokenHash is known, and only when the comment is loaded by ID.
+	ManageToken     string
+	ManageTokenHash string
+
+	Upvotes   int
+	Downvotes int
+
+	// ReplyCount is the number of direct replies. DescendantCount is filled
+	// only when a single comment is requested.
+	ReplyCount      int
+	DescendantCount int
+
+	// RemainingChildren is the number of direct replies that were not loaded
+	// because of depth or breadth limits; RepliesCursor continues from them.
+	RemainingChildren int
+	RepliesCursor     string
+
+	// Headline is a snippet of the content with the words matching a search
+	// highlighted. It is empty outside of search results.
+	Headline string
+	// Matched marks comments that match the search in search results.
+	Matched bool
 }
 
-type CommentTree struct {
+// IsDeleted reports whether the comment was soft-deleted and is kept in the
+// tree only as a placeholder for its replies.
+func (c Comment) IsDeleted() bool {
+	return c.DeletedAt != nil
+}
+
+// Score is the number of upvotes minus the number of downvotes.
+func (c Comment) Score() int {
+	return c.Upvotes - c.Downvotes
+}
+
+// CommentRevision is a previous version of a comment's content, kept when
+// the comment is edited. CreatedAt is the time that version was written.
+type CommentRevision struct {
+	ID        int
+	CommentID int
+	Content   string
+	CreatedAt time.Time
+}
+
+// CommentContext is a comment together with the chain of its ancestors,
+// ordered from the thread root down to the direct parent.
+type CommentContext struct {
+	Ancestors []Comment
+	Comment   Comment
+}
+
+// CommentsQuery selects comments for a listing. Without ParentID it pages
+// over the root comments of ThreadKey, with ParentID it returns the subtree
+// of that comment. In a search ThreadKey is optional and empty means every
+// thread.
+// Zero MaxDepth and MaxChildren mean the subtrees are not limited.
+//
+// After and Before are opaque cursors received from the client; the usecase
+// decodes them into AfterKey and BeforeKey for the repository. WithTotal
+// asks for the exact number of matching roots to be counted.
+//
+// Filter narrows down the comments that are listed: the roots in a thread
+// listing, the comments of the subtree with ParentID, or the matches of a
+// search.
+//
+// ChildSort and ChildOrder order the replies at every level of the tree
+// independently of the roots; when empty, replies follow SortBy if it is
+// based on votes and are kept in the order they were written otherwise.
+type CommentsQuery struct {
+	ThreadKey   string
+	ParentID    *int
+	Page        int
+	PageSize    int
+	Search      string
+	SortBy      string
+	SortOrder   string
+	ChildSort   string
+	ChildOrder  string
+	MaxDepth    int
+	MaxChildren int
+	Filter      CommentFilter
+
+	After     string
+	Before    string
+	AfterKey  *Keyset
+	BeforeKey *Keyset
+	WithTotal bool
+}
+
+// CommentFilter holds structured conditions on comments. Zero fields do not
+// filter. CreatedAfter is inclusive and CreatedBefore exclusive; RootID
+// selects the thread root itself and every comment below it.
+type CommentFilter struct {
+	Author        string
+	AuthorPrefix  bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	RootID        *int
+	MinReplies    int
+	IsRoot        *bool
+}
+
+// IsEmpty reports whether the filter has no conditions.
+func (f CommentFilter) IsEmpty() bool {
+	return f.Author == "" && f.CreatedAfter == nil && f.CreatedBefore == nil &&
+		f.RootID == nil && f.MinReplies <= 0 && f.IsRoot == nil
+}
+
+// Keyset is a position in a listing of root comments: the value of the sort
+// field rendered as text and the comment ID that breaks ties.
+type Keyset struct {
+	Value string
+	ID    int
+}
+
+// CommentsPage is a listing as returned by the repository. First and Last
+// are the keysets of the first and last root on the page, HasMore tells
+// whether more roots follow in the direction of the query.
+type CommentsPage struct {
 	Comments []Comment
 	Total    int
+	HasMore  bool
+	First    *Keyset
+	Last     *Keyset
+}
+
+// RepliesQuery selects a slice of the direct replies to a comment. The sort
+// fields are those of the listing the replies belong to.
+type RepliesQuery struct {
+	ParentID    int
+	Offset      int
+	MaxDepth    int
+	MaxChildren int
+	SortBy      string
+	SortOrder   string
+	ChildSort   string
+	ChildOrder  string
+}
+
+// CommentReplies is one slice of the direct replies to a comment.
+type CommentReplies struct {
+	Comments   []Comment
+	Remaining  int
+	NextCursor string
+}
+
+// SearchHit is a comment matching a search together with the chain of its
+// ancestors, ordered from the thread root down to the direct parent.
+type SearchHit struct {
+	RootID    int
+	Ancestors []Comment
+	Comment   Comment
+}
+
+type SearchResults struct {
+	Hits     []SearchHit
+	Total    int
+	HasTotal bool
 	Page     int
 	PageSize int
 	HasNext  bool
 	HasPrev  bool
 }
+
+type CommentTree struct {
+	Comments   []Comment
+	Total      int
+	HasTotal   bool
+	Page       int
+	PageSize   int
+	HasNext    bool
+	HasPrev    bool
+	NextCursor string
+	PrevCursor string
+}
+
+// ModerationQueue is a page of comments with one status, oldest first.
+type ModerationQueue struct {
+	Comments []Comment
+	Status   CommentStatus
+	Total    int
+	Page     int
+	PageSize int
+}
+
+type commentTokenContextKey struct{}
+
+// WithCommentToken returns a copy of ctx carrying the management token of a
+// guest comment presented with the request.
+func WithCommentToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, commentTokenContextKey{}, token)
+}
+
+// CommentTokenFromContext returns the comment management token of ctx.
+func CommentTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(commentTokenContextKey{}).(string)
+	return token
+}
+
+// HashCommentToken returns the hash a comment management token is stored as.
+func HashCommentToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
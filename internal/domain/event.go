@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+// EventType names a change to a comment.
+type EventType string
+
+const (
+	EventCommentCreated EventType = "comment.created"
+	// EventCommentUpdated covers edits and restores by authors and moderators.
+	EventCommentUpdated EventType = "comment.updated"
+	// EventCommentModerated is a change of status: a moderator's decision or
+	// a comment hidden by flags.
+	EventCommentModerated EventType = "comment.moderated"
+	// EventCommentDeleted is published for the deleted comment only; in
+	// cascade mode its replies are gone with it.
+	EventCommentDeleted EventType = "comment.deleted"
+)
+
+// CommentEvent is a change to a comment of a site. Comment is the comment as
+// it is after the change; a deleted comment is marked deleted.
+type CommentEvent struct {
+	// ID orders the events of a bus; it is set when the event is published.
+	ID uint64
+	// Key identifies the change itself. An event may be published more than
+	// once, always with the same key, so consumers drop the repeats by it.
+	Key        string
+	Type       EventType
+	SiteID     int
+	Comment    Comment
+	OccurredAt time.Time
+}
+
+// OutboxPosition is a place in the stored comment events for following them:
+// the transaction that stored an event and the ID of the event.
+type OutboxPosition struct {
+	TxID int64
+	ID   int64
+}
+
+func (t EventType) IsValid() bool {
+	switch t {
+	case EventCommentCreated, EventCommentUpdated, EventCommentModerated, EventCommentDeleted:
+		return true
+	}
+	return false
+}
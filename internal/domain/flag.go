@@ -0,0 +1,55 @@
+package domain
+
+import "time"
+
+// FlagReason is why a viewer reported a comment.
+type FlagReason string
+
+const (
+	FlagReasonSpam     FlagReason = "spam"
+	FlagReasonAbuse    FlagReason = "abuse"
+	FlagReasonOffTopic FlagReason = "off_topic"
+	FlagReasonOther    FlagReason = "other"
+)
+
+func (r FlagReason) IsValid() bool {
+	switch r {
+	case FlagReasonSpam, FlagReasonAbuse, FlagReasonOffTopic, FlagReasonOther:
+		return true
+	}
+	return false
+}
+
+// Flag is a report of a comment. Reporter identifies who sent it so each
+// reporter flags a comment only once.
+type Flag struct {
+	ID        int
+	CommentID int
+	Reporter  string
+	Reason    FlagReason
+	Note      string
+	CreatedAt time.Time
+}
+
+// FlagResult is the state of a comment after it was flagged.
+type FlagResult struct {
+	// OpenFlags is the number of flags waiting for a moderator.
+	OpenFlags int
+	// Hidden is true if this flag pushed the comment over the threshold
+	// and it was taken out of public view.
+	Hidden bool
+}
+
+// FlaggedComment is a comment with its open flags, newest first.
+type FlaggedComment struct {
+	Comment Comment
+	Flags   []Flag
+}
+
+// FlagInbox is a page of flagged comments, the most flagged first.
+type FlagInbox struct {
+	Comments []FlaggedComment
+	Total    int
+	Page     int
+	PageSize int
+}
@@ -0,0 +1,111 @@
+package domain
+
+import "time"
+
+// NotificationKind is why a user is notified about a comment.
+type NotificationKind string
+
+const (
+	// NotificationReply is a reply to a comment of the user.
+	NotificationReply NotificationKind = "reply"
+	// NotificationMention is a comment mentioning the user as @username.
+	NotificationMention NotificationKind = "mention"
+)
+
+// Notification tells a registered user about a comment. A comment notifies
+// a user once; a reply that also mentions them is a reply.
+type Notification struct {
+	ID      int64
+	UserID  int
+	Kind    NotificationKind
+	Comment Comment
+	// ReadAt is nil until the user marks the notification read.
+	ReadAt    *time.Time
+	CreatedAt time.Time
+}
+
+// IsRead reports whether the user has seen the notification.
+func (n Notification) IsRead() bool {
+	return n.ReadAt != nil
+}
+
+// NotificationInbox is a page of the notifications of a user, newest first.
+type NotificationInbox struct {
+	Notifications []Notification
+	UnreadOnly    bool
+	// Unread counts all unread notifications of the user, not just those
+	// of the page.
+	Unread   int
+	Total    int
+	Page     int
+	PageSize int
+}
+
+// EmailMode is how a user gets notifications by email.
+type EmailMode string
+
+const (
+	// EmailOff sends no email.
+	EmailOff EmailMode = "off"
+	// EmailInstant sends new notifications shortly after they arrive,
+	// several in one email if they arrive close together.
+	EmailInstant EmailMode = "instant"
+	// EmailDigest sends the unread notifications in one email at most once
+	// per digest interval.
+	EmailDigest EmailMode = "digest"
+)
+
+func (m EmailMode) IsValid() bool {
+	switch m {
+	case EmailOff, EmailInstant, EmailDigest:
+		return true
+	}
+	return false
+}
+
+// NotificationPreferences are the notification settings of a user.
+type NotificationPreferences struct {
+	UserID   int
+	Replies  bool
+	Mentions bool
+	Email    string
+	// EmailConfirmed is true once the user presented the token sent to
+	// Email. Notifications are emailed only to a confirmed address.
+	EmailConfirmed bool
+	// EmailTokenHash is the hash of the last token sent to Email, cleared
+	// when it is used.
+	EmailTokenHash   string
+	EmailTokenSentAt *time.Time
+	EmailMode        EmailMode
+	UpdatedAt        time.Time
+}
+
+// DefaultNotificationPreferences are the preferences of a user who has not
+// changed them: replies and mentions in the inbox, no email.
+func DefaultNotificationPreferences(userID int) NotificationPreferences {
+	return NotificationPreferences{
+		UserID:    userID,
+		Replies:   true,
+		Mentions:  true,
+		EmailMode: EmailOff,
+	}
+}
+
+// NotificationPreferencesUpdate holds the preferences to change. Nil fields
+// are kept.
+type NotificationPreferencesUpdate struct {
+	Replies   *bool
+	Mentions  *bool
+	Email     *string
+	EmailMode *EmailMode
+}
+
+// NotificationEmail is an email claimed for sending: the unread
+// notifications of a user not emailed yet.
+type NotificationEmail struct {
+	UserID        int
+	Username      string
+	Email         string
+	SiteName      string
+	Notifications []Notification
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// RateLimit allows Limit requests per Window. Unused requests add up to a
+// burst of at most Limit.
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// IsZero reports whether the limit is switched off.
+func (l RateLimit) IsZero() bool {
+	return l.Limit <= 0 || l.Window <= 0
+}
+
+// RateLimitStatus is the state of a rate limit after a request was counted.
+type RateLimitStatus struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the full limit is available again.
+	Reset time.Duration
+	// RetryAfter is how long a rejected client has to wait for its next
+	// request.
+	RetryAfter time.Duration
+	Window     time.Duration
+}
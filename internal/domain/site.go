@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultSiteID is the site that serves requests without an API key.
+const DefaultSiteID = 1
+
+// Length limits of a site that does not set its own.
+const (
+	DefaultMaxContentLength = 1000
+	DefaultMaxAuthorLength  = 50
+)
+
+// ModerationMode selects whether new comments of a site are published
+// immediately or wait for a moderator.
+type ModerationMode string
+
+const (
+	ModerationNone ModerationMode = "none"
+	ModerationPre  ModerationMode = "pre"
+)
+
+// Site is an independent tenant of the service. Its comments, threads and
+// settings are invisible to every other site.
+type Site struct {
+	ID               int
+	Name             string
+	AllowedOrigins   []string
+	MaxContentLength int
+	MaxAuthorLength  int
+	ModerationMode   ModerationMode
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// AllowsOrigin reports whether browsers on origin may call the API of the
+// site. A site without allowed origins accepts any origin.
+func (s Site) AllowsOrigin(origin string) bool {
+	if len(s.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, allowed := range s.AllowedOrigins {
+		if allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// SiteUpdate holds the settings to change on a site. Nil fields are kept.
+type SiteUpdate struct {
+	Name             *string
+	AllowedOrigins   *[]string
+	MaxContentLength *int
+	MaxAuthorLength  *int
+	ModerationMode   *ModerationMode
+}
+
+type siteContextKey struct{}
+
+// WithSite returns a copy of ctx scoped to site.
+func WithSite(ctx context.Context, site Site) context.Context {
+	return context.WithValue(ctx, siteContextKey{}, site)
+}
+
+// SiteFromContext returns the site ctx is scoped to.
+func SiteFromContext(ctx context.Context) (Site, bool) {
+	site, ok := ctx.Value(siteContextKey{}).(Site)
+	return site, ok
+}
@@ -0,0 +1,27 @@
+package domain
+
+// SpamDecision is what happens to a new comment after the spam checks.
+type SpamDecision string
+
+const (
+	// SpamAccept publishes the comment as the site's moderation mode says.
+	SpamAccept SpamDecision = "accept"
+	// SpamQueue holds the comment for a moderator as pending.
+	SpamQueue SpamDecision = "queue"
+	// SpamReject refuses the comment.
+	SpamReject SpamDecision = "reject"
+)
+
+// SpamVerdict is the combined result of the spam checks of a comment.
+type SpamVerdict struct {
+	Decision SpamDecision
+	Score    float64
+	// Reasons name the checks that scored the comment, e.g. "links: 5 > 3".
+	Reasons []string
+}
+
+// SpamTokenCount tells in how many spam and ham comments a token was seen.
+type SpamTokenCount struct {
+	Spam int
+	Ham  int
+}
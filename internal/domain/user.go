@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Role is what a user may do on their site besides posting.
+type Role string
+
+const (
+	// RoleUser may edit and delete their own comments.
+	RoleUser Role = "user"
+	// RoleModerator may act on any comment of the site.
+	RoleModerator Role = "moderator"
+	// RoleAdmin moderates and also manages the site and its users.
+	RoleAdmin Role = "admin"
+)
+
+// IsValid reports whether r is one of the known roles.
+func (r Role) IsValid() bool {
+	return r == RoleUser || r == RoleModerator || r == RoleAdmin
+}
+
+// User is a registered account of a site. Its username is the author name of
+// the comments it posts.
+type User struct {
+	ID        int
+	SiteID    int
+	Username  string
+	Role      Role
+	CreatedAt time.Time
+}
+
+// CanModerate reports whether the user may act on comments of others.
+func (u User) CanModerate() bool {
+	return u.Role == RoleModerator || u.Role == RoleAdmin
+}
+
+type userContextKey struct{}
+
+// WithUser returns a copy of ctx authenticated as user.
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns the user ctx is authenticated as. Guest requests
+// have no user.
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userContextKey{}).(User)
+	return user, ok
+}
+
+type clientIPContextKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the IP address of the client
+// that sent the request.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPContextKey{}, ip)
+}
+
+// ClientIPFromContext returns the client IP address of ctx.
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPContextKey{}).(string)
+	return ip
+}
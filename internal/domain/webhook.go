@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Webhook is an endpoint of another service that is sent the comment events
+// of a site. Secret signs the payloads so the receiver can check where they
+// come from.
+type Webhook struct {
+	ID     int
+	SiteID int
+	URL    string
+	Secret string
+	// Events are the event types the webhook gets; empty means all of them.
+	Events    []EventType
+	Active    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Wants reports whether the webhook is sent events of type t.
+func (w Webhook) Wants(t EventType) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, t)
+}
+
+// WebhookUpdate holds the settings to change on a webhook. Nil fields are
+// kept.
+type WebhookUpdate struct {
+	URL    *string
+	Events *[]EventType
+	Active *bool
+}
+
+// DeliveryStatus is where a webhook delivery is in its lifecycle.
+type DeliveryStatus string
+
+const (
+	// DeliveryPending waits for its next attempt.
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliveryDelivered was accepted by the receiver.
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead ran out of attempts and is only retried by hand.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+func (s DeliveryStatus) IsValid() bool {
+	switch s {
+	case DeliveryPending, DeliveryDelivered, DeliveryDead:
+		return true
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent, or to be sent, to one webhook.
+// LastStatusCode is 0 when the receiver never answered.
+type WebhookDelivery struct {
+	ID             int64
+	WebhookID      int
+	EventType      EventType
+	Payload        []byte
+	Status         DeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+// DueDelivery is a delivery claimed for its next attempt, with the endpoint
+// it goes to.
+type DueDelivery struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// DeliveryLog is a page of the deliveries of a webhook, newest first.
+type DeliveryLog struct {
+	Deliveries []WebhookDelivery
+	Status     DeliveryStatus
+	Total      int
+	Page       int
+	PageSize   int
+}
@@ -0,0 +1,179 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before it
+// is dropped.
+const subscriberBuffer = 64
+
+// Bus delivers comment events to the subscribers of this process and keeps
+// the latest of them so a subscriber can resume after a reconnect.
+type Bus struct {
+	mu     sync.Mutex
+	lastID uint64
+	// replay is a ring of the latest events; next is where the next event
+	// goes and full tells whether the ring has wrapped.
+	replay []domain.CommentEvent
+	next   int
+	full   bool
+	// keys are the keys of the events in the ring, for dropping events
+	// published again.
+	keys   map[string]struct{}
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewBus creates a bus that keeps the latest replaySize events. Event IDs
+// start from the current time, so IDs handed out before a restart are older
+// than any kept event and are not mistaken for new ones.
+func NewBus(replaySize int) *Bus {
+	return &Bus{
+		lastID: uint64(time.Now().UnixNano()),
+		replay: make([]domain.CommentEvent, max(replaySize, 1)),
+		keys:   make(map[string]struct{}),
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+// Filter decides whether a subscriber gets an event and may change the event
+// it gets.
+type Filter func(event domain.CommentEvent) (domain.CommentEvent, bool)
+
+// Subscription receives the events of a bus that pass its filter.
+type Subscription struct {
+	bus    *Bus
+	filter Filter
+	events chan domain.CommentEvent
+}
+
+// Events returns the channel of the subscription. It is closed when the
+// subscription is closed, including when the subscriber fell too far behind.
+func (s *Subscription) Events() <-chan domain.CommentEvent {
+	return s.events
+}
+
+// Close stops the subscription.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
+
+// Publish numbers an event and delivers it to the subscribers. An event whose
+// key is still kept was already published and is dropped. Subscribers whose
+// buffer is full are dropped rather than waited for; they can resume from the
+// last event they got.
+func (b *Bus) Publish(ctx context.Context, event domain.CommentEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if event.Key != "" {
+		if _, ok := b.keys[event.Key]; ok {
+			return nil
+		}
+	}
+
+	b.lastID++
+	event.ID = b.lastID
+
+	if b.full {
+		delete(b.keys, b.replay[b.next].Key)
+	}
+	if event.Key != "" {
+		b.keys[event.Key] = struct{}{}
+	}
+	b.replay[b.next] = event
+	b.next = (b.next + 1) % len(b.replay)
+	if b.next == 0 {
+		b.full = true
+	}
+
+	for sub := range b.subs {
+		filtered, ok := sub.filter(event)
+		if !ok {
+			continue
+		}
+		select {
+		case sub.events <- filtered:
+		default:
+			b.remove(sub)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe starts a subscription. With resume set, the kept events after
+// lastID that pass filter are returned to be sent first; complete is false
+// when some of them are no longer kept, so the subscriber missed events.
+func (b *Bus) Subscribe(filter Filter, resume bool, lastID uint64) (sub *Subscription, missed []domain.CommentEvent, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{
+		bus:    b,
+		filter: filter,
+		events: make(chan domain.CommentEvent, subscriberBuffer),
+	}
+	if b.closed {
+		close(sub.events)
+		return sub, nil, true
+	}
+	b.subs[sub] = struct{}{}
+
+	if !resume {
+		return sub, nil, true
+	}
+
+	kept := b.kept()
+	oldest := b.lastID + 1
+	if len(kept) > 0 {
+		oldest = kept[0].ID
+	}
+	complete = lastID <= b.lastID && lastID+1 >= oldest
+	for _, event := range kept {
+		if event.ID <= lastID {
+			continue
+		}
+		if filtered, ok := filter(event); ok {
+			missed = append(missed, filtered)
+		}
+	}
+
+	return sub, missed, complete
+}
+
+// Close ends all subscriptions, e.g. for the server to shut down without
+// waiting for long-lived streams. Later subscriptions end right away.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// kept returns the events in the replay ring, oldest first.
+func (b *Bus) kept() []domain.CommentEvent {
+	if !b.full {
+		return b.replay[:b.next]
+	}
+	return append(append([]domain.CommentEvent(nil), b.replay[b.next:]...), b.replay[:b.next]...)
+}
+
+// remove drops a subscriber. The caller holds b.mu.
+func (b *Bus) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.events)
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+// resubscribeDelay is how long Follow waits before subscribing again after
+// losing its subscription.
+const resubscribeDelay = time.Second
+
+// Follow passes every event of the bus to handle until ctx is done. When the
+// subscription is lost, e.g. because handle fell behind, it is resumed from
+// the last handled event; gap is called if events were missed meanwhile.
+func (b *Bus) Follow(ctx context.Context, handle func(domain.CommentEvent), gap func()) {
+	all := func(event domain.CommentEvent) (domain.CommentEvent, bool) { return event, true }
+
+	var lastID uint64
+	resume := false
+	for {
+		sub, missed, complete := b.Subscribe(all, resume, lastID)
+		if !complete {
+			gap()
+		}
+		for _, event := range missed {
+			handle(event)
+			lastID = event.ID
+		}
+
+		follow(ctx, sub.Events(), handle, &lastID)
+		sub.Close()
+		resume = lastID != 0
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+func follow(ctx context.Context, events <-chan domain.CommentEvent, handle func(domain.CommentEvent), lastID *uint64) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			handle(event)
+			*lastID = event.ID
+		}
+	}
+}
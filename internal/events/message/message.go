@@ -0,0 +1,75 @@
+package message
+
+import (
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+// Event is a comment event sent out of the service, to webhooks and the
+// message broker: its type, the comment the way the API shows it, the site
+// and an ID that is the same every time the event is sent.
+type Event struct {
+	ID         string    `json:"id"`
+	SiteID     int       `json:"site_id"`
+	Type       string    `json:"type"`
+	Comment    Comment   `json:"comment"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Comment is the comment of an event.
+type Comment struct {
+	ID        int       `json:"id"`
+	ParentID  *int      `json:"parent_id,omitempty"`
+	RootID    int       `json:"root_id"`
+	ThreadKey string    `json:"thread_key"`
+	Content   string    `json:"content"`
+	Author    string    `json:"author"`
+	UserID    *int      `json:"user_id,omitempty"`
+	Status    string    `json:"status"`
+	Deleted   bool      `json:"deleted,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Upvotes   int `json:"upvotes"`
+	Downvotes int `json:"downvotes"`
+	Score     int `json:"score"`
+}
+
+func FromDomainEvent(event domain.CommentEvent) Event {
+	return Event{
+		ID:         event.Key,
+		SiteID:     event.SiteID,
+		Type:       string(event.Type),
+		Comment:    FromDomainComment(event.Comment),
+		OccurredAt: event.OccurredAt,
+	}
+}
+
+func FromDomainComment(comment domain.Comment) Comment {
+	msg := Comment{
+		ID:        comment.ID,
+		ParentID:  comment.ParentID,
+		RootID:    comment.RootID,
+		ThreadKey: comment.ThreadKey,
+		Content:   comment.Content,
+		Author:    comment.Author,
+		UserID:    comment.UserID,
+		Status:    string(comment.Status),
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+
+		Upvotes:   comment.Upvotes,
+		Downvotes: comment.Downvotes,
+		Score:     comment.Score(),
+	}
+
+	if comment.IsDeleted() {
+		msg.Content = domain.DeletedPlaceholder
+		msg.Author = domain.DeletedPlaceholder
+		msg.UserID = nil
+		msg.Deleted = true
+	}
+
+	return msg
+}
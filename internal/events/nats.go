@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"comments-system/internal/domain"
+	"comments-system/internal/events/message"
+
+	"github.com/nats-io/nats.go"
+)
+
+// HeaderEventType carries the type of an event in a NATS message, so
+// consumers can route it without decoding the body.
+const HeaderEventType = "Comment-Event-Type"
+
+// NATSPublisher publishes comment events to a NATS JetStream stream, one
+// message per event on the subject <prefix>.<site ID>.<event type>. The key
+// of an event is its message ID, so JetStream drops an event published again
+// within the duplicate window of the stream.
+type NATSPublisher struct {
+	js     nats.JetStreamContext
+	prefix string
+}
+
+// NewNATSPublisher creates a publisher on conn and creates the stream of
+// prefix.> named stream if there is none.
+func NewNATSPublisher(conn *nats.Conn, stream, prefix string) (*NATSPublisher, error) {
+	js, err := conn.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+
+	_, err = js.StreamInfo(stream)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:     stream,
+			Subjects: []string{prefix + ".>"},
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up stream %s: %w", stream, err)
+	}
+
+	return &NATSPublisher{
+		js:     js,
+		prefix: prefix,
+	}, nil
+}
+
+// Publish sends an event and waits for the stream to store it.
+func (p *NATSPublisher) Publish(ctx context.Context, event domain.CommentEvent) error {
+	body, err := json.Marshal(message.FromDomainEvent(event))
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	msg := nats.NewMsg(fmt.Sprintf("%s.%d.%s", p.prefix, event.SiteID, event.Type))
+	msg.Header.Set(nats.MsgIdHdr, event.Key)
+	msg.Header.Set(HeaderEventType, string(event.Type))
+	msg.Data = body
+
+	if _, err := p.js.PublishMsg(msg, nats.Context(ctx)); err != nil {
+		return fmt.Errorf("failed to publish %s event to NATS: %w", event.Type, err)
+	}
+
+	return nil
+}
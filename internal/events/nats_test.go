@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"comments-system/internal/domain"
+	"comments-system/internal/events/message"
+
+	"github.com/nats-io/nats.go"
+)
+
+// fakeJetStream stores the messages published to it and, like a stream with
+// a duplicate window, drops those whose message ID it already has.
+type fakeJetStream struct {
+	nats.JetStreamContext
+	ids  map[string]bool
+	msgs []*nats.Msg
+}
+
+func (js *fakeJetStream) PublishMsg(m *nats.Msg, _ ...nats.PubOpt) (*nats.PubAck, error) {
+	id := m.Header.Get(nats.MsgIdHdr)
+	if js.ids[id] {
+		return &nats.PubAck{Stream: "COMMENTS", Duplicate: true}, nil
+	}
+	js.ids[id] = true
+	js.msgs = append(js.msgs, m)
+	return &nats.PubAck{Stream: "COMMENTS", Sequence: uint64(len(js.msgs))}, nil
+}
+
+func TestNATSPublisherPublish(t *testing.T) {
+	js := &fakeJetStream{ids: make(map[string]bool)}
+	p := &NATSPublisher{js: js, prefix: "comments"}
+
+	event := domain.CommentEvent{
+		Key:        "42",
+		Type:       domain.EventCommentCreated,
+		SiteID:     3,
+		Comment:    domain.Comment{ID: 7, RootID: 7, ThreadKey: "article-1", Content: "hello", Author: "ann"},
+		OccurredAt: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	// The relay publishes an event again if it could not record that it was
+	// published; the stream keeps it once.
+	for range 2 {
+		if err := p.Publish(context.Background(), event); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	if len(js.msgs) != 1 {
+		t.Fatalf("stream has %d messages, want 1", len(js.msgs))
+	}
+	msg := js.msgs[0]
+	if msg.Subject != "comments.3.comment.created" {
+		t.Errorf("subject = %q, want comments.3.comment.created", msg.Subject)
+	}
+	if id := msg.Header.Get(nats.MsgIdHdr); id != "42" {
+		t.Errorf("%s = %q, want 42", nats.MsgIdHdr, id)
+	}
+	if typ := msg.Header.Get(HeaderEventType); typ != string(domain.EventCommentCreated) {
+		t.Errorf("%s = %q, want %q", HeaderEventType, typ, domain.EventCommentCreated)
+	}
+
+	var body message.Event
+	if err := json.Unmarshal(msg.Data, &body); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	if body.ID != "42" || body.SiteID != 3 || body.Type != "comment.created" || body.Comment.ID != 7 || body.Comment.Content != "hello" {
+		t.Errorf("message = %+v", body)
+	}
+}
@@ -0,0 +1,296 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"comments-system/internal/domain"
+	"comments-system/internal/http-server/handler/auth/dto"
+	users_usecase "comments-system/internal/usecase/users"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// SessionCookie is the cookie that holds the session token of the web
+// interface.
+const SessionCookie = "session"
+
+type AuthHandler struct {
+	usecase       usersUsecase
+	secureCookies bool
+	logger        *zlog.Zerolog
+	validate      *validator.Validate
+}
+
+// NewAuthHandler creates the handler. secureCookies marks session cookies as
+// HTTPS-only.
+func NewAuthHandler(usecase usersUsecase, secureCookies bool, logger *zlog.Zerolog) *AuthHandler {
+	return &AuthHandler{
+		usecase:       usecase,
+		secureCookies: secureCookies,
+		logger:        logger,
+		validate:      validator.New(),
+	}
+}
+
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.usecase.Register(ctx, req.Username, req.Password)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to register user")
+
+		if errors.Is(err, users_usecase.ErrUsernameTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, users_usecase.ErrInvalidUsername) ||
+			errors.Is(err, users_usecase.ErrPasswordTooShort) ||
+			errors.Is(err, users_usecase.ErrPasswordTooLong) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.FromDomainUser(user)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// Login checks the credentials of a user and starts a session of the web
+// interface in a cookie.
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.usecase.Login(ctx, req.Username, req.Password)
+	if err != nil {
+		h.writeLoginError(w, err)
+		return
+	}
+
+	token, expiresAt, err := h.usecase.CreateSession(ctx, user)
+	if err != nil {
+		h.logger.Error().Err(err).Int("user_id", user.ID).Msg("Failed to create session")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	resp := dto.FromDomainUser(user)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookie); err == nil && cookie.Value != "" {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		if err := h.usecase.Logout(ctx, cookie.Value); err != nil {
+			h.logger.Error().Err(err).Msg("Failed to delete session")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Token exchanges the credentials of a user for a bearer access token for
+// API clients.
+func (h *AuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.usecase.Login(ctx, req.Username, req.Password)
+	if err != nil {
+		h.writeLoginError(w, err)
+		return
+	}
+
+	token, expiresAt, err := h.usecase.IssueToken(user)
+	if err != nil {
+		h.logger.Error().Err(err).Int("user_id", user.ID).Msg("Failed to issue token")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(time.Until(expiresAt).Seconds()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// Me returns the user the request is authenticated as.
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	user, ok := domain.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	resp := dto.FromDomainUser(user)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// ListUsers returns the users of the site of the request.
+func (h *AuthHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	users, err := h.usecase.ListUsers(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to list users")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.FromDomainUsers(users)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// UpdateUser changes the role of a user of the site of the request.
+func (h *AuthHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", userIDStr).Msg("Invalid user ID")
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.usecase.SetRole(ctx, userID, domain.Role(req.Role))
+	if err != nil {
+		h.logger.Error().Err(err).Int("user_id", userID).Msg("Failed to update user")
+
+		if errors.Is(err, users_usecase.ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, users_usecase.ErrInvalidUserID) ||
+			errors.Is(err, users_usecase.ErrInvalidRole) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.FromDomainUser(user)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *AuthHandler) decodeCredentials(w http.ResponseWriter, r *http.Request) (dto.CredentialsRequest, bool) {
+	var req dto.CredentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return dto.CredentialsRequest{}, false
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return dto.CredentialsRequest{}, false
+	}
+
+	return req, true
+}
+
+func (h *AuthHandler) writeLoginError(w http.ResponseWriter, err error) {
+	if errors.Is(err, users_usecase.ErrInvalidCredentials) {
+		h.logger.Warn().Msg("Login failed")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	h.logger.Error().Err(err).Msg("Failed to log in")
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"comments-system/internal/domain"
+	"context"
+	"time"
+)
+
+type usersUsecase interface {
+	Register(ctx context.Context, username, password string) (domain.User, error)
+	Login(ctx context.Context, username, password string) (domain.User, error)
+	CreateSession(ctx context.Context, user domain.User) (string, time.Time, error)
+	Logout(ctx context.Context, token string) error
+	IssueToken(user domain.User) (string, time.Time, error)
+	ListUsers(ctx context.Context) ([]domain.User, error)
+	SetRole(ctx context.Context, id int, role domain.Role) (domain.User, error)
+}
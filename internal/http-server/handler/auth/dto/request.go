@@ -0,0 +1,10 @@
+package dto
+
+type CredentialsRequest struct {
+	Username string `json:"username" validate:"required,min=2,max=50"`
+	Password string `json:"password" validate:"required"`
+}
+
+type UpdateUserRequest struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
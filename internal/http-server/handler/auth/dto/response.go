@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"comments-system/internal/domain"
+	"time"
+)
+
+type UserResponse struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func FromDomainUser(user domain.User) UserResponse {
+	return UserResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		Role:      string(user.Role),
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+func FromDomainUsers(users []domain.User) []UserResponse {
+	resp := make([]UserResponse, 0, len(users))
+	for _, user := range users {
+		resp = append(resp, FromDomainUser(user))
+	}
+	return resp
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
 	"comments-system/internal/domain"
 	"comments-system/internal/http-server/handler/comments/dto"
+	"comments-system/internal/ratelimit"
 	comments_usecase "comments-system/internal/usecase/comments"
 
 	"github.com/go-chi/chi/v5"
@@ -19,19 +22,31 @@ import (
 
 type CommentsHandler struct {
 	usecase  commentsUsecase
+	events   eventStream
 	logger   *zlog.Zerolog
 	validate *validator.Validate
 }
 
-func NewCommentsHandler(usecase commentsUsecase, logger *zlog.Zerolog) *CommentsHandler {
+func NewCommentsHandler(usecase commentsUsecase, events eventStream, logger *zlog.Zerolog) *CommentsHandler {
 	return &CommentsHandler{
 		usecase:  usecase,
+		events:   events,
 		logger:   logger,
 		validate: validator.New(),
 	}
 }
 
 func (h *CommentsHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	h.createComment(w, r, "")
+}
+
+// CreateThreadComment posts a comment to the thread named in the path,
+// creating the thread if this is its first comment.
+func (h *CommentsHandler) CreateThreadComment(w http.ResponseWriter, r *http.Request) {
+	h.createComment(w, r, chi.URLParam(r, "key"))
+}
+
+func (h *CommentsHandler) createComment(w http.ResponseWriter, r *http.Request, threadKey string) {
 	var req dto.CreateCommentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error().Err(err).Msg("Failed to decode request body")
@@ -46,9 +61,10 @@ func (h *CommentsHandler) CreateComment(w http.ResponseWriter, r *http.Request)
 	}
 
 	comment := domain.Comment{
-		ParentID: req.ParentID,
-		Content:  req.Content,
-		Author:   req.Author,
+		ParentID:  req.ParentID,
+		ThreadKey: threadKey,
+		Content:   req.Content,
+		Author:    req.Author,
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
@@ -62,7 +78,18 @@ func (h *CommentsHandler) CreateComment(w http.ResponseWriter, r *http.Request)
 			http.Error(w, "Parent comment not found", http.StatusBadRequest)
 			return
 		}
+		if errors.Is(err, comments_usecase.ErrSpamDetected) {
+			http.Error(w, "Comment rejected as spam", http.StatusUnprocessableEntity)
+			return
+		}
+		var limitErr *comments_usecase.RateLimitError
+		if errors.As(err, &limitErr) {
+			ratelimit.SetHeaders(w.Header(), limitErr.Status)
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
 		if errors.Is(err, comments_usecase.ErrContentRequired) ||
+			errors.Is(err, comments_usecase.ErrInvalidThreadKey) ||
 			errors.Is(err, comments_usecase.ErrAuthorRequired) ||
 			errors.Is(err, comments_usecase.ErrContentTooLong) ||
 			errors.Is(err, comments_usecase.ErrAuthorTooLong) {
@@ -74,14 +101,7 @@ func (h *CommentsHandler) CreateComment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	resp := dto.CommentResponse{
-		ID:        createdComment.ID,
-		ParentID:  createdComment.ParentID,
-		Content:   createdComment.Content,
-		Author:    createdComment.Author,
-		CreatedAt: createdComment.CreatedAt,
-		UpdatedAt: createdComment.UpdatedAt,
-	}
+	resp := dto.FromDomainComment(createdComment)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -91,7 +111,17 @@ func (h *CommentsHandler) CreateComment(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *CommentsHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	h.getComments(w, r, "")
+}
+
+// GetThreadComments lists the comments of the thread named in the path.
+func (h *CommentsHandler) GetThreadComments(w http.ResponseWriter, r *http.Request) {
+	h.getComments(w, r, chi.URLParam(r, "key"))
+}
+
+func (h *CommentsHandler) getComments(w http.ResponseWriter, r *http.Request, threadKey string) {
 	var req dto.GetCommentsRequest
+	req.ThreadKey = threadKey
 
 	parentIDStr := r.URL.Query().Get("parent")
 	if parentIDStr != "" {
@@ -109,6 +139,31 @@ func (h *CommentsHandler) GetComments(w http.ResponseWriter, r *http.Request) {
 	req.Search = r.URL.Query().Get("search")
 	req.SortBy = r.URL.Query().Get("sort_by")
 	req.SortOrder = r.URL.Query().Get("sort_order")
+	req.ChildSort = r.URL.Query().Get("child_sort")
+	req.ChildOrder = r.URL.Query().Get("child_order")
+	req.MaxDepth, _ = strconv.Atoi(r.URL.Query().Get("max_depth"))
+	req.MaxChildren, _ = strconv.Atoi(r.URL.Query().Get("max_children"))
+	req.After = r.URL.Query().Get("after")
+	req.Before = r.URL.Query().Get("before")
+
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Invalid filter parameters")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Filter = filter
+
+	req.WithTotal = true
+	if withTotalStr := r.URL.Query().Get("with_total"); withTotalStr != "" {
+		withTotal, err := strconv.ParseBool(withTotalStr)
+		if err != nil {
+			h.logger.Error().Err(err).Str("with_total", withTotalStr).Msg("Invalid with_total")
+			http.Error(w, "Invalid with_total", http.StatusBadRequest)
+			return
+		}
+		req.WithTotal = withTotal
+	}
 
 	if err := req.Validate(); err != nil {
 		h.logger.Error().Err(err).Msg("Invalid query parameters")
@@ -119,7 +174,7 @@ func (h *CommentsHandler) GetComments(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	tree, err := h.usecase.GetComments(ctx, req.ParentID, req.Page, req.PageSize, req.Search, req.SortBy, req.SortOrder)
+	tree, err := h.usecase.GetComments(ctx, req.ToDomain())
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Failed to get comments")
 
@@ -127,6 +182,11 @@ func (h *CommentsHandler) GetComments(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Comment not found", http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, comments_usecase.ErrInvalidCursor) ||
+			errors.Is(err, comments_usecase.ErrInvalidThreadKey) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -140,6 +200,364 @@ func (h *CommentsHandler) GetComments(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *CommentsHandler) SearchComments(w http.ResponseWriter, r *http.Request) {
+	var req dto.SearchCommentsRequest
+
+	req.Query = r.URL.Query().Get("q")
+	req.ThreadKey = r.URL.Query().Get("thread")
+	req.Page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	req.PageSize, _ = strconv.Atoi(r.URL.Query().Get("page_size"))
+	req.SortBy = r.URL.Query().Get("sort_by")
+	req.SortOrder = r.URL.Query().Get("sort_order")
+
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Invalid filter parameters")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Filter = filter
+
+	req.WithTotal = true
+	if withTotalStr := r.URL.Query().Get("with_total"); withTotalStr != "" {
+		withTotal, err := strconv.ParseBool(withTotalStr)
+		if err != nil {
+			h.logger.Error().Err(err).Str("with_total", withTotalStr).Msg("Invalid with_total")
+			http.Error(w, "Invalid with_total", http.StatusBadRequest)
+			return
+		}
+		req.WithTotal = withTotal
+	}
+
+	if err := req.Validate(); err != nil {
+		h.logger.Error().Err(err).Msg("Invalid query parameters")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	results, err := h.usecase.SearchComments(ctx, req.ToDomain())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to search comments")
+
+		if errors.Is(err, comments_usecase.ErrSearchRequired) ||
+			errors.Is(err, comments_usecase.ErrInvalidThreadKey) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.FromDomainSearchResults(results)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *CommentsHandler) GetReplies(w http.ResponseWriter, r *http.Request) {
+	commentIDStr := chi.URLParam(r, "id")
+	commentID, err := strconv.Atoi(commentIDStr)
+	if err != nil {
+		h.logger.Error().Err(err).Str("comment_id", commentIDStr).Msg("Invalid comment ID")
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.GetRepliesRequest
+	req.Cursor = r.URL.Query().Get("cursor")
+	req.MaxDepth, _ = strconv.Atoi(r.URL.Query().Get("max_depth"))
+	req.MaxChildren, _ = strconv.Atoi(r.URL.Query().Get("max_children"))
+	req.SortBy = r.URL.Query().Get("sort_by")
+	req.SortOrder = r.URL.Query().Get("sort_order")
+	req.ChildSort = r.URL.Query().Get("child_sort")
+	req.ChildOrder = r.URL.Query().Get("child_order")
+
+	if err := req.Validate(); err != nil {
+		h.logger.Error().Err(err).Msg("Invalid query parameters")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	replies, err := h.usecase.GetReplies(ctx, req.Cursor, req.ToDomain(commentID))
+	if err != nil {
+		h.logger.Error().Err(err).Int("comment_id", commentID).Msg("Failed to get replies")
+
+		if errors.Is(err, comments_usecase.ErrCommentNotFound) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrInvalidCommentID) ||
+			errors.Is(err, comments_usecase.ErrInvalidCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.FromDomainCommentReplies(replies)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *CommentsHandler) GetComment(w http.ResponseWriter, r *http.Request) {
+	commentIDStr := chi.URLParam(r, "id")
+	commentID, err := strconv.Atoi(commentIDStr)
+	if err != nil {
+		h.logger.Error().Err(err).Str("comment_id", commentIDStr).Msg("Invalid comment ID")
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	comment, err := h.usecase.GetComment(ctx, commentID)
+	if err != nil {
+		h.logger.Error().Err(err).Int("comment_id", commentID).Msg("Failed to get comment")
+
+		if errors.Is(err, comments_usecase.ErrCommentNotFound) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrInvalidCommentID) {
+			http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.FromDomainCommentDetails(comment)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *CommentsHandler) GetCommentContext(w http.ResponseWriter, r *http.Request) {
+	commentIDStr := chi.URLParam(r, "id")
+	commentID, err := strconv.Atoi(commentIDStr)
+	if err != nil {
+		h.logger.Error().Err(err).Str("comment_id", commentIDStr).Msg("Invalid comment ID")
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	depth := comments_usecase.DefaultContextDepth
+	if depthStr := r.URL.Query().Get("depth"); depthStr != "" {
+		depth, err = strconv.Atoi(depthStr)
+		if err != nil {
+			h.logger.Error().Err(err).Str("depth", depthStr).Msg("Invalid depth")
+			http.Error(w, "Invalid depth", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	commentContext, err := h.usecase.GetCommentContext(ctx, commentID, depth)
+	if err != nil {
+		h.logger.Error().Err(err).Int("comment_id", commentID).Msg("Failed to get comment context")
+
+		if errors.Is(err, comments_usecase.ErrCommentNotFound) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrInvalidCommentID) {
+			http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.FromDomainCommentContext(commentContext)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *CommentsHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	commentIDStr := chi.URLParam(r, "id")
+	commentID, err := strconv.Atoi(commentIDStr)
+	if err != nil {
+		h.logger.Error().Err(err).Str("comment_id", commentIDStr).Msg("Invalid comment ID")
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.UpdateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	updatedComment, err := h.usecase.UpdateComment(ctx, commentID, req.Content)
+	if err != nil {
+		h.logger.Error().Err(err).Int("comment_id", commentID).Msg("Failed to update comment")
+
+		if errors.Is(err, comments_usecase.ErrCommentNotFound) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrAuthenticationRequired) {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrCommentDeleted) {
+			http.Error(w, "Comment is deleted", http.StatusGone)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrInvalidCommentID) ||
+			errors.Is(err, comments_usecase.ErrContentRequired) ||
+			errors.Is(err, comments_usecase.ErrContentTooLong) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.FromDomainComment(updatedComment)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *CommentsHandler) VoteComment(w http.ResponseWriter, r *http.Request) {
+	commentIDStr := chi.URLParam(r, "id")
+	commentID, err := strconv.Atoi(commentIDStr)
+	if err != nil {
+		h.logger.Error().Err(err).Str("comment_id", commentIDStr).Msg("Invalid comment ID")
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.VoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	votedComment, err := h.usecase.VoteComment(ctx, commentID, req.Value)
+	if err != nil {
+		h.logger.Error().Err(err).Int("comment_id", commentID).Msg("Failed to vote on comment")
+
+		if errors.Is(err, comments_usecase.ErrCommentNotFound) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrCommentDeleted) {
+			http.Error(w, "Comment is deleted", http.StatusGone)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrInvalidCommentID) ||
+			errors.Is(err, comments_usecase.ErrVoterRequired) ||
+			errors.Is(err, comments_usecase.ErrInvalidVote) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.FromDomainComment(votedComment)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *CommentsHandler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	commentIDStr := chi.URLParam(r, "id")
+	commentID, err := strconv.Atoi(commentIDStr)
+	if err != nil {
+		h.logger.Error().Err(err).Str("comment_id", commentIDStr).Msg("Invalid comment ID")
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	revisions, err := h.usecase.GetRevisions(ctx, commentID)
+	if err != nil {
+		h.logger.Error().Err(err).Int("comment_id", commentID).Msg("Failed to get comment revisions")
+
+		if errors.Is(err, comments_usecase.ErrCommentNotFound) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrCommentDeleted) {
+			http.Error(w, "Comment is deleted", http.StatusGone)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrInvalidCommentID) {
+			http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.FromDomainRevisions(revisions)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
 func (h *CommentsHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	commentIDStr := chi.URLParam(r, "id")
 	commentID, err := strconv.Atoi(commentIDStr)
@@ -160,6 +578,14 @@ func (h *CommentsHandler) DeleteComment(w http.ResponseWriter, r *http.Request)
 			http.Error(w, "Comment not found", http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, comments_usecase.ErrAuthenticationRequired) {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		if errors.Is(err, comments_usecase.ErrInvalidCommentID) {
 			http.Error(w, "Invalid comment ID", http.StatusBadRequest)
 			return
@@ -171,3 +597,180 @@ func (h *CommentsHandler) DeleteComment(w http.ResponseWriter, r *http.Request)
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h *CommentsHandler) RestoreComment(w http.ResponseWriter, r *http.Request) {
+	commentIDStr := chi.URLParam(r, "id")
+	commentID, err := strconv.Atoi(commentIDStr)
+	if err != nil {
+		h.logger.Error().Err(err).Str("comment_id", commentIDStr).Msg("Invalid comment ID")
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	restoredComment, err := h.usecase.RestoreComment(ctx, commentID)
+	if err != nil {
+		h.logger.Error().Err(err).Int("comment_id", commentID).Msg("Failed to restore comment")
+
+		if errors.Is(err, comments_usecase.ErrCommentNotFound) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrAuthenticationRequired) {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrCommentNotDeleted) {
+			http.Error(w, "Comment is not deleted", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrInvalidCommentID) {
+			http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.FromDomainComment(restoredComment)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// RevokeManageToken disables the management token of a guest comment.
+func (h *CommentsHandler) RevokeManageToken(w http.ResponseWriter, r *http.Request) {
+	commentIDStr := chi.URLParam(r, "id")
+	commentID, err := strconv.Atoi(commentIDStr)
+	if err != nil {
+		h.logger.Error().Err(err).Str("comment_id", commentIDStr).Msg("Invalid comment ID")
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err = h.usecase.RevokeManageToken(ctx, commentID)
+	if err != nil {
+		h.logger.Error().Err(err).Int("comment_id", commentID).Msg("Failed to revoke manage token")
+
+		if errors.Is(err, comments_usecase.ErrCommentNotFound) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrAuthenticationRequired) {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrInvalidCommentID) {
+			http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CommentsHandler) PurgeComment(w http.ResponseWriter, r *http.Request) {
+	commentIDStr := chi.URLParam(r, "id")
+	commentID, err := strconv.Atoi(commentIDStr)
+	if err != nil {
+		h.logger.Error().Err(err).Str("comment_id", commentIDStr).Msg("Invalid comment ID")
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err = h.usecase.PurgeComment(ctx, commentID)
+	if err != nil {
+		h.logger.Error().Err(err).Int("comment_id", commentID).Msg("Failed to purge comment")
+
+		if errors.Is(err, comments_usecase.ErrCommentNotFound) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrInvalidCommentID) {
+			http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseFilter reads the structured filter parameters shared by the listing
+// and search endpoints. Dates are accepted as RFC 3339 timestamps or as
+// plain YYYY-MM-DD days.
+func parseFilter(query url.Values) (dto.CommentFilterRequest, error) {
+	var filter dto.CommentFilterRequest
+
+	filter.Author = query.Get("author")
+	filter.AuthorMode = query.Get("author_mode")
+
+	if s := query.Get("created_after"); s != "" {
+		t, err := parseFilterTime(s)
+		if err != nil {
+			return filter, fmt.Errorf("invalid created_after: %s", s)
+		}
+		filter.CreatedAfter = &t
+	}
+	if s := query.Get("created_before"); s != "" {
+		t, err := parseFilterTime(s)
+		if err != nil {
+			return filter, fmt.Errorf("invalid created_before: %s", s)
+		}
+		filter.CreatedBefore = &t
+	}
+	if s := query.Get("root_id"); s != "" {
+		rootID, err := strconv.Atoi(s)
+		if err != nil {
+			return filter, fmt.Errorf("invalid root_id: %s", s)
+		}
+		filter.RootID = &rootID
+	}
+	if s := query.Get("min_replies"); s != "" {
+		minReplies, err := strconv.Atoi(s)
+		if err != nil {
+			return filter, fmt.Errorf("invalid min_replies: %s", s)
+		}
+		filter.MinReplies = minReplies
+	}
+	if s := query.Get("is_root"); s != "" {
+		isRoot, err := strconv.ParseBool(s)
+		if err != nil {
+			return filter, fmt.Errorf("invalid is_root: %s", s)
+		}
+		filter.IsRoot = &isRoot
+	}
+
+	return filter, nil
+}
+
+func parseFilterTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
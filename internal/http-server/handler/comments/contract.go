@@ -2,11 +2,32 @@ package comments
 
 import (
 	"comments-system/internal/domain"
+	"comments-system/internal/events"
 	"context"
 )
 
 type commentsUsecase interface {
 	CreateComment(ctx context.Context, comment domain.Comment) (domain.Comment, error)
-	GetComments(ctx context.Context, parentID *int, page, pageSize int, searchQuery, sortBy, sortOrder string) (domain.CommentTree, error)
+	GetComments(ctx context.Context, q domain.CommentsQuery) (domain.CommentTree, error)
+	SearchComments(ctx context.Context, q domain.CommentsQuery) (domain.SearchResults, error)
+	GetReplies(ctx context.Context, cursor string, q domain.RepliesQuery) (domain.CommentReplies, error)
+	GetComment(ctx context.Context, id int) (domain.Comment, error)
+	GetCommentContext(ctx context.Context, id, depth int) (domain.CommentContext, error)
+	UpdateComment(ctx context.Context, id int, content string) (domain.Comment, error)
+	VoteComment(ctx context.Context, id int, value int) (domain.Comment, error)
+	GetRevisions(ctx context.Context, id int) ([]domain.CommentRevision, error)
 	DeleteComment(ctx context.Context, id int) error
+	RestoreComment(ctx context.Context, id int) (domain.Comment, error)
+	RevokeManageToken(ctx context.Context, id int) error
+	PurgeComment(ctx context.Context, id int) error
+	ListModerationQueue(ctx context.Context, status domain.CommentStatus, page, pageSize int) (domain.ModerationQueue, error)
+	ModerateComments(ctx context.Context, ids []int, status domain.CommentStatus) (int, error)
+	FlagComment(ctx context.Context, id int, flag domain.Flag) (domain.FlagResult, error)
+	ListFlaggedComments(ctx context.Context, page, pageSize int) (domain.FlagInbox, error)
+	DismissFlags(ctx context.Context, ids []int) (int, error)
+	StreamFilter(ctx context.Context, rootID int, threadKey string) (func(domain.CommentEvent) (domain.CommentEvent, bool), error)
+}
+
+type eventStream interface {
+	Subscribe(filter events.Filter, resume bool, lastID uint64) (*events.Subscription, []domain.CommentEvent, bool)
 }
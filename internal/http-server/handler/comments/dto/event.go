@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+type CommentEventResponse struct {
+	Type       string          `json:"type"`
+	Comment    CommentResponse `json:"comment"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+func FromDomainCommentEvent(event domain.CommentEvent) CommentEventResponse {
+	return CommentEventResponse{
+		Type:       string(event.Type),
+		Comment:    FromDomainComment(event.Comment),
+		OccurredAt: event.OccurredAt,
+	}
+}
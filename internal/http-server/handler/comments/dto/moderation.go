@@ -0,0 +1,112 @@
+package dto
+
+import (
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type ModerationQueueRequest struct {
+	Status   string `query:"status" validate:"omitempty,oneof=pending approved rejected spam"`
+	Page     int    `query:"page" validate:"min=0"`
+	PageSize int    `query:"page_size" validate:"min=0,max=100"`
+}
+
+func (r *ModerationQueueRequest) Validate() error {
+	validate := validator.New()
+	return validate.Struct(r)
+}
+
+type ModerateCommentsRequest struct {
+	IDs []int `json:"ids" validate:"required,min=1,max=100,dive,min=1"`
+}
+
+type FlagInboxRequest struct {
+	Page     int `query:"page" validate:"min=0"`
+	PageSize int `query:"page_size" validate:"min=0,max=100"`
+}
+
+func (r *FlagInboxRequest) Validate() error {
+	validate := validator.New()
+	return validate.Struct(r)
+}
+
+type ModerationQueueResponse struct {
+	Comments []CommentResponse `json:"comments"`
+	Status   string            `json:"status"`
+	Total    int               `json:"total"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+}
+
+type ModerateCommentsResponse struct {
+	Status  string `json:"status"`
+	Updated int    `json:"updated"`
+}
+
+func FromDomainModerationQueue(queue domain.ModerationQueue) ModerationQueueResponse {
+	return ModerationQueueResponse{
+		Comments: FromDomainComments(queue.Comments),
+		Status:   string(queue.Status),
+		Total:    queue.Total,
+		Page:     queue.Page,
+		PageSize: queue.PageSize,
+	}
+}
+
+type FlagResponse struct {
+	ID        int       `json:"id"`
+	Reporter  string    `json:"reporter"`
+	Reason    string    `json:"reason"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FlaggedCommentResponse struct {
+	Comment   CommentResponse `json:"comment"`
+	FlagCount int             `json:"flag_count"`
+	Flags     []FlagResponse  `json:"flags"`
+}
+
+type FlagInboxResponse struct {
+	Comments []FlaggedCommentResponse `json:"comments"`
+	Total    int                      `json:"total"`
+	Page     int                      `json:"page"`
+	PageSize int                      `json:"page_size"`
+}
+
+type DismissFlagsResponse struct {
+	Dismissed int `json:"dismissed"`
+}
+
+func FromDomainFlagInbox(inbox domain.FlagInbox) FlagInboxResponse {
+	resp := FlagInboxResponse{
+		Comments: make([]FlaggedCommentResponse, len(inbox.Comments)),
+		Total:    inbox.Total,
+		Page:     inbox.Page,
+		PageSize: inbox.PageSize,
+	}
+
+	for i, flagged := range inbox.Comments {
+		flags := make([]FlagResponse, len(flagged.Flags))
+		for j, f := range flagged.Flags {
+			flags[j] = FlagResponse{
+				ID:        f.ID,
+				Reporter:  f.Reporter,
+				Reason:    string(f.Reason),
+				Note:      f.Note,
+				CreatedAt: f.CreatedAt,
+			}
+		}
+
+		resp.Comments[i] = FlaggedCommentResponse{
+			Comment:   FromDomainComment(flagged.Comment),
+			FlagCount: len(flags),
+			Flags:     flags,
+		}
+	}
+
+	return resp
+}
@@ -1,25 +1,140 @@
 package dto
 
 import (
+	"time"
+
+	"comments-system/internal/domain"
+
 	"github.com/go-playground/validator/v10"
 )
 
+// CreateCommentRequest is the body of a new comment. Author is required only
+// from guests; authenticated users post under their username.
 type CreateCommentRequest struct {
 	ParentID *int   `json:"parent_id,omitempty"`
-	Content  string `json:"content" validate:"required,min=1,max=1000"`
-	Author   string `json:"author" validate:"required,min=2,max=50"`
+	Content  string `json:"content" validate:"required,min=1,max=10000"`
+	Author   string `json:"author" validate:"omitempty,min=2,max=50"`
+}
+
+type UpdateCommentRequest struct {
+	Content string `json:"content" validate:"required,min=1,max=10000"`
+}
+
+type VoteRequest struct {
+	Value int `json:"value" validate:"oneof=-1 0 1"`
+}
+
+// CommentFilterRequest holds the structured filters shared by the listing and
+// search endpoints.
+type CommentFilterRequest struct {
+	Author        string     `query:"author" validate:"max=50"`
+	AuthorMode    string     `query:"author_mode" validate:"omitempty,oneof=exact prefix"`
+	CreatedAfter  *time.Time `query:"created_after"`
+	CreatedBefore *time.Time `query:"created_before"`
+	RootID        *int       `query:"root_id" validate:"omitempty,min=1"`
+	MinReplies    int        `query:"min_replies" validate:"min=0"`
+	IsRoot        *bool      `query:"is_root"`
+}
+
+func (r CommentFilterRequest) ToDomain() domain.CommentFilter {
+	return domain.CommentFilter{
+		Author:        r.Author,
+		AuthorPrefix:  r.AuthorMode == "prefix",
+		CreatedAfter:  r.CreatedAfter,
+		CreatedBefore: r.CreatedBefore,
+		RootID:        r.RootID,
+		MinReplies:    r.MinReplies,
+		IsRoot:        r.IsRoot,
+	}
 }
 
 type GetCommentsRequest struct {
-	ParentID  *int   `query:"parent"`
+	ThreadKey   string
+	ParentID    *int   `query:"parent"`
+	Page        int    `query:"page"`
+	PageSize    int    `query:"page_size"`
+	Search      string `query:"search"`
+	SortBy      string `query:"sort_by"`
+	SortOrder   string `query:"sort_order"`
+	ChildSort   string `query:"child_sort" validate:"omitempty,oneof=created_at updated_at id score best hot controversial"`
+	ChildOrder  string `query:"child_order" validate:"omitempty,oneof=asc desc"`
+	MaxDepth    int    `query:"max_depth" validate:"min=0"`
+	MaxChildren int    `query:"max_children" validate:"min=0"`
+	Filter      CommentFilterRequest
+	After       string `query:"after"`
+	Before      string `query:"before"`
+	WithTotal   bool   `query:"with_total"`
+}
+
+func (r *GetCommentsRequest) Validate() error {
+	if r.Page < 1 {
+		r.Page = 1
+	}
+	if r.PageSize < 1 {
+		r.PageSize = 10
+	}
+	if r.PageSize > 100 {
+		r.PageSize = 100
+	}
+
+	validSortFields := map[string]bool{
+		"created_at":    true,
+		"updated_at":    true,
+		"id":            true,
+		"relevance":     true,
+		"score":         true,
+		"best":          true,
+		"hot":           true,
+		"controversial": true,
+	}
+	validSortOrders := map[string]bool{
+		"asc":  true,
+		"desc": true,
+	}
+
+	if !validSortFields[r.SortBy] {
+		r.SortBy = "created_at"
+	}
+	if !validSortOrders[r.SortOrder] {
+		r.SortOrder = "desc"
+	}
+
+	validate := validator.New()
+	return validate.Struct(r)
+}
+
+func (r *GetCommentsRequest) ToDomain() domain.CommentsQuery {
+	return domain.CommentsQuery{
+		ThreadKey:   r.ThreadKey,
+		ParentID:    r.ParentID,
+		Page:        r.Page,
+		PageSize:    r.PageSize,
+		Search:      r.Search,
+		SortBy:      r.SortBy,
+		SortOrder:   r.SortOrder,
+		ChildSort:   r.ChildSort,
+		ChildOrder:  r.ChildOrder,
+		MaxDepth:    r.MaxDepth,
+		MaxChildren: r.MaxChildren,
+		Filter:      r.Filter.ToDomain(),
+		After:       r.After,
+		Before:      r.Before,
+		WithTotal:   r.WithTotal,
+	}
+}
+
+type SearchCommentsRequest struct {
+	Query     string `query:"q"`
+	ThreadKey string `query:"thread"`
 	Page      int    `query:"page"`
 	PageSize  int    `query:"page_size"`
-	Search    string `query:"search"`
 	SortBy    string `query:"sort_by"`
 	SortOrder string `query:"sort_order"`
+	Filter    CommentFilterRequest
+	WithTotal bool `query:"with_total"`
 }
 
-func (r *GetCommentsRequest) Validate() error {
+func (r *SearchCommentsRequest) Validate() error {
 	if r.Page < 1 {
 		r.Page = 1
 	}
@@ -31,9 +146,14 @@ func (r *GetCommentsRequest) Validate() error {
 	}
 
 	validSortFields := map[string]bool{
-		"created_at": true,
-		"updated_at": true,
-		"id":         true,
+		"relevance":     true,
+		"created_at":    true,
+		"updated_at":    true,
+		"id":            true,
+		"score":         true,
+		"best":          true,
+		"hot":           true,
+		"controversial": true,
 	}
 	validSortOrders := map[string]bool{
 		"asc":  true,
@@ -41,7 +161,7 @@ func (r *GetCommentsRequest) Validate() error {
 	}
 
 	if !validSortFields[r.SortBy] {
-		r.SortBy = "created_at"
+		r.SortBy = "relevance"
 	}
 	if !validSortOrders[r.SortOrder] {
 		r.SortOrder = "desc"
@@ -50,3 +170,55 @@ func (r *GetCommentsRequest) Validate() error {
 	validate := validator.New()
 	return validate.Struct(r)
 }
+
+func (r *SearchCommentsRequest) ToDomain() domain.CommentsQuery {
+	return domain.CommentsQuery{
+		ThreadKey: r.ThreadKey,
+		Page:      r.Page,
+		PageSize:  r.PageSize,
+		Search:    r.Query,
+		SortBy:    r.SortBy,
+		SortOrder: r.SortOrder,
+		Filter:    r.Filter.ToDomain(),
+		WithTotal: r.WithTotal,
+	}
+}
+
+type GetRepliesRequest struct {
+	Cursor      string `query:"cursor"`
+	MaxDepth    int    `query:"max_depth" validate:"min=0"`
+	MaxChildren int    `query:"max_children" validate:"min=0"`
+	SortBy      string `query:"sort_by" validate:"omitempty,oneof=created_at updated_at id relevance score best hot controversial"`
+	SortOrder   string `query:"sort_order" validate:"omitempty,oneof=asc desc"`
+	ChildSort   string `query:"child_sort" validate:"omitempty,oneof=created_at updated_at id score best hot controversial"`
+	ChildOrder  string `query:"child_order" validate:"omitempty,oneof=asc desc"`
+}
+
+func (r *GetRepliesRequest) Validate() error {
+	validate := validator.New()
+	return validate.Struct(r)
+}
+
+func (r *GetRepliesRequest) ToDomain(parentID int) domain.RepliesQuery {
+	return domain.RepliesQuery{
+		ParentID:    parentID,
+		MaxDepth:    r.MaxDepth,
+		MaxChildren: r.MaxChildren,
+		SortBy:      r.SortBy,
+		SortOrder:   r.SortOrder,
+		ChildSort:   r.ChildSort,
+		ChildOrder:  r.ChildOrder,
+	}
+}
+
+type FlagRequest struct {
+	Reason string `json:"reason" validate:"required,oneof=spam abuse off_topic other"`
+	Note   string `json:"note" validate:"max=500"`
+}
+
+func (r *FlagRequest) ToDomain() domain.Flag {
+	return domain.Flag{
+		Reason: domain.FlagReason(r.Reason),
+		Note:   r.Note,
+	}
+}
@@ -8,30 +8,115 @@ import (
 type CommentResponse struct {
 	ID        int               `json:"id"`
 	ParentID  *int              `json:"parent_id,omitempty"`
+	RootID    int               `json:"root_id"`
+	ThreadKey string            `json:"thread_key"`
 	Content   string            `json:"content"`
 	Author    string            `json:"author"`
+	UserID    *int              `json:"user_id,omitempty"`
+	Status    string            `json:"status"`
+	Deleted   bool              `json:"deleted,omitempty"`
 	CreatedAt time.Time         `json:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at"`
 	Children  []CommentResponse `json:"children,omitempty"`
+
+	Upvotes   int `json:"upvotes"`
+	Downvotes int `json:"downvotes"`
+	Score     int `json:"score"`
+
+	RemainingChildren int    `json:"remaining_children,omitempty"`
+	RepliesCursor     string `json:"replies_cursor,omitempty"`
+	Headline          string `json:"headline,omitempty"`
+	Matched           bool   `json:"matched,omitempty"`
+
+	// ManageToken is returned once, when a guest creates the comment.
+	ManageToken string `json:"manage_token,omitempty"`
 }
 
 type CommentsResponse struct {
-	Comments []CommentResponse `json:"comments"`
-	Total    int               `json:"total"`
-	Page     int               `json:"page"`
-	PageSize int               `json:"page_size"`
-	HasNext  bool              `json:"has_next"`
-	HasPrev  bool              `json:"has_prev"`
+	Comments   []CommentResponse `json:"comments"`
+	Total      *int              `json:"total,omitempty"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+	HasNext    bool              `json:"has_next"`
+	HasPrev    bool              `json:"has_prev"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	PrevCursor string            `json:"prev_cursor,omitempty"`
+}
+
+type SearchHitResponse struct {
+	RootID    int               `json:"root_id"`
+	Ancestors []CommentResponse `json:"ancestors"`
+	Comment   CommentResponse   `json:"comment"`
+}
+
+type SearchResponse struct {
+	Results  []SearchHitResponse `json:"results"`
+	Total    *int                `json:"total,omitempty"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+	HasNext  bool                `json:"has_next"`
+	HasPrev  bool                `json:"has_prev"`
+}
+
+type RepliesResponse struct {
+	Comments          []CommentResponse `json:"comments"`
+	RemainingChildren int               `json:"remaining_children"`
+	NextCursor        string            `json:"next_cursor,omitempty"`
+}
+
+type CommentDetailsResponse struct {
+	CommentResponse
+	ReplyCount      int `json:"reply_count"`
+	DescendantCount int `json:"descendant_count"`
+}
+
+type CommentContextResponse struct {
+	Ancestors []CommentResponse      `json:"ancestors"`
+	Comment   CommentDetailsResponse `json:"comment"`
+}
+
+type RevisionResponse struct {
+	ID        int       `json:"id"`
+	CommentID int       `json:"comment_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RevisionsResponse struct {
+	Revisions []RevisionResponse `json:"revisions"`
 }
 
 func FromDomainComment(comment domain.Comment) CommentResponse {
 	resp := CommentResponse{
 		ID:        comment.ID,
 		ParentID:  comment.ParentID,
+		RootID:    comment.RootID,
+		ThreadKey: comment.ThreadKey,
 		Content:   comment.Content,
 		Author:    comment.Author,
+		UserID:    comment.UserID,
+		Status:    string(comment.Status),
 		CreatedAt: comment.CreatedAt,
 		UpdatedAt: comment.UpdatedAt,
+
+		Upvotes:   comment.Upvotes,
+		Downvotes: comment.Downvotes,
+		Score:     comment.Score(),
+
+		RemainingChildren: comment.RemainingChildren,
+		RepliesCursor:     comment.RepliesCursor,
+		Headline:          comment.Headline,
+		Matched:           comment.Matched,
+
+		ManageToken: comment.ManageToken,
+	}
+
+	if comment.IsDeleted() {
+		resp.Content = domain.DeletedPlaceholder
+		resp.Author = domain.DeletedPlaceholder
+		resp.UserID = nil
+		resp.Deleted = true
+		resp.Headline = ""
 	}
 
 	if len(comment.Children) > 0 {
@@ -53,12 +138,83 @@ func FromDomainComments(comments []domain.Comment) []CommentResponse {
 }
 
 func FromDomainCommentTree(tree domain.CommentTree) CommentsResponse {
-	return CommentsResponse{
-		Comments: FromDomainComments(tree.Comments),
-		Total:    tree.Total,
-		Page:     tree.Page,
-		PageSize: tree.PageSize,
-		HasNext:  tree.HasNext,
-		HasPrev:  tree.HasPrev,
+	resp := CommentsResponse{
+		Comments:   FromDomainComments(tree.Comments),
+		Page:       tree.Page,
+		PageSize:   tree.PageSize,
+		HasNext:    tree.HasNext,
+		HasPrev:    tree.HasPrev,
+		NextCursor: tree.NextCursor,
+		PrevCursor: tree.PrevCursor,
+	}
+
+	if tree.HasTotal {
+		total := tree.Total
+		resp.Total = &total
 	}
+
+	return resp
+}
+
+func FromDomainSearchResults(results domain.SearchResults) SearchResponse {
+	resp := SearchResponse{
+		Results:  make([]SearchHitResponse, len(results.Hits)),
+		Page:     results.Page,
+		PageSize: results.PageSize,
+		HasNext:  results.HasNext,
+		HasPrev:  results.HasPrev,
+	}
+
+	for i, hit := range results.Hits {
+		resp.Results[i] = SearchHitResponse{
+			RootID:    hit.RootID,
+			Ancestors: FromDomainComments(hit.Ancestors),
+			Comment:   FromDomainComment(hit.Comment),
+		}
+	}
+
+	if results.HasTotal {
+		total := results.Total
+		resp.Total = &total
+	}
+
+	return resp
+}
+
+func FromDomainCommentReplies(replies domain.CommentReplies) RepliesResponse {
+	return RepliesResponse{
+		Comments:          FromDomainComments(replies.Comments),
+		RemainingChildren: replies.Remaining,
+		NextCursor:        replies.NextCursor,
+	}
+}
+
+func FromDomainCommentDetails(comment domain.Comment) CommentDetailsResponse {
+	return CommentDetailsResponse{
+		CommentResponse: FromDomainComment(comment),
+		ReplyCount:      comment.ReplyCount,
+		DescendantCount: comment.DescendantCount,
+	}
+}
+
+func FromDomainCommentContext(commentContext domain.CommentContext) CommentContextResponse {
+	return CommentContextResponse{
+		Ancestors: FromDomainComments(commentContext.Ancestors),
+		Comment:   FromDomainCommentDetails(commentContext.Comment),
+	}
+}
+
+func FromDomainRevisions(revisions []domain.CommentRevision) RevisionsResponse {
+	resp := RevisionsResponse{
+		Revisions: make([]RevisionResponse, len(revisions)),
+	}
+	for i, rev := range revisions {
+		resp.Revisions[i] = RevisionResponse{
+			ID:        rev.ID,
+			CommentID: rev.CommentID,
+			Content:   rev.Content,
+			CreatedAt: rev.CreatedAt,
+		}
+	}
+	return resp
 }
@@ -0,0 +1,140 @@
+package comments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"comments-system/internal/http-server/handler/comments/dto"
+	comments_usecase "comments-system/internal/usecase/comments"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// FlagComment reports a comment to the moderators of the site.
+func (h *CommentsHandler) FlagComment(w http.ResponseWriter, r *http.Request) {
+	commentIDStr := chi.URLParam(r, "id")
+	commentID, err := strconv.Atoi(commentIDStr)
+	if err != nil {
+		h.logger.Error().Err(err).Str("comment_id", commentIDStr).Msg("Invalid comment ID")
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.FlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	result, err := h.usecase.FlagComment(ctx, commentID, req.ToDomain())
+	if err != nil {
+		h.logger.Error().Err(err).Int("comment_id", commentID).Msg("Failed to flag comment")
+
+		if errors.Is(err, comments_usecase.ErrCommentNotFound) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrCommentDeleted) {
+			http.Error(w, "Comment is deleted", http.StatusGone)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrAlreadyFlagged) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrInvalidCommentID) ||
+			errors.Is(err, comments_usecase.ErrReporterRequired) ||
+			errors.Is(err, comments_usecase.ErrInvalidFlagReason) ||
+			errors.Is(err, comments_usecase.ErrFlagNoteTooLong) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if result.Hidden {
+		h.logger.Info().Int("comment_id", commentID).Int("open_flags", result.OpenFlags).Msg("Flagged comment hidden")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// FlagInbox lists the comments of the site with open flags.
+func (h *CommentsHandler) FlagInbox(w http.ResponseWriter, r *http.Request) {
+	var req dto.FlagInboxRequest
+	req.Page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	req.PageSize, _ = strconv.Atoi(r.URL.Query().Get("page_size"))
+
+	if err := req.Validate(); err != nil {
+		h.logger.Error().Err(err).Msg("Invalid query parameters")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	inbox, err := h.usecase.ListFlaggedComments(ctx, req.Page, req.PageSize)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to list flagged comments")
+		h.writeModerationError(w, err)
+		return
+	}
+
+	resp := dto.FromDomainFlagInbox(inbox)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// DismissFlags closes the open flags of the comments listed in the request
+// body and leaves the comments as they are.
+func (h *CommentsHandler) DismissFlags(w http.ResponseWriter, r *http.Request) {
+	var req dto.ModerateCommentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	dismissed, err := h.usecase.DismissFlags(ctx, req.IDs)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to dismiss flags")
+		h.writeModerationError(w, err)
+		return
+	}
+
+	resp := dto.DismissFlagsResponse{Dismissed: dismissed}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
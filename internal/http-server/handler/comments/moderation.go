@@ -0,0 +1,114 @@
+package comments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"comments-system/internal/domain"
+	"comments-system/internal/http-server/handler/comments/dto"
+	comments_usecase "comments-system/internal/usecase/comments"
+)
+
+// ModerationQueue lists the comments of the site with the status given in
+// the query, pending ones by default.
+func (h *CommentsHandler) ModerationQueue(w http.ResponseWriter, r *http.Request) {
+	var req dto.ModerationQueueRequest
+	req.Status = r.URL.Query().Get("status")
+	req.Page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	req.PageSize, _ = strconv.Atoi(r.URL.Query().Get("page_size"))
+
+	if err := req.Validate(); err != nil {
+		h.logger.Error().Err(err).Msg("Invalid query parameters")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	queue, err := h.usecase.ListModerationQueue(ctx, domain.CommentStatus(req.Status), req.Page, req.PageSize)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to list moderation queue")
+		h.writeModerationError(w, err)
+		return
+	}
+
+	resp := dto.FromDomainModerationQueue(queue)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// ApproveComments publishes the comments listed in the request body.
+func (h *CommentsHandler) ApproveComments(w http.ResponseWriter, r *http.Request) {
+	h.moderateComments(w, r, domain.StatusApproved)
+}
+
+// RejectComments hides the comments listed in the request body.
+func (h *CommentsHandler) RejectComments(w http.ResponseWriter, r *http.Request) {
+	h.moderateComments(w, r, domain.StatusRejected)
+}
+
+// MarkSpam hides the comments listed in the request body as spam.
+func (h *CommentsHandler) MarkSpam(w http.ResponseWriter, r *http.Request) {
+	h.moderateComments(w, r, domain.StatusSpam)
+}
+
+func (h *CommentsHandler) moderateComments(w http.ResponseWriter, r *http.Request, status domain.CommentStatus) {
+	var req dto.ModerateCommentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	updated, err := h.usecase.ModerateComments(ctx, req.IDs, status)
+	if err != nil {
+		h.logger.Error().Err(err).Str("status", string(status)).Msg("Failed to moderate comments")
+		h.writeModerationError(w, err)
+		return
+	}
+
+	h.logger.Info().Str("status", string(status)).Ints("comment_ids", req.IDs).Int("updated", updated).Msg("Comments moderated")
+
+	resp := dto.ModerateCommentsResponse{
+		Status:  string(status),
+		Updated: updated,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *CommentsHandler) writeModerationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, comments_usecase.ErrAuthenticationRequired):
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+	case errors.Is(err, comments_usecase.ErrForbidden):
+		http.Error(w, "Moderator role required", http.StatusForbidden)
+	case errors.Is(err, comments_usecase.ErrInvalidStatus),
+		errors.Is(err, comments_usecase.ErrNoCommentIDs),
+		errors.Is(err, comments_usecase.ErrTooManyCommentIDs),
+		errors.Is(err, comments_usecase.ErrInvalidCommentID):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,136 @@
+package comments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"comments-system/internal/domain"
+	"comments-system/internal/http-server/handler/comments/dto"
+	comments_usecase "comments-system/internal/usecase/comments"
+)
+
+const (
+	// streamHeartbeat keeps idle streams from being closed by proxies.
+	streamHeartbeat = 25 * time.Second
+	// streamRetry is how long browsers wait before reconnecting, in ms.
+	streamRetry = 3000
+	// streamResetEvent tells a resuming client that events were missed and
+	// it should load the comments again.
+	streamResetEvent = "stream.reset"
+)
+
+// StreamComments sends the comment events the caller may see as
+// Server-Sent Events, optionally only those of the thread under the root
+// comment given in the root query parameter or of the thread key given in
+// thread. A client reconnecting with Last-Event-ID first gets the events it
+// missed.
+func (h *CommentsHandler) StreamComments(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var rootID int
+	if s := query.Get("root"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil || id <= 0 {
+			h.logger.Error().Str("root", s).Msg("Invalid root comment ID")
+			http.Error(w, "Invalid root comment ID", http.StatusBadRequest)
+			return
+		}
+		rootID = id
+	}
+
+	// Identity is read from the request context for the whole stream.
+	filter, err := h.usecase.StreamFilter(r.Context(), rootID, query.Get("thread"))
+	if err != nil {
+		h.logger.Error().Err(err).Int("root_id", rootID).Msg("Failed to open comment stream")
+
+		if errors.Is(err, comments_usecase.ErrCommentNotFound) {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, comments_usecase.ErrInvalidCommentID) ||
+			errors.Is(err, comments_usecase.ErrInvalidThreadKey) ||
+			errors.Is(err, comments_usecase.ErrNotRootComment) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	lastID, err := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	resume := err == nil
+
+	sub, missed, complete := h.events.Subscribe(filter, resume, lastID)
+	defer sub.Close()
+
+	// The stream outlives the write timeout of the server.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to clear write deadline of comment stream")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", streamRetry); err != nil {
+		return
+	}
+	if !complete {
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: {}\n\n", streamResetEvent); err != nil {
+			return
+		}
+	}
+	for _, event := range missed {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		h.logger.Error().Err(err).Msg("Comment stream cannot be flushed")
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			// A closed subscription fell behind or the server is shutting
+			// down; the client reconnects and resumes.
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event domain.CommentEvent) error {
+	data, err := json.Marshal(dto.FromDomainCommentEvent(event))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
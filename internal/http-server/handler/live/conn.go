@@ -0,0 +1,342 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"comments-system/internal/domain"
+	"comments-system/internal/http-server/handler/comments/dto"
+	comments_usecase "comments-system/internal/usecase/comments"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait = 10 * time.Second
+	// pongWait is how long a client may stay silent, pings included.
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+
+	maxMessageSize = 64 << 10
+	// sendQueue is how many messages a connection may fall behind before it
+	// is closed.
+	sendQueue = 64
+	// maxRooms is how many threads a connection may watch at once.
+	maxRooms = 50
+	// typingInterval is how often a connection may send typing signals;
+	// signals in between are ignored.
+	typingInterval = 2 * time.Second
+	postTimeout    = 10 * time.Second
+)
+
+// conn is a client connection. Messages of the client are handled one at a
+// time by the goroutine of the request, writes go through send.
+type conn struct {
+	handler *LiveHandler
+	ws      *websocket.Conn
+	// ctx carries the site and the user of the client.
+	ctx context.Context
+	ip  string
+
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// rooms and lastTyping belong to the reading goroutine.
+	rooms      map[room]struct{}
+	lastTyping time.Time
+}
+
+func newConn(handler *LiveHandler, ws *websocket.Conn, ctx context.Context, ip string) *conn {
+	return &conn{
+		handler: handler,
+		ws:      ws,
+		ctx:     ctx,
+		ip:      ip,
+		send:    make(chan []byte, sendQueue),
+		done:    make(chan struct{}),
+		rooms:   make(map[room]struct{}),
+	}
+}
+
+// trySend queues a message without waiting and reports whether there was
+// room for it.
+func (c *conn) trySend(data []byte) bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+	}
+
+	select {
+	case c.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// reply queues an answer to a message of the client, waiting for room in
+// the queue.
+func (c *conn) reply(msg serverMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		c.handler.logger.Error().Err(err).Msg("Failed to encode live message")
+		return
+	}
+
+	select {
+	case c.send <- data:
+	case <-c.done:
+	}
+}
+
+func (c *conn) replyError(requestID, text string) {
+	c.reply(serverMessage{Type: msgError, RequestID: requestID, Error: text})
+}
+
+// close ends the connection. It is safe to call more than once and from any
+// goroutine.
+func (c *conn) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.handler.hub.unregister(c)
+
+		msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "")
+		_ = c.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+		c.ws.Close()
+	})
+}
+
+// writeLoop writes the queued messages and pings the client until the
+// connection is closed.
+func (c *conn) writeLoop() {
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case data := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.ws.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.close()
+				return
+			}
+		case <-ping.C:
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// readLoop handles the messages of the client until it disconnects or stops
+// answering pings.
+func (c *conn) readLoop() {
+	c.ws.SetReadLimit(maxMessageSize)
+	c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				c.handler.logger.Warn().Err(err).Str("ip", c.ip).Msg("Live connection closed unexpectedly")
+			}
+			return
+		}
+		c.ws.SetReadDeadline(time.Now().Add(pongWait))
+
+		var msg clientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.replyError("", "invalid message")
+			continue
+		}
+
+		switch msg.Type {
+		case msgSubscribe:
+			c.subscribe(msg)
+		case msgUnsubscribe:
+			c.unsubscribe(msg)
+		case msgTyping:
+			c.typing(msg)
+		case msgPost:
+			c.post(msg)
+		default:
+			c.replyError(msg.RequestID, "unknown message type")
+		}
+	}
+}
+
+func (c *conn) room(root int) room {
+	var site int
+	if s, ok := domain.SiteFromContext(c.ctx); ok {
+		site = s.ID
+	}
+	return room{site: site, root: root}
+}
+
+// subscribe starts sending the client the events of the thread under a root
+// comment it may see.
+func (c *conn) subscribe(msg clientMessage) {
+	if msg.Root <= 0 {
+		c.replyError(msg.RequestID, "invalid root comment ID")
+		return
+	}
+
+	r := c.room(msg.Root)
+	if _, ok := c.rooms[r]; !ok {
+		if len(c.rooms) >= maxRooms {
+			c.replyError(msg.RequestID, "too many subscriptions")
+			return
+		}
+
+		filter, err := c.handler.usecase.StreamFilter(c.ctx, msg.Root, "")
+		if err != nil {
+			c.replyError(msg.RequestID, c.errorText(err))
+			return
+		}
+
+		c.handler.hub.join(c, r, filter)
+		c.rooms[r] = struct{}{}
+	}
+
+	c.reply(serverMessage{Type: msgSubscribed, RequestID: msg.RequestID, Root: msg.Root})
+}
+
+func (c *conn) unsubscribe(msg clientMessage) {
+	r := c.room(msg.Root)
+	if _, ok := c.rooms[r]; ok {
+		c.handler.hub.leave(c, r)
+		delete(c.rooms, r)
+	}
+
+	c.reply(serverMessage{Type: msgUnsubscribed, RequestID: msg.RequestID, Root: msg.Root})
+}
+
+// typing tells the others watching a thread that the client is writing a
+// reply. Signals are not stored and not answered.
+func (c *conn) typing(msg clientMessage) {
+	r := c.room(msg.Root)
+	if _, ok := c.rooms[r]; !ok {
+		c.replyError(msg.RequestID, "not subscribed to this thread")
+		return
+	}
+	if time.Since(c.lastTyping) < typingInterval {
+		return
+	}
+	c.lastTyping = time.Now()
+
+	author := strings.TrimSpace(msg.Author)
+	if user, ok := domain.UserFromContext(c.ctx); ok {
+		author = user.Username
+	} else if site, ok := domain.SiteFromContext(c.ctx); ok && len(author) > site.MaxAuthorLength {
+		author = truncate(author, site.MaxAuthorLength)
+	}
+
+	c.handler.hub.typing(c, r, serverMessage{
+		Type:     msgTyping,
+		Root:     msg.Root,
+		ParentID: msg.ParentID,
+		Author:   author,
+	})
+}
+
+// post creates a comment exactly as POST /api/comments does, counted against
+// the same rate limits.
+func (c *conn) post(msg clientMessage) {
+	ctx, cancel := context.WithTimeout(c.ctx, postTimeout)
+	defer cancel()
+
+	status, err := c.handler.limiter.Take(ctx, c.ip)
+	if err != nil {
+		c.handler.logger.Error().Err(err).Str("ip", c.ip).Msg("Failed to check rate limit")
+	} else if !status.Allowed {
+		c.reply(serverMessage{
+			Type:       msgError,
+			RequestID:  msg.RequestID,
+			Error:      "too many requests",
+			RetryAfter: retryAfter(status),
+		})
+		return
+	}
+
+	created, err := c.handler.usecase.CreateComment(ctx, domain.Comment{
+		ParentID:  msg.ParentID,
+		ThreadKey: msg.ThreadKey,
+		Content:   msg.Content,
+		Author:    msg.Author,
+	})
+	if err != nil {
+		var limitErr *comments_usecase.RateLimitError
+		if errors.As(err, &limitErr) {
+			c.reply(serverMessage{
+				Type:       msgError,
+				RequestID:  msg.RequestID,
+				Error:      err.Error(),
+				RetryAfter: retryAfter(limitErr.Status),
+			})
+			return
+		}
+
+		c.replyError(msg.RequestID, c.errorText(err))
+		return
+	}
+
+	comment := dto.FromDomainComment(created)
+	c.reply(serverMessage{
+		Type:      msgPosted,
+		RequestID: msg.RequestID,
+		Root:      created.RootID,
+		Comment:   &comment,
+	})
+}
+
+// errorText describes an error of the usecase to the client. Unexpected
+// errors are logged and reported without details.
+func (c *conn) errorText(err error) string {
+	switch {
+	case errors.Is(err, comments_usecase.ErrCommentNotFound):
+		return "comment not found"
+	case errors.Is(err, comments_usecase.ErrInvalidParentID):
+		return "parent comment not found"
+	case errors.Is(err, comments_usecase.ErrSpamDetected):
+		return "comment rejected as spam"
+	case errors.Is(err, comments_usecase.ErrInvalidCommentID),
+		errors.Is(err, comments_usecase.ErrNotRootComment),
+		errors.Is(err, comments_usecase.ErrInvalidThreadKey),
+		errors.Is(err, comments_usecase.ErrContentRequired),
+		errors.Is(err, comments_usecase.ErrAuthorRequired),
+		errors.Is(err, comments_usecase.ErrContentTooLong),
+		errors.Is(err, comments_usecase.ErrAuthorTooLong):
+		return err.Error()
+	}
+
+	c.handler.logger.Error().Err(err).Str("ip", c.ip).Msg("Live request failed")
+	return "internal server error"
+}
+
+// retryAfter returns the wait of a rejected request in whole seconds.
+func retryAfter(status domain.RateLimitStatus) int {
+	return max(int((status.RetryAfter+time.Second-1)/time.Second), 1)
+}
+
+// truncate cuts s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	for len(s) > n {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	return s
+}
@@ -0,0 +1,22 @@
+package live
+
+import (
+	"context"
+	"net/http"
+
+	"comments-system/internal/domain"
+)
+
+type commentsUsecase interface {
+	CreateComment(ctx context.Context, comment domain.Comment) (domain.Comment, error)
+	StreamFilter(ctx context.Context, rootID int, threadKey string) (func(domain.CommentEvent) (domain.CommentEvent, bool), error)
+}
+
+type eventStream interface {
+	Follow(ctx context.Context, handle func(domain.CommentEvent), gap func())
+}
+
+type ipLimiter interface {
+	ClientIP(r *http.Request) string
+	Take(ctx context.Context, ip string) (domain.RateLimitStatus, error)
+}
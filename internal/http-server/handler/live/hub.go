@@ -0,0 +1,173 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"comments-system/internal/domain"
+	"comments-system/internal/http-server/handler/comments/dto"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+// room is the thread under a root comment of a site.
+type room struct {
+	site int
+	root int
+}
+
+// Hub fans the comment events of the event bus and the typing signals of
+// clients out to the connections watching a thread. It never waits for a
+// connection: one that cannot keep up is closed.
+type Hub struct {
+	events eventStream
+	logger *zlog.Zerolog
+
+	mu    sync.RWMutex
+	rooms map[room]map[*conn]func(domain.CommentEvent) (domain.CommentEvent, bool)
+	// conns holds the rooms of each connection.
+	conns map[*conn]map[room]struct{}
+}
+
+func NewHub(events eventStream, logger *zlog.Zerolog) *Hub {
+	return &Hub{
+		events: events,
+		logger: logger,
+		rooms:  make(map[room]map[*conn]func(domain.CommentEvent) (domain.CommentEvent, bool)),
+		conns:  make(map[*conn]map[room]struct{}),
+	}
+}
+
+// Run relays the events of the bus until ctx is done. When events were
+// missed, the clients are told to reload.
+func (h *Hub) Run(ctx context.Context) {
+	h.events.Follow(ctx, h.dispatch, h.reset)
+}
+
+// dispatch sends an event to the connections watching its thread that may
+// see it.
+func (h *Hub) dispatch(event domain.CommentEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c, filter := range h.rooms[room{site: event.SiteID, root: event.Comment.RootID}] {
+		filtered, ok := filter(event)
+		if !ok {
+			continue
+		}
+
+		comment := dto.FromDomainComment(filtered.Comment)
+		h.sendOrDrop(c, serverMessage{
+			Type:    string(filtered.Type),
+			Root:    filtered.Comment.RootID,
+			Comment: &comment,
+		})
+	}
+}
+
+// typing tells the other connections watching a thread that someone is
+// writing a reply. Signals are dropped for connections that are behind.
+func (h *Hub) typing(from *conn, r room, msg serverMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.rooms[r] {
+		if c != from {
+			c.trySend(data)
+		}
+	}
+}
+
+// reset tells every connection that events were missed.
+func (h *Hub) reset() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.conns {
+		h.sendOrDrop(c, serverMessage{Type: msgReset})
+	}
+}
+
+// sendOrDrop sends a message the client must not miss, closing the
+// connection if its queue is full.
+func (h *Hub) sendOrDrop(c *conn, msg serverMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode live message")
+		return
+	}
+	if !c.trySend(data) {
+		h.logger.Warn().Str("ip", c.ip).Msg("Live connection is too slow, closing it")
+		go c.close()
+	}
+}
+
+func (h *Hub) register(c *conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conns[c] = make(map[room]struct{})
+}
+
+// unregister removes a connection from the hub and all its rooms.
+func (h *Hub) unregister(c *conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for r := range h.conns[c] {
+		h.leaveLocked(c, r)
+	}
+	delete(h.conns, c)
+}
+
+// join adds a connection to a room; filter decides which events of the
+// room it gets.
+func (h *Hub) join(c *conn, r room, filter func(domain.CommentEvent) (domain.CommentEvent, bool)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	rooms, ok := h.conns[c]
+	if !ok {
+		// The connection is already closed.
+		return
+	}
+	rooms[r] = struct{}{}
+	if h.rooms[r] == nil {
+		h.rooms[r] = make(map[*conn]func(domain.CommentEvent) (domain.CommentEvent, bool))
+	}
+	h.rooms[r][c] = filter
+}
+
+func (h *Hub) leave(c *conn, r room) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leaveLocked(c, r)
+}
+
+func (h *Hub) leaveLocked(c *conn, r room) {
+	delete(h.conns[c], r)
+	delete(h.rooms[r], c)
+	if len(h.rooms[r]) == 0 {
+		delete(h.rooms, r)
+	}
+}
+
+// Close closes all connections, e.g. for the server to shut down: hijacked
+// connections are not waited for by http.Server.Shutdown.
+func (h *Hub) Close() {
+	h.mu.RLock()
+	conns := make([]*conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.RUnlock()
+
+	for _, c := range conns {
+		c.close()
+	}
+}
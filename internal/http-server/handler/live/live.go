@@ -0,0 +1,72 @@
+package live
+
+import (
+	"net/http"
+	"net/url"
+
+	"comments-system/internal/domain"
+
+	"github.com/gorilla/websocket"
+	"github.com/wb-go/wbf/zlog"
+)
+
+type LiveHandler struct {
+	hub      *Hub
+	usecase  commentsUsecase
+	limiter  ipLimiter
+	logger   *zlog.Zerolog
+	upgrader websocket.Upgrader
+}
+
+// NewLiveHandler creates the WebSocket handler. limiter is the per-IP limit
+// of new comments shared with the HTTP API.
+func NewLiveHandler(hub *Hub, usecase commentsUsecase, limiter ipLimiter, logger *zlog.Zerolog) *LiveHandler {
+	return &LiveHandler{
+		hub:     hub,
+		usecase: usecase,
+		limiter: limiter,
+		logger:  logger,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			CheckOrigin:     checkOrigin,
+		},
+	}
+}
+
+// Connect upgrades the request to a WebSocket connection on which the client
+// watches threads, signals typing and posts comments as the user of the
+// request.
+func (h *LiveHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	ws, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already answered the request.
+		h.logger.Warn().Err(err).Str("ip", r.RemoteAddr).Msg("Failed to upgrade live connection")
+		return
+	}
+
+	c := newConn(h, ws, r.Context(), h.limiter.ClientIP(r))
+	h.hub.register(c)
+	defer c.close()
+
+	go c.writeLoop()
+	c.readLoop()
+}
+
+// checkOrigin lets browsers connect from the origin of the service itself or
+// from the origins the site allows explicitly. Unlike cross-origin HTTP
+// requests, a WebSocket connection would carry the session cookie of the
+// visitor to any page that opens it.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+		return true
+	}
+
+	site, ok := domain.SiteFromContext(r.Context())
+	return ok && len(site.AllowedOrigins) > 0 && site.AllowsOrigin(origin)
+}
@@ -0,0 +1,48 @@
+package live
+
+import (
+	"comments-system/internal/http-server/handler/comments/dto"
+)
+
+// Types of the messages clients send.
+const (
+	msgSubscribe   = "subscribe"
+	msgUnsubscribe = "unsubscribe"
+	msgTyping      = "typing"
+	msgPost        = "post"
+)
+
+// Types of the messages the server sends besides the comment events, which
+// are named after the event type.
+const (
+	msgSubscribed   = "subscribed"
+	msgUnsubscribed = "unsubscribed"
+	msgPosted       = "posted"
+	msgError        = "error"
+	// msgReset tells the client that events were missed and it should load
+	// its threads again.
+	msgReset = "stream.reset"
+)
+
+// clientMessage is a message from a client. RequestID is echoed in the reply
+// so the client can match them.
+type clientMessage struct {
+	Type      string `json:"type"`
+	RequestID string `json:"request_id,omitempty"`
+	Root      int    `json:"root,omitempty"`
+	ParentID  *int   `json:"parent_id,omitempty"`
+	ThreadKey string `json:"thread_key,omitempty"`
+	Content   string `json:"content,omitempty"`
+	Author    string `json:"author,omitempty"`
+}
+
+type serverMessage struct {
+	Type       string               `json:"type"`
+	RequestID  string               `json:"request_id,omitempty"`
+	Root       int                  `json:"root,omitempty"`
+	ParentID   *int                 `json:"parent_id,omitempty"`
+	Author     string               `json:"author,omitempty"`
+	Comment    *dto.CommentResponse `json:"comment,omitempty"`
+	Error      string               `json:"error,omitempty"`
+	RetryAfter int                  `json:"retry_after,omitempty"`
+}
@@ -0,0 +1,14 @@
+package notifications
+
+import (
+	"comments-system/internal/domain"
+	"context"
+)
+
+type notificationsUsecase interface {
+	ListNotifications(ctx context.Context, unreadOnly bool, page, pageSize int) (domain.NotificationInbox, error)
+	MarkRead(ctx context.Context, ids []int64, all bool) (int, error)
+	GetPreferences(ctx context.Context) (domain.NotificationPreferences, error)
+	UpdatePreferences(ctx context.Context, upd domain.NotificationPreferencesUpdate) (domain.NotificationPreferences, error)
+	ConfirmEmail(ctx context.Context, token string) (domain.NotificationPreferences, error)
+}
@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"comments-system/internal/domain"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type InboxRequest struct {
+	Unread   bool `query:"unread"`
+	Page     int  `query:"page" validate:"min=0"`
+	PageSize int  `query:"page_size" validate:"min=0,max=100"`
+}
+
+func (r *InboxRequest) Validate() error {
+	validate := validator.New()
+	return validate.Struct(r)
+}
+
+// MarkReadRequest marks the listed notifications read, or all of them.
+type MarkReadRequest struct {
+	IDs []int64 `json:"ids" validate:"required_without=All,max=100,dive,gt=0"`
+	All bool    `json:"all"`
+}
+
+// UpdatePreferencesRequest changes only the fields present in the body.
+type UpdatePreferencesRequest struct {
+	Replies   *bool   `json:"replies"`
+	Mentions  *bool   `json:"mentions"`
+	Email     *string `json:"email" validate:"omitempty,max=254"`
+	EmailMode *string `json:"email_mode" validate:"omitempty,oneof=off instant digest"`
+}
+
+func (r UpdatePreferencesRequest) ToDomain() domain.NotificationPreferencesUpdate {
+	upd := domain.NotificationPreferencesUpdate{
+		Replies:  r.Replies,
+		Mentions: r.Mentions,
+		Email:    r.Email,
+	}
+	if r.EmailMode != nil {
+		mode := domain.EmailMode(*r.EmailMode)
+		upd.EmailMode = &mode
+	}
+	return upd
+}
+
+type ConfirmEmailRequest struct {
+	Token string `json:"token" validate:"required,max=100"`
+}
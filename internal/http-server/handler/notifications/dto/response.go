@@ -0,0 +1,99 @@
+package dto
+
+import (
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+// NotificationCommentResponse is the comment a notification is about, with
+// what a client needs to show it and open its thread.
+type NotificationCommentResponse struct {
+	ID        int       `json:"id"`
+	ParentID  *int      `json:"parent_id,omitempty"`
+	RootID    int       `json:"root_id"`
+	ThreadKey string    `json:"thread_key"`
+	Author    string    `json:"author"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type NotificationResponse struct {
+	ID        int64                       `json:"id"`
+	Kind      string                      `json:"kind"`
+	Comment   NotificationCommentResponse `json:"comment"`
+	Read      bool                        `json:"read"`
+	ReadAt    *time.Time                  `json:"read_at,omitempty"`
+	CreatedAt time.Time                   `json:"created_at"`
+}
+
+type InboxResponse struct {
+	Notifications []NotificationResponse `json:"notifications"`
+	Unread        int                    `json:"unread"`
+	Total         int                    `json:"total"`
+	Page          int                    `json:"page"`
+	PageSize      int                    `json:"page_size"`
+}
+
+type MarkReadResponse struct {
+	Updated int `json:"updated"`
+}
+
+type PreferencesResponse struct {
+	Replies        bool       `json:"replies"`
+	Mentions       bool       `json:"mentions"`
+	Email          string     `json:"email"`
+	EmailConfirmed bool       `json:"email_confirmed"`
+	EmailMode      string     `json:"email_mode"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+func FromDomainNotification(n domain.Notification) NotificationResponse {
+	return NotificationResponse{
+		ID:   n.ID,
+		Kind: string(n.Kind),
+		Comment: NotificationCommentResponse{
+			ID:        n.Comment.ID,
+			ParentID:  n.Comment.ParentID,
+			RootID:    n.Comment.RootID,
+			ThreadKey: n.Comment.ThreadKey,
+			Author:    n.Comment.Author,
+			Content:   n.Comment.Content,
+			CreatedAt: n.Comment.CreatedAt,
+		},
+		Read:      n.IsRead(),
+		ReadAt:    n.ReadAt,
+		CreatedAt: n.CreatedAt,
+	}
+}
+
+func FromDomainInbox(inbox domain.NotificationInbox) InboxResponse {
+	notifications := make([]NotificationResponse, 0, len(inbox.Notifications))
+	for _, n := range inbox.Notifications {
+		notifications = append(notifications, FromDomainNotification(n))
+	}
+
+	return InboxResponse{
+		Notifications: notifications,
+		Unread:        inbox.Unread,
+		Total:         inbox.Total,
+		Page:          inbox.Page,
+		PageSize:      inbox.PageSize,
+	}
+}
+
+func FromDomainPreferences(prefs domain.NotificationPreferences) PreferencesResponse {
+	resp := PreferencesResponse{
+		Replies:        prefs.Replies,
+		Mentions:       prefs.Mentions,
+		Email:          prefs.Email,
+		EmailConfirmed: prefs.EmailConfirmed,
+		EmailMode:      string(prefs.EmailMode),
+	}
+	// Defaults that were never saved have no time.
+	if !prefs.UpdatedAt.IsZero() {
+		updatedAt := prefs.UpdatedAt
+		resp.UpdatedAt = &updatedAt
+	}
+	return resp
+}
@@ -0,0 +1,203 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"comments-system/internal/http-server/handler/notifications/dto"
+	notifications_usecase "comments-system/internal/usecase/notifications"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/wb-go/wbf/zlog"
+)
+
+type NotificationsHandler struct {
+	usecase  notificationsUsecase
+	logger   *zlog.Zerolog
+	validate *validator.Validate
+}
+
+func NewNotificationsHandler(usecase notificationsUsecase, logger *zlog.Zerolog) *NotificationsHandler {
+	return &NotificationsHandler{
+		usecase:  usecase,
+		logger:   logger,
+		validate: validator.New(),
+	}
+}
+
+// ListNotifications returns a page of the inbox of the user, or only of the
+// unread notifications with unread=true.
+func (h *NotificationsHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	var req dto.InboxRequest
+	req.Unread, _ = strconv.ParseBool(r.URL.Query().Get("unread"))
+	req.Page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	req.PageSize, _ = strconv.Atoi(r.URL.Query().Get("page_size"))
+
+	if err := req.Validate(); err != nil {
+		h.logger.Error().Err(err).Msg("Invalid query parameters")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	inbox, err := h.usecase.ListNotifications(ctx, req.Unread, req.Page, req.PageSize)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to list notifications")
+		h.writeError(w, err)
+		return
+	}
+
+	resp := dto.FromDomainInbox(inbox)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *NotificationsHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	var req dto.MarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	updated, err := h.usecase.MarkRead(ctx, req.IDs, req.All)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to mark notifications read")
+		h.writeError(w, err)
+		return
+	}
+
+	resp := dto.MarkReadResponse{Updated: updated}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *NotificationsHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	prefs, err := h.usecase.GetPreferences(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to get notification preferences")
+		h.writeError(w, err)
+		return
+	}
+
+	resp := dto.FromDomainPreferences(prefs)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *NotificationsHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	var req dto.UpdatePreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	prefs, err := h.usecase.UpdatePreferences(ctx, req.ToDomain())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to update notification preferences")
+		h.writeError(w, err)
+		return
+	}
+
+	resp := dto.FromDomainPreferences(prefs)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// ConfirmEmail confirms the email address of the user with the token sent to
+// it.
+func (h *NotificationsHandler) ConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	var req dto.ConfirmEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	prefs, err := h.usecase.ConfirmEmail(ctx, req.Token)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to confirm email")
+		h.writeError(w, err)
+		return
+	}
+
+	resp := dto.FromDomainPreferences(prefs)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *NotificationsHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, notifications_usecase.ErrAuthenticationRequired):
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+	case errors.Is(err, notifications_usecase.ErrEmailUnavailable) ||
+		errors.Is(err, notifications_usecase.ErrEmailNotConfirmed):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, notifications_usecase.ErrEmailTokenTooSoon):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, notifications_usecase.ErrEmailTokenNotSent):
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	case errors.Is(err, notifications_usecase.ErrInvalidNotificationID) ||
+		errors.Is(err, notifications_usecase.ErrNothingToMark) ||
+		errors.Is(err, notifications_usecase.ErrTooManyIDs) ||
+		errors.Is(err, notifications_usecase.ErrInvalidEmail) ||
+		errors.Is(err, notifications_usecase.ErrInvalidEmailMode) ||
+		errors.Is(err, notifications_usecase.ErrEmailRequired) ||
+		errors.Is(err, notifications_usecase.ErrInvalidEmailToken):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,15 @@
+package sites
+
+import (
+	"comments-system/internal/domain"
+	"context"
+)
+
+type sitesUsecase interface {
+	CreateSite(ctx context.Context, site domain.Site) (domain.Site, string, error)
+	ListSites(ctx context.Context) ([]domain.Site, error)
+	GetSite(ctx context.Context, id int) (domain.Site, error)
+	UpdateSite(ctx context.Context, id int, upd domain.SiteUpdate) (domain.Site, error)
+	RotateAPIKey(ctx context.Context, id int) (string, error)
+	DeleteSite(ctx context.Context, id int) error
+}
@@ -0,0 +1,44 @@
+package dto
+
+import "comments-system/internal/domain"
+
+type CreateSiteRequest struct {
+	Name             string   `json:"name" validate:"required,max=100"`
+	AllowedOrigins   []string `json:"allowed_origins"`
+	MaxContentLength int      `json:"max_content_length" validate:"omitempty,min=1,max=10000"`
+	MaxAuthorLength  int      `json:"max_author_length" validate:"omitempty,min=1,max=50"`
+	ModerationMode   string   `json:"moderation_mode" validate:"omitempty,oneof=none pre"`
+}
+
+func (r CreateSiteRequest) ToDomain() domain.Site {
+	return domain.Site{
+		Name:             r.Name,
+		AllowedOrigins:   r.AllowedOrigins,
+		MaxContentLength: r.MaxContentLength,
+		MaxAuthorLength:  r.MaxAuthorLength,
+		ModerationMode:   domain.ModerationMode(r.ModerationMode),
+	}
+}
+
+// UpdateSiteRequest changes only the fields present in the body.
+type UpdateSiteRequest struct {
+	Name             *string   `json:"name" validate:"omitempty,min=1,max=100"`
+	AllowedOrigins   *[]string `json:"allowed_origins"`
+	MaxContentLength *int      `json:"max_content_length" validate:"omitempty,min=1,max=10000"`
+	MaxAuthorLength  *int      `json:"max_author_length" validate:"omitempty,min=1,max=50"`
+	ModerationMode   *string   `json:"moderation_mode" validate:"omitempty,oneof=none pre"`
+}
+
+func (r UpdateSiteRequest) ToDomain() domain.SiteUpdate {
+	upd := domain.SiteUpdate{
+		Name:             r.Name,
+		AllowedOrigins:   r.AllowedOrigins,
+		MaxContentLength: r.MaxContentLength,
+		MaxAuthorLength:  r.MaxAuthorLength,
+	}
+	if r.ModerationMode != nil {
+		mode := domain.ModerationMode(*r.ModerationMode)
+		upd.ModerationMode = &mode
+	}
+	return upd
+}
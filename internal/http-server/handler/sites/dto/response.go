@@ -0,0 +1,54 @@
+package dto
+
+import (
+	"comments-system/internal/domain"
+	"time"
+)
+
+type SiteResponse struct {
+	ID               int       `json:"id"`
+	Name             string    `json:"name"`
+	AllowedOrigins   []string  `json:"allowed_origins"`
+	MaxContentLength int       `json:"max_content_length"`
+	MaxAuthorLength  int       `json:"max_author_length"`
+	ModerationMode   string    `json:"moderation_mode"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// CreateSiteResponse carries the API key of a new site. The key is not
+// stored and cannot be shown again.
+type CreateSiteResponse struct {
+	Site   SiteResponse `json:"site"`
+	APIKey string       `json:"api_key"`
+}
+
+type APIKeyResponse struct {
+	APIKey string `json:"api_key"`
+}
+
+func FromDomainSite(site domain.Site) SiteResponse {
+	origins := site.AllowedOrigins
+	if origins == nil {
+		origins = []string{}
+	}
+
+	return SiteResponse{
+		ID:               site.ID,
+		Name:             site.Name,
+		AllowedOrigins:   origins,
+		MaxContentLength: site.MaxContentLength,
+		MaxAuthorLength:  site.MaxAuthorLength,
+		ModerationMode:   string(site.ModerationMode),
+		CreatedAt:        site.CreatedAt,
+		UpdatedAt:        site.UpdatedAt,
+	}
+}
+
+func FromDomainSites(sites []domain.Site) []SiteResponse {
+	resp := make([]SiteResponse, 0, len(sites))
+	for _, site := range sites {
+		resp = append(resp, FromDomainSite(site))
+	}
+	return resp
+}
@@ -0,0 +1,252 @@
+package sites
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"comments-system/internal/domain"
+	"comments-system/internal/http-server/handler/sites/dto"
+	sites_usecase "comments-system/internal/usecase/sites"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/wb-go/wbf/zlog"
+)
+
+type SitesHandler struct {
+	usecase  sitesUsecase
+	logger   *zlog.Zerolog
+	validate *validator.Validate
+}
+
+func NewSitesHandler(usecase sitesUsecase, logger *zlog.Zerolog) *SitesHandler {
+	return &SitesHandler{
+		usecase:  usecase,
+		logger:   logger,
+		validate: validator.New(),
+	}
+}
+
+func (h *SitesHandler) CreateSite(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateSiteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	site, apiKey, err := h.usecase.CreateSite(ctx, req.ToDomain())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to create site")
+
+		if isValidationError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.CreateSiteResponse{
+		Site:   dto.FromDomainSite(site),
+		APIKey: apiKey,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *SitesHandler) ListSites(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	sites, err := h.usecase.ListSites(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to list sites")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.FromDomainSites(sites)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *SitesHandler) GetSite(w http.ResponseWriter, r *http.Request) {
+	siteID, ok := h.parseSiteID(w, r)
+	if !ok {
+		return
+	}
+	h.getSite(w, r, siteID)
+}
+
+// GetCurrentSite returns the settings of the site of the request.
+func (h *SitesHandler) GetCurrentSite(w http.ResponseWriter, r *http.Request) {
+	site, _ := domain.SiteFromContext(r.Context())
+	h.getSite(w, r, site.ID)
+}
+
+func (h *SitesHandler) getSite(w http.ResponseWriter, r *http.Request, siteID int) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	site, err := h.usecase.GetSite(ctx, siteID)
+	if err != nil {
+		h.logger.Error().Err(err).Int("site_id", siteID).Msg("Failed to get site")
+		h.writeError(w, err)
+		return
+	}
+
+	resp := dto.FromDomainSite(site)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *SitesHandler) UpdateSite(w http.ResponseWriter, r *http.Request) {
+	siteID, ok := h.parseSiteID(w, r)
+	if !ok {
+		return
+	}
+	h.updateSite(w, r, siteID)
+}
+
+// UpdateCurrentSite changes the settings of the site of the request.
+func (h *SitesHandler) UpdateCurrentSite(w http.ResponseWriter, r *http.Request) {
+	site, _ := domain.SiteFromContext(r.Context())
+	h.updateSite(w, r, site.ID)
+}
+
+func (h *SitesHandler) updateSite(w http.ResponseWriter, r *http.Request, siteID int) {
+
+	var req dto.UpdateSiteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	site, err := h.usecase.UpdateSite(ctx, siteID, req.ToDomain())
+	if err != nil {
+		h.logger.Error().Err(err).Int("site_id", siteID).Msg("Failed to update site")
+		h.writeError(w, err)
+		return
+	}
+
+	resp := dto.FromDomainSite(site)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// RotateAPIKey issues a new API key for a site and returns it. The previous
+// key is rejected from then on.
+func (h *SitesHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	siteID, ok := h.parseSiteID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	apiKey, err := h.usecase.RotateAPIKey(ctx, siteID)
+	if err != nil {
+		h.logger.Error().Err(err).Int("site_id", siteID).Msg("Failed to rotate API key")
+		h.writeError(w, err)
+		return
+	}
+
+	resp := dto.APIKeyResponse{APIKey: apiKey}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *SitesHandler) DeleteSite(w http.ResponseWriter, r *http.Request) {
+	siteID, ok := h.parseSiteID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := h.usecase.DeleteSite(ctx, siteID)
+	if err != nil {
+		h.logger.Error().Err(err).Int("site_id", siteID).Msg("Failed to delete site")
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SitesHandler) parseSiteID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	siteIDStr := chi.URLParam(r, "id")
+	siteID, err := strconv.Atoi(siteIDStr)
+	if err != nil {
+		h.logger.Error().Err(err).Str("site_id", siteIDStr).Msg("Invalid site ID")
+		http.Error(w, "Invalid site ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return siteID, true
+}
+
+func (h *SitesHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sites_usecase.ErrSiteNotFound):
+		http.Error(w, "Site not found", http.StatusNotFound)
+	case errors.Is(err, sites_usecase.ErrDefaultSite):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, sites_usecase.ErrInvalidSiteID) || isValidationError(err):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func isValidationError(err error) bool {
+	return errors.Is(err, sites_usecase.ErrNameRequired) ||
+		errors.Is(err, sites_usecase.ErrNameTooLong) ||
+		errors.Is(err, sites_usecase.ErrInvalidOrigin) ||
+		errors.Is(err, sites_usecase.ErrInvalidContentLimit) ||
+		errors.Is(err, sites_usecase.ErrInvalidAuthorLimit) ||
+		errors.Is(err, sites_usecase.ErrInvalidModerationMode)
+}
@@ -0,0 +1,17 @@
+package webhooks
+
+import (
+	"comments-system/internal/domain"
+	"context"
+)
+
+type webhooksUsecase interface {
+	CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	GetWebhook(ctx context.Context, id int) (domain.Webhook, error)
+	UpdateWebhook(ctx context.Context, id int, upd domain.WebhookUpdate) (domain.Webhook, error)
+	RotateSecret(ctx context.Context, id int) (string, error)
+	DeleteWebhook(ctx context.Context, id int) error
+	ListDeliveries(ctx context.Context, id int, status domain.DeliveryStatus, page, pageSize int) (domain.DeliveryLog, error)
+	Redeliver(ctx context.Context, id int, deliveryID int64) error
+}
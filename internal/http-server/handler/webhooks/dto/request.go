@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"comments-system/internal/domain"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// CreateWebhookRequest subscribes url to the listed events, or to all of
+// them when events is empty. A secret is generated when none is given.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,max=2000"`
+	Secret string   `json:"secret" validate:"omitempty,min=16,max=100"`
+	Events []string `json:"events" validate:"dive,oneof=comment.created comment.updated comment.moderated comment.deleted"`
+	Active *bool    `json:"active"`
+}
+
+func (r CreateWebhookRequest) ToDomain() domain.Webhook {
+	hook := domain.Webhook{
+		URL:    r.URL,
+		Secret: r.Secret,
+		Events: toEventTypes(r.Events),
+		Active: true,
+	}
+	if r.Active != nil {
+		hook.Active = *r.Active
+	}
+	return hook
+}
+
+// UpdateWebhookRequest changes only the fields present in the body.
+type UpdateWebhookRequest struct {
+	URL    *string   `json:"url" validate:"omitempty,min=1,max=2000"`
+	Events *[]string `json:"events" validate:"omitempty,dive,oneof=comment.created comment.updated comment.moderated comment.deleted"`
+	Active *bool     `json:"active"`
+}
+
+func (r UpdateWebhookRequest) ToDomain() domain.WebhookUpdate {
+	upd := domain.WebhookUpdate{
+		URL:    r.URL,
+		Active: r.Active,
+	}
+	if r.Events != nil {
+		events := toEventTypes(*r.Events)
+		upd.Events = &events
+	}
+	return upd
+}
+
+type DeliveryLogRequest struct {
+	Status   string `query:"status" validate:"omitempty,oneof=pending delivered dead"`
+	Page     int    `query:"page" validate:"min=0"`
+	PageSize int    `query:"page_size" validate:"min=0,max=100"`
+}
+
+func (r *DeliveryLogRequest) Validate() error {
+	validate := validator.New()
+	return validate.Struct(r)
+}
+
+func toEventTypes(events []string) []domain.EventType {
+	types := make([]domain.EventType, 0, len(events))
+	for _, event := range events {
+		types = append(types, domain.EventType(event))
+	}
+	return types
+}
@@ -0,0 +1,107 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+// WebhookResponse leaves out the secret, which is only shown when it is set.
+type WebhookResponse struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateWebhookResponse struct {
+	Webhook WebhookResponse `json:"webhook"`
+	Secret  string          `json:"secret"`
+}
+
+type SecretResponse struct {
+	Secret string `json:"secret"`
+}
+
+type DeliveryResponse struct {
+	ID             int64           `json:"id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+type DeliveryLogResponse struct {
+	Deliveries []DeliveryResponse `json:"deliveries"`
+	Status     string             `json:"status,omitempty"`
+	Total      int                `json:"total"`
+	Page       int                `json:"page"`
+	PageSize   int                `json:"page_size"`
+}
+
+func FromDomainWebhook(hook domain.Webhook) WebhookResponse {
+	events := make([]string, 0, len(hook.Events))
+	for _, event := range hook.Events {
+		events = append(events, string(event))
+	}
+
+	return WebhookResponse{
+		ID:        hook.ID,
+		URL:       hook.URL,
+		Events:    events,
+		Active:    hook.Active,
+		CreatedAt: hook.CreatedAt,
+		UpdatedAt: hook.UpdatedAt,
+	}
+}
+
+func FromDomainWebhooks(hooks []domain.Webhook) []WebhookResponse {
+	resp := make([]WebhookResponse, 0, len(hooks))
+	for _, hook := range hooks {
+		resp = append(resp, FromDomainWebhook(hook))
+	}
+	return resp
+}
+
+func FromDomainDelivery(d domain.WebhookDelivery) DeliveryResponse {
+	resp := DeliveryResponse{
+		ID:             d.ID,
+		Event:          string(d.EventType),
+		Payload:        json.RawMessage(d.Payload),
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+	// Only pending deliveries have a next attempt.
+	if d.Status == domain.DeliveryPending {
+		next := d.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+	return resp
+}
+
+func FromDomainDeliveryLog(log domain.DeliveryLog) DeliveryLogResponse {
+	deliveries := make([]DeliveryResponse, 0, len(log.Deliveries))
+	for _, d := range log.Deliveries {
+		deliveries = append(deliveries, FromDomainDelivery(d))
+	}
+
+	return DeliveryLogResponse{
+		Deliveries: deliveries,
+		Status:     string(log.Status),
+		Total:      log.Total,
+		Page:       log.Page,
+		PageSize:   log.PageSize,
+	}
+}
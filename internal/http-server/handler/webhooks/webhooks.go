@@ -0,0 +1,292 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"comments-system/internal/domain"
+	"comments-system/internal/http-server/handler/webhooks/dto"
+	webhooks_usecase "comments-system/internal/usecase/webhooks"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/wb-go/wbf/zlog"
+)
+
+type WebhooksHandler struct {
+	usecase  webhooksUsecase
+	logger   *zlog.Zerolog
+	validate *validator.Validate
+}
+
+func NewWebhooksHandler(usecase webhooksUsecase, logger *zlog.Zerolog) *WebhooksHandler {
+	return &WebhooksHandler{
+		usecase:  usecase,
+		logger:   logger,
+		validate: validator.New(),
+	}
+}
+
+func (h *WebhooksHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	hook, err := h.usecase.CreateWebhook(ctx, req.ToDomain())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to create webhook")
+		h.writeError(w, err)
+		return
+	}
+
+	resp := dto.CreateWebhookResponse{
+		Webhook: dto.FromDomainWebhook(hook),
+		Secret:  hook.Secret,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *WebhooksHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	hooks, err := h.usecase.ListWebhooks(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to list webhooks")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := dto.FromDomainWebhooks(hooks)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *WebhooksHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := h.parseWebhookID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	hook, err := h.usecase.GetWebhook(ctx, webhookID)
+	if err != nil {
+		h.logger.Error().Err(err).Int("webhook_id", webhookID).Msg("Failed to get webhook")
+		h.writeError(w, err)
+		return
+	}
+
+	resp := dto.FromDomainWebhook(hook)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *WebhooksHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := h.parseWebhookID(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to decode request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.logger.Error().Err(err).Msg("Request validation failed")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	hook, err := h.usecase.UpdateWebhook(ctx, webhookID, req.ToDomain())
+	if err != nil {
+		h.logger.Error().Err(err).Int("webhook_id", webhookID).Msg("Failed to update webhook")
+		h.writeError(w, err)
+		return
+	}
+
+	resp := dto.FromDomainWebhook(hook)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// RotateSecret issues a new signing secret for a webhook and returns it.
+func (h *WebhooksHandler) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := h.parseWebhookID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	secret, err := h.usecase.RotateSecret(ctx, webhookID)
+	if err != nil {
+		h.logger.Error().Err(err).Int("webhook_id", webhookID).Msg("Failed to rotate webhook secret")
+		h.writeError(w, err)
+		return
+	}
+
+	resp := dto.SecretResponse{Secret: secret}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func (h *WebhooksHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := h.parseWebhookID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err := h.usecase.DeleteWebhook(ctx, webhookID)
+	if err != nil {
+		h.logger.Error().Err(err).Int("webhook_id", webhookID).Msg("Failed to delete webhook")
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries returns a page of the delivery log of a webhook for
+// debugging its receiver.
+func (h *WebhooksHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := h.parseWebhookID(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.DeliveryLogRequest
+	req.Status = r.URL.Query().Get("status")
+	req.Page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	req.PageSize, _ = strconv.Atoi(r.URL.Query().Get("page_size"))
+
+	if err := req.Validate(); err != nil {
+		h.logger.Error().Err(err).Msg("Invalid query parameters")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	log, err := h.usecase.ListDeliveries(ctx, webhookID, domain.DeliveryStatus(req.Status), req.Page, req.PageSize)
+	if err != nil {
+		h.logger.Error().Err(err).Int("webhook_id", webhookID).Msg("Failed to list webhook deliveries")
+		h.writeError(w, err)
+		return
+	}
+
+	resp := dto.FromDomainDeliveryLog(log)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+// Redeliver sends a delivery again, e.g. a dead one after the receiver is
+// fixed.
+func (h *WebhooksHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := h.parseWebhookID(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryIDStr := chi.URLParam(r, "deliveryID")
+	deliveryID, err := strconv.ParseInt(deliveryIDStr, 10, 64)
+	if err != nil {
+		h.logger.Error().Err(err).Str("delivery_id", deliveryIDStr).Msg("Invalid delivery ID")
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err = h.usecase.Redeliver(ctx, webhookID, deliveryID)
+	if err != nil {
+		h.logger.Error().Err(err).Int("webhook_id", webhookID).Int64("delivery_id", deliveryID).Msg("Failed to redeliver webhook delivery")
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *WebhooksHandler) parseWebhookID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	webhookIDStr := chi.URLParam(r, "id")
+	webhookID, err := strconv.Atoi(webhookIDStr)
+	if err != nil {
+		h.logger.Error().Err(err).Str("webhook_id", webhookIDStr).Msg("Invalid webhook ID")
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return webhookID, true
+}
+
+func (h *WebhooksHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, webhooks_usecase.ErrWebhookNotFound):
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+	case errors.Is(err, webhooks_usecase.ErrDeliveryNotFound):
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+	case errors.Is(err, webhooks_usecase.ErrInvalidWebhookID) ||
+		errors.Is(err, webhooks_usecase.ErrInvalidDeliveryID) ||
+		isValidationError(err):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func isValidationError(err error) bool {
+	return errors.Is(err, webhooks_usecase.ErrInvalidURL) ||
+		errors.Is(err, webhooks_usecase.ErrURLTooLong) ||
+		errors.Is(err, webhooks_usecase.ErrInvalidEvent) ||
+		errors.Is(err, webhooks_usecase.ErrInvalidSecret) ||
+		errors.Is(err, webhooks_usecase.ErrInvalidDeliveryStatus)
+}
@@ -1,11 +1,23 @@
 package middleware
 
 import (
+	"bufio"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"runtime/debug"
+	"slices"
+	"strings"
 	"time"
 
+	"comments-system/internal/domain"
+	"comments-system/internal/ratelimit"
+	sites_usecase "comments-system/internal/usecase/sites"
+	users_usecase "comments-system/internal/usecase/users"
+
 	"github.com/wb-go/wbf/zlog"
 )
 
@@ -46,6 +58,22 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap lets http.ResponseController reach the flushing and deadline
+// methods of the underlying writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Hijack hands the connection over for WebSocket upgrades, which need an
+// http.Hijacker.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil {
+		rw.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, buf, err
+}
+
 func RecoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -69,3 +97,248 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// AdminMiddleware lets a request through only when its X-Admin-Token header
+// matches token. An empty token disables the protected routes entirely.
+func AdminMiddleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided := r.Header.Get("X-Admin-Token")
+			if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				zlog.Logger.Warn().
+					Str("method", r.Method).
+					Str("path", r.URL.Path).
+					Str("ip", r.RemoteAddr).
+					Msg("Admin access denied")
+
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type siteResolver interface {
+	ResolveSite(ctx context.Context, apiKey string) (domain.Site, error)
+}
+
+// TenantMiddleware scopes a request to the site its X-Site-Key header
+// belongs to. Clients that cannot set headers, like browser EventSource, may
+// pass the key in the site_key query parameter instead. Requests without a
+// key are served by the default site. Browser requests are only let through
+// from the allowed origins of the site.
+func TenantMiddleware(resolver siteResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+
+			// Preflight requests carry no API key, so the origin is checked
+			// on the actual request.
+			if r.Method == http.MethodOptions && origin != "" {
+				setCORSHeaders(w, origin)
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Site-Key, X-Comment-Token, Last-Event-ID")
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			apiKey := r.Header.Get("X-Site-Key")
+			if apiKey == "" {
+				apiKey = r.URL.Query().Get("site_key")
+			}
+
+			site, err := resolver.ResolveSite(r.Context(), apiKey)
+			if err != nil {
+				if errors.Is(err, sites_usecase.ErrInvalidAPIKey) {
+					zlog.Logger.Warn().
+						Str("method", r.Method).
+						Str("path", r.URL.Path).
+						Str("ip", r.RemoteAddr).
+						Msg("Invalid site API key")
+
+					http.Error(w, "Invalid API key", http.StatusUnauthorized)
+					return
+				}
+
+				zlog.Logger.Error().Err(err).Msg("Failed to resolve site")
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			if origin != "" {
+				if !site.AllowsOrigin(origin) {
+					zlog.Logger.Warn().
+						Int("site_id", site.ID).
+						Str("origin", origin).
+						Str("path", r.URL.Path).
+						Msg("Origin not allowed")
+
+					http.Error(w, "Origin not allowed", http.StatusForbidden)
+					return
+				}
+				setCORSHeaders(w, origin)
+			}
+
+			next.ServeHTTP(w, r.WithContext(domain.WithSite(r.Context(), site)))
+		})
+	}
+}
+
+func setCORSHeaders(w http.ResponseWriter, origin string) {
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Add("Vary", "Origin")
+	w.Header().Set("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After")
+}
+
+type authenticator interface {
+	AuthenticateSession(ctx context.Context, token string) (domain.User, error)
+	AuthenticateToken(ctx context.Context, token string) (domain.User, error)
+}
+
+// AuthMiddleware authenticates a request by its bearer token or, failing
+// that, by the session cookie of the web interface. Requests with neither are
+// served as guests. The X-Comment-Token header of guests managing their
+// comments is passed on for the usecase to check. It must run after
+// TenantMiddleware since users belong to a site.
+func AuthMiddleware(auth authenticator, sessionCookie string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token := r.Header.Get("X-Comment-Token"); token != "" {
+				r = r.WithContext(domain.WithCommentToken(r.Context(), token))
+			}
+
+			if header := r.Header.Get("Authorization"); header != "" {
+				token, ok := strings.CutPrefix(header, "Bearer ")
+				if !ok {
+					http.Error(w, "Unsupported authorization scheme", http.StatusUnauthorized)
+					return
+				}
+
+				user, err := auth.AuthenticateToken(r.Context(), token)
+				if err != nil {
+					if errors.Is(err, users_usecase.ErrInvalidToken) {
+						zlog.Logger.Warn().
+							Err(err).
+							Str("path", r.URL.Path).
+							Str("ip", r.RemoteAddr).
+							Msg("Invalid access token")
+
+						w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+						http.Error(w, "Invalid access token", http.StatusUnauthorized)
+						return
+					}
+
+					zlog.Logger.Error().Err(err).Msg("Failed to authenticate token")
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+
+				next.ServeHTTP(w, r.WithContext(domain.WithUser(r.Context(), user)))
+				return
+			}
+
+			cookie, err := r.Cookie(sessionCookie)
+			if err != nil || cookie.Value == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, err := auth.AuthenticateSession(r.Context(), cookie.Value)
+			if err != nil {
+				if errors.Is(err, users_usecase.ErrInvalidSession) {
+					// A stale cookie is dropped and the request goes on as a guest.
+					http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				zlog.Logger.Error().Err(err).Msg("Failed to authenticate session")
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(domain.WithUser(r.Context(), user)))
+		})
+	}
+}
+
+// RequireRole lets through only users with one of roles. Guests are asked to
+// authenticate.
+func RequireRole(roles ...domain.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := domain.UserFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+			if !slices.Contains(roles, user.Role) {
+				zlog.Logger.Warn().
+					Int("user_id", user.ID).
+					Str("role", string(user.Role)).
+					Str("path", r.URL.Path).
+					Msg("Role not allowed")
+
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type clientIPResolver interface {
+	ClientIP(r *http.Request) string
+}
+
+// ClientIPMiddleware passes the client IP address of a request on to the
+// usecases, which tell guests apart by it.
+func ClientIPMiddleware(resolver clientIPResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := domain.WithClientIP(r.Context(), resolver.ClientIP(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+type ipLimiter interface {
+	Enabled() bool
+	ClientIP(r *http.Request) string
+	Take(ctx context.Context, ip string) (domain.RateLimitStatus, error)
+}
+
+// RateLimitMiddleware limits the requests of each client IP with limiter.
+// Requests are let through when the store of the limits fails.
+func RateLimitMiddleware(limiter ipLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !limiter.Enabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := limiter.ClientIP(r)
+			status, err := limiter.Take(r.Context(), ip)
+			if err != nil {
+				zlog.Logger.Error().Err(err).Str("ip", ip).Msg("Failed to check rate limit")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ratelimit.SetHeaders(w.Header(), status)
+			if !status.Allowed {
+				zlog.Logger.Warn().
+					Str("method", r.Method).
+					Str("path", r.URL.Path).
+					Str("ip", ip).
+					Msg("Rate limit exceeded")
+
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
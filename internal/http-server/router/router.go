@@ -1,7 +1,13 @@
 package router
 
 import (
+	"comments-system/internal/domain"
+	"comments-system/internal/http-server/handler/auth"
 	"comments-system/internal/http-server/handler/comments"
+	"comments-system/internal/http-server/handler/live"
+	"comments-system/internal/http-server/handler/notifications"
+	"comments-system/internal/http-server/handler/sites"
+	"comments-system/internal/http-server/handler/webhooks"
 	"comments-system/internal/http-server/middleware"
 	"net/http"
 	"os"
@@ -12,10 +18,19 @@ import (
 )
 
 type Handler struct {
-	CommentsHandler *comments.CommentsHandler
+	CommentsHandler      *comments.CommentsHandler
+	SitesHandler         *sites.SitesHandler
+	AuthHandler          *auth.AuthHandler
+	LiveHandler          *live.LiveHandler
+	WebhooksHandler      *webhooks.WebhooksHandler
+	NotificationsHandler *notifications.NotificationsHandler
 }
 
-func SetupRouter(h *Handler) http.Handler {
+// SetupRouter builds the HTTP routes. tenant scopes comment routes to the
+// site of the request, authenticate identifies the user making it, clientIP
+// records the address of guests, limitPosting rate limits new comments and
+// limitFlagging rate limits flags.
+func SetupRouter(h *Handler, adminToken string, tenant, authenticate, clientIP, limitPosting, limitFlagging func(http.Handler) http.Handler) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RecoveryMiddleware)
@@ -43,10 +58,108 @@ func SetupRouter(h *Handler) http.Handler {
 			})
 		})
 
+		r.Route("/auth", func(r chi.Router) {
+			r.Use(tenant, authenticate)
+
+			r.Post("/register", h.AuthHandler.Register)
+			r.Post("/login", h.AuthHandler.Login)
+			r.Post("/logout", h.AuthHandler.Logout)
+			r.Post("/token", h.AuthHandler.Token)
+			r.Get("/me", h.AuthHandler.Me)
+		})
+
+		// Site admins manage the users and settings of their own site.
+		r.Route("/users", func(r chi.Router) {
+			r.Use(tenant, authenticate, middleware.RequireRole(domain.RoleAdmin))
+
+			r.Get("/", h.AuthHandler.ListUsers)
+			r.Patch("/{id}", h.AuthHandler.UpdateUser)
+		})
+
+		r.Route("/site", func(r chi.Router) {
+			r.Use(tenant, authenticate, middleware.RequireRole(domain.RoleAdmin))
+
+			r.Get("/", h.SitesHandler.GetCurrentSite)
+			r.Patch("/", h.SitesHandler.UpdateCurrentSite)
+		})
+
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(tenant, authenticate, middleware.RequireRole(domain.RoleAdmin))
+
+			r.Post("/", h.WebhooksHandler.CreateWebhook)
+			r.Get("/", h.WebhooksHandler.ListWebhooks)
+			r.Get("/{id}", h.WebhooksHandler.GetWebhook)
+			r.Patch("/{id}", h.WebhooksHandler.UpdateWebhook)
+			r.Delete("/{id}", h.WebhooksHandler.DeleteWebhook)
+			r.Post("/{id}/rotate-secret", h.WebhooksHandler.RotateSecret)
+			r.Get("/{id}/deliveries", h.WebhooksHandler.ListDeliveries)
+			r.Post("/{id}/deliveries/{deliveryID}/redeliver", h.WebhooksHandler.Redeliver)
+		})
+
+		// Every user has an inbox of replies to their comments and mentions.
+		r.Route("/notifications", func(r chi.Router) {
+			r.Use(tenant, authenticate, middleware.RequireRole(domain.RoleUser, domain.RoleModerator, domain.RoleAdmin))
+
+			r.Get("/", h.NotificationsHandler.ListNotifications)
+			r.Post("/read", h.NotificationsHandler.MarkRead)
+			r.Get("/preferences", h.NotificationsHandler.GetPreferences)
+			r.Patch("/preferences", h.NotificationsHandler.UpdatePreferences)
+			r.Post("/preferences/confirm-email", h.NotificationsHandler.ConfirmEmail)
+		})
+
 		r.Route("/comments", func(r chi.Router) {
-			r.Post("/", h.CommentsHandler.CreateComment)
+			r.Use(tenant, authenticate, clientIP)
+
+			r.With(limitPosting).Post("/", h.CommentsHandler.CreateComment)
 			r.Get("/", h.CommentsHandler.GetComments)
+			r.Get("/search", h.CommentsHandler.SearchComments)
+			r.Get("/stream", h.CommentsHandler.StreamComments)
+			r.Get("/ws", h.LiveHandler.Connect)
+			r.Get("/{id}", h.CommentsHandler.GetComment)
+			r.Patch("/{id}", h.CommentsHandler.UpdateComment)
 			r.Delete("/{id}", h.CommentsHandler.DeleteComment)
+			r.Get("/{id}/context", h.CommentsHandler.GetCommentContext)
+			r.Get("/{id}/replies", h.CommentsHandler.GetReplies)
+			r.Get("/{id}/revisions", h.CommentsHandler.GetRevisions)
+			r.Post("/{id}/vote", h.CommentsHandler.VoteComment)
+			r.With(limitFlagging).Post("/{id}/flags", h.CommentsHandler.FlagComment)
+			r.Post("/{id}/restore", h.CommentsHandler.RestoreComment)
+			r.Delete("/{id}/token", h.CommentsHandler.RevokeManageToken)
+		})
+
+		r.Route("/moderation", func(r chi.Router) {
+			r.Use(tenant, authenticate, middleware.RequireRole(domain.RoleModerator, domain.RoleAdmin))
+
+			r.Get("/queue", h.CommentsHandler.ModerationQueue)
+			r.Post("/queue/approve", h.CommentsHandler.ApproveComments)
+			r.Post("/queue/reject", h.CommentsHandler.RejectComments)
+			r.Post("/queue/spam", h.CommentsHandler.MarkSpam)
+			r.Get("/flags", h.CommentsHandler.FlagInbox)
+			r.Post("/flags/dismiss", h.CommentsHandler.DismissFlags)
+		})
+
+		r.Route("/threads/{key}", func(r chi.Router) {
+			r.Use(tenant, authenticate)
+
+			r.Get("/comments", h.CommentsHandler.GetThreadComments)
+			r.With(limitPosting).Post("/comments", h.CommentsHandler.CreateThreadComment)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.AdminMiddleware(adminToken))
+
+			r.With(tenant).Delete("/comments/{id}", h.CommentsHandler.PurgeComment)
+			r.With(tenant).Get("/users", h.AuthHandler.ListUsers)
+			r.With(tenant).Patch("/users/{id}", h.AuthHandler.UpdateUser)
+
+			r.Route("/sites", func(r chi.Router) {
+				r.Post("/", h.SitesHandler.CreateSite)
+				r.Get("/", h.SitesHandler.ListSites)
+				r.Get("/{id}", h.SitesHandler.GetSite)
+				r.Patch("/{id}", h.SitesHandler.UpdateSite)
+				r.Delete("/{id}", h.SitesHandler.DeleteSite)
+				r.Post("/{id}/rotate-key", h.SitesHandler.RotateAPIKey)
+			})
 		})
 
 		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,22 @@
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+type notificationStore interface {
+	CreateForComment(ctx context.Context, siteID int, comment domain.Comment, mentions []string) (int, error)
+}
+
+type emailStore interface {
+	ClaimEmails(ctx context.Context, limit int, instantEvery, digestEvery time.Duration) ([]domain.NotificationEmail, error)
+	MarkEmailed(ctx context.Context, userID int, ids []int64) error
+	FailEmail(ctx context.Context, userID int, retryDelay time.Duration, maxFailures int) (bool, error)
+}
+
+type mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
@@ -0,0 +1,61 @@
+package notifications
+
+import (
+	"fmt"
+	"strings"
+
+	"comments-system/internal/domain"
+)
+
+const (
+	// maxEmailNotifications is how many notifications an email lists; the
+	// rest are only counted.
+	maxEmailNotifications = 20
+	// maxSnippetLength is how many characters of a comment an email quotes.
+	maxSnippetLength = 200
+)
+
+// composeEmail writes the subject and text of an email about the
+// notifications of e.
+func composeEmail(e domain.NotificationEmail) (string, string) {
+	var subject string
+	switch {
+	case len(e.Notifications) > 1:
+		subject = fmt.Sprintf("Новые уведомления: %d", len(e.Notifications))
+	case e.Notifications[0].Kind == domain.NotificationMention:
+		subject = "Вас упомянули в комментарии"
+	default:
+		subject = "Новый ответ на ваш комментарий"
+	}
+	if e.SiteName != "" {
+		subject = "[" + e.SiteName + "] " + subject
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Здравствуйте, %s!\n", e.Username)
+	for i, n := range e.Notifications {
+		if i == maxEmailNotifications {
+			fmt.Fprintf(&b, "\n...и еще %d.\n", len(e.Notifications)-i)
+			break
+		}
+
+		action := "ответил(а) на ваш комментарий"
+		if n.Kind == domain.NotificationMention {
+			action = "упомянул(а) вас"
+		}
+		fmt.Fprintf(&b, "\n%s %s в обсуждении «%s»:\n> %s\n", n.Comment.Author, action, n.Comment.ThreadKey, snippet(n.Comment.Content))
+	}
+	b.WriteString("\nПисьма можно отключить в настройках уведомлений.\n")
+
+	return subject, b.String()
+}
+
+// snippet shortens a comment to one line of at most maxSnippetLength
+// characters.
+func snippet(content string) string {
+	s := strings.Join(strings.Fields(content), " ")
+	if runes := []rune(s); len(runes) > maxSnippetLength {
+		return string(runes[:maxSnippetLength]) + "…"
+	}
+	return s
+}
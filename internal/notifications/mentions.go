@@ -0,0 +1,36 @@
+package notifications
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxMentions is how many users one comment may notify by mentioning them.
+const maxMentions = 10
+
+// mentionPattern matches @username not preceded by a character of a
+// username, so e-mail addresses are not taken for mentions. Usernames are
+// letters, digits and . _ -.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}._@-])@([\p{L}\p{N}._-]{2,50})`)
+
+// Mentions returns the lowercased usernames mentioned in content, the first
+// maxMentions of them. A mention at the end of a sentence is also tried
+// without the trailing punctuation, since usernames may end with it too.
+func Mentions(content string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if len(name) >= 2 && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, maxMentions) {
+		name := strings.ToLower(m[1])
+		add(name)
+		add(strings.TrimRight(name, ".-_"))
+	}
+
+	return names
+}
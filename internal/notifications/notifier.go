@@ -0,0 +1,42 @@
+package notifications
+
+import (
+	"context"
+
+	"comments-system/internal/domain"
+)
+
+// Notifier creates the notifications of comment events: a reply notifies the
+// author of its parent and a comment notifies the users it mentions. Only
+// approved comments notify, so a comment held for moderation notifies once
+// it is approved. An edit notifies the users newly mentioned in it.
+type Notifier struct {
+	store notificationStore
+}
+
+func NewNotifier(store notificationStore) *Notifier {
+	return &Notifier{store: store}
+}
+
+// Publish creates the notifications of an event. An event published again
+// notifies no one twice.
+func (n *Notifier) Publish(ctx context.Context, event domain.CommentEvent) error {
+	switch event.Type {
+	case domain.EventCommentCreated, domain.EventCommentUpdated, domain.EventCommentModerated:
+	default:
+		return nil
+	}
+
+	comment := event.Comment
+	if comment.Status != domain.StatusApproved || comment.IsDeleted() {
+		return nil
+	}
+
+	mentions := Mentions(comment.Content)
+	if comment.ParentID == nil && len(mentions) == 0 {
+		return nil
+	}
+
+	_, err := n.store.CreateForComment(ctx, event.SiteID, comment, mentions)
+	return err
+}
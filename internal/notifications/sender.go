@@ -0,0 +1,113 @@
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+// maxEmailFailures is how many emails to a user may fail in a row before
+// email is turned off for them.
+const maxEmailFailures = 5
+
+// EmailSender emails users their new notifications. With the instant mode a
+// user gets an email on the first poll after a notification arrives and at
+// most one per poll interval, so notifications arriving together share an
+// email; with the digest mode at most one per digest interval. A failed email
+// is tried again after the poll interval, doubled for every failure before.
+type EmailSender struct {
+	store    emailStore
+	mailer   mailer
+	interval time.Duration
+	digest   time.Duration
+	batch    int
+	logger   *zlog.Zerolog
+}
+
+// NewEmailSender creates a sender that looks for due emails every interval
+// and sends up to batch of them at once.
+func NewEmailSender(store emailStore, mailer mailer, interval, digest time.Duration, batch int, logger *zlog.Zerolog) *EmailSender {
+	return &EmailSender{
+		store:    store,
+		mailer:   mailer,
+		interval: interval,
+		digest:   digest,
+		batch:    batch,
+		logger:   logger,
+	}
+}
+
+// Run sends emails until ctx is done.
+func (s *EmailSender) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		// A full batch means more emails may be due right away.
+		if s.sendDue(ctx) == s.batch && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDue claims a batch of due emails, sends them and returns how many
+// there were.
+func (s *EmailSender) sendDue(ctx context.Context) int {
+	emails, err := s.store.ClaimEmails(ctx, s.batch, s.interval, s.digest)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error().Err(err).Msg("Failed to claim notification emails")
+		}
+		return 0
+	}
+
+	for _, e := range emails {
+		if ctx.Err() != nil {
+			break
+		}
+		s.send(ctx, e)
+	}
+
+	return len(emails)
+}
+
+func (s *EmailSender) send(ctx context.Context, e domain.NotificationEmail) {
+	if len(e.Notifications) == 0 {
+		// Read or hidden since it was claimed.
+		return
+	}
+
+	// The outcome is recorded even if ctx ends meanwhile.
+	markCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	subject, body := composeEmail(e)
+	if err := s.mailer.Send(ctx, e.Email, subject, body); err != nil {
+		s.logger.Warn().Err(err).Int("user_id", e.UserID).Msg("Failed to send notification email")
+		disabled, err := s.store.FailEmail(markCtx, e.UserID, s.interval, maxEmailFailures)
+		if err != nil {
+			s.logger.Error().Err(err).Int("user_id", e.UserID).Msg("Failed to record notification email failure")
+		}
+		if disabled {
+			s.logger.Warn().Int("user_id", e.UserID).Msg("Turned notification email off after repeated failures")
+		}
+		return
+	}
+
+	ids := make([]int64, len(e.Notifications))
+	for i, n := range e.Notifications {
+		ids[i] = n.ID
+	}
+	if err := s.store.MarkEmailed(markCtx, e.UserID, ids); err != nil {
+		s.logger.Error().Err(err).Int("user_id", e.UserID).Msg("Failed to record notification email")
+	}
+}
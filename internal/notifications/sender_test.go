@@ -0,0 +1,138 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/rs/zerolog"
+)
+
+// memoryEmails keeps the emails of one user the way the repository does:
+// the unread notifications not emailed yet, and the failures in a row that
+// turn email off.
+type memoryEmails struct {
+	email       domain.NotificationEmail
+	off         bool
+	failures    int
+	retryDelays []time.Duration
+	emailed     []int64
+}
+
+func (s *memoryEmails) ClaimEmails(context.Context, int, time.Duration, time.Duration) ([]domain.NotificationEmail, error) {
+	if s.off || len(s.email.Notifications) == 0 {
+		return nil, nil
+	}
+	return []domain.NotificationEmail{s.email}, nil
+}
+
+func (s *memoryEmails) MarkEmailed(_ context.Context, _ int, ids []int64) error {
+	s.emailed = append(s.emailed, ids...)
+	s.email.Notifications = nil
+	s.failures = 0
+	return nil
+}
+
+func (s *memoryEmails) FailEmail(_ context.Context, _ int, retryDelay time.Duration, maxFailures int) (bool, error) {
+	s.failures++
+	s.retryDelays = append(s.retryDelays, retryDelay)
+	s.off = s.failures >= maxFailures
+	return s.off, nil
+}
+
+type sentEmail struct {
+	to, subject, body string
+}
+
+type fakeMailer struct {
+	sent []sentEmail
+	err  error
+}
+
+func (m *fakeMailer) Send(_ context.Context, to, subject, body string) error {
+	m.sent = append(m.sent, sentEmail{to: to, subject: subject, body: body})
+	return m.err
+}
+
+func newTestSender(store emailStore, m mailer) *EmailSender {
+	logger := zerolog.Nop()
+	return NewEmailSender(store, m, time.Minute, time.Hour, 10, &logger)
+}
+
+func testNotification(id int64, kind domain.NotificationKind, author string) domain.Notification {
+	return domain.Notification{
+		ID:      id,
+		UserID:  1,
+		Kind:    kind,
+		Comment: domain.Comment{ID: int(id), Author: author, ThreadKey: "article-1", Content: "comment by " + author},
+	}
+}
+
+func TestEmailSenderSharesEmail(t *testing.T) {
+	store := &memoryEmails{email: domain.NotificationEmail{
+		UserID:   1,
+		Username: "ann",
+		Email:    "ann@example.com",
+		SiteName: "blog",
+		Notifications: []domain.Notification{
+			testNotification(1, domain.NotificationReply, "bob"),
+			testNotification(2, domain.NotificationMention, "carol"),
+			testNotification(3, domain.NotificationReply, "dave"),
+		},
+	}}
+	m := &fakeMailer{}
+	sender := newTestSender(store, m)
+
+	sender.sendDue(context.Background())
+	sender.sendDue(context.Background())
+
+	if len(m.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(m.sent))
+	}
+	sent := m.sent[0]
+	if sent.to != "ann@example.com" || sent.subject != "[blog] Новые уведомления: 3" {
+		t.Errorf("sent to %q with subject %q", sent.to, sent.subject)
+	}
+	for _, author := range []string{"bob", "carol", "dave"} {
+		if !strings.Contains(sent.body, "comment by "+author) {
+			t.Errorf("email does not quote the comment of %s", author)
+		}
+	}
+	if want := []int64{1, 2, 3}; !slices.Equal(store.emailed, want) {
+		t.Errorf("marked %v emailed, want %v", store.emailed, want)
+	}
+}
+
+func TestEmailSenderTurnsEmailOffAfterFailures(t *testing.T) {
+	store := &memoryEmails{email: domain.NotificationEmail{
+		UserID:        1,
+		Email:         "ann@example.com",
+		Notifications: []domain.Notification{testNotification(1, domain.NotificationReply, "bob")},
+	}}
+	m := &fakeMailer{err: errors.New("mailbox unavailable")}
+	sender := newTestSender(store, m)
+
+	for range maxEmailFailures + 2 {
+		sender.sendDue(context.Background())
+	}
+
+	if len(m.sent) != maxEmailFailures {
+		t.Errorf("tried %d emails, want %d", len(m.sent), maxEmailFailures)
+	}
+	if !store.off {
+		t.Error("email is still on")
+	}
+	for _, delay := range store.retryDelays {
+		if delay != time.Minute {
+			t.Errorf("retry delay = %v, want the poll interval", delay)
+		}
+	}
+	if len(store.emailed) != 0 {
+		t.Errorf("marked %v emailed", store.emailed)
+	}
+}
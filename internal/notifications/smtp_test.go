@@ -0,0 +1,177 @@
+package notifications
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpServer is a minimal SMTP server without STARTTLS that accepts one
+// session and records it.
+type smtpServer struct {
+	addr     string
+	commands []string
+	data     []byte
+	done     chan struct{}
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &smtpServer{addr: ln.Addr().String(), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s.serve(textproto.NewConn(conn))
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(c *textproto.Conn) {
+	c.PrintfLine("220 localhost ready")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		s.commands = append(s.commands, line)
+
+		verb, _, _ := strings.Cut(strings.ToUpper(line), " ")
+		switch verb {
+		case "EHLO":
+			c.PrintfLine("250-localhost")
+			c.PrintfLine("250-AUTH PLAIN")
+			c.PrintfLine("250 8BITMIME")
+		case "AUTH":
+			c.PrintfLine("235 authenticated")
+		case "MAIL", "RCPT":
+			c.PrintfLine("250 ok")
+		case "DATA":
+			c.PrintfLine("354 go ahead")
+			s.data, err = c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			c.PrintfLine("250 queued")
+		case "QUIT":
+			c.PrintfLine("221 bye")
+			return
+		default:
+			c.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// session waits for the session to end and returns its commands.
+func (s *smtpServer) session(t *testing.T) []string {
+	t.Helper()
+	select {
+	case <-s.done:
+	case <-time.After(time.Second):
+		t.Fatal("smtp session did not end")
+	}
+	return s.commands
+}
+
+func newTestMailer(addr, host, username, password string) *SMTPMailer {
+	m := NewSMTPMailer(host, 25, username, password, "Comments <noreply@example.com>", time.Second)
+	m.addr = addr
+	return m
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	srv := newSMTPServer(t)
+	m := newTestMailer(srv.addr, "127.0.0.1", "mailer", "secret")
+
+	body := "Здравствуйте, ann!\n\n" + strings.Repeat("очень длинная строка ", 10) + "\nx = 1\n"
+	if err := m.Send(context.Background(), "ann@example.com", "Новый ответ", body); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	commands := srv.session(t)
+	auth := base64.StdEncoding.EncodeToString([]byte("\x00mailer\x00secret"))
+	want := []string{"EHLO localhost", "AUTH PLAIN " + auth, "MAIL FROM:<noreply@example.com> BODY=8BITMIME", "RCPT TO:<ann@example.com>", "DATA", "QUIT"}
+	if strings.Join(commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands = %q, want %q", commands, want)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(srv.data)))
+	if err != nil {
+		t.Fatalf("failed to parse email: %v", err)
+	}
+	headers := map[string]string{
+		"From":                      "Comments <noreply@example.com>",
+		"To":                        "ann@example.com",
+		"Mime-Version":              "1.0",
+		"Content-Type":              "text/plain; charset=utf-8",
+		"Content-Transfer-Encoding": "quoted-printable",
+	}
+	for name, want := range headers {
+		if got := msg.Header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); err != nil || subject != "Новый ответ" {
+		t.Errorf("Subject = %q, %v, want Новый ответ", subject, err)
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Date: %v", err)
+	}
+
+	raw, _ := io.ReadAll(msg.Body)
+	scanner := bufio.NewScanner(strings.NewReader(string(raw)))
+	for scanner.Scan() {
+		if len(scanner.Text()) > 76 {
+			t.Errorf("encoded line longer than 76 characters: %q", scanner.Text())
+		}
+	}
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(string(raw))))
+	if err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	// The server reads the lines of the message without their CR.
+	if got, want := string(decoded), body; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestSMTPMailerKeepsCredentialsFromPlainRemoteServer(t *testing.T) {
+	srv := newSMTPServer(t)
+	// The server offers no STARTTLS and is not local by name.
+	m := newTestMailer(srv.addr, "smtp.example.com", "mailer", "secret")
+
+	if err := m.Send(context.Background(), "ann@example.com", "subject", "body"); err == nil {
+		t.Fatal("Send() error = nil, want a refusal to authenticate")
+	}
+
+	for _, command := range srv.session(t) {
+		if strings.HasPrefix(command, "AUTH") || strings.HasPrefix(command, "MAIL") {
+			t.Errorf("server got %q", command)
+		}
+	}
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	m := NewSMTPMailer("127.0.0.1", 25, "", "", "noreply@example.com", time.Second)
+	if err := m.Send(context.Background(), "ann@example.com\r\nBcc: eve@example.com", "subject", "body"); err == nil {
+		t.Error("Send() error = nil, want invalid recipient")
+	}
+}
@@ -0,0 +1,18 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+type eventStore interface {
+	PublishPending(ctx context.Context, limit int, publish func(events []domain.CommentEvent) (int, error)) (int, error)
+	DeletePublished(ctx context.Context, olderThan time.Time) (int, error)
+}
+
+type eventLog interface {
+	Head(ctx context.Context) (domain.OutboxPosition, error)
+	ListAfter(ctx context.Context, pos domain.OutboxPosition, limit int) ([]domain.CommentEvent, domain.OutboxPosition, error)
+}
@@ -0,0 +1,39 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+
+	"comments-system/internal/domain"
+)
+
+// EventPublisher passes comment events on, e.g. to the live streams of this
+// instance, to webhooks or to a message broker. The same event may be
+// published more than once, so a publisher drops or tolerates repeats by the
+// key of the event.
+type EventPublisher interface {
+	Publish(ctx context.Context, event domain.CommentEvent) error
+}
+
+// Publishers publishes an event to every publisher of the list. The event
+// fails if any of them fails and is published again to all of them later.
+type Publishers []EventPublisher
+
+func (p Publishers) Publish(ctx context.Context, event domain.CommentEvent) error {
+	var errs []error
+	for _, publisher := range p {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Wakers wakes every relay and tail of the list.
+type Wakers []interface{ Wake() }
+
+func (w Wakers) Wake() {
+	for _, waker := range w {
+		waker.Wake()
+	}
+}
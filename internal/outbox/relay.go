@@ -0,0 +1,150 @@
+package outbox
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+)
+
+const (
+	// maxRetryDelay caps the exponential delay after failed publishing.
+	maxRetryDelay = time.Minute
+	// cleanupInterval is how often published events past the retention are
+	// removed.
+	cleanupInterval = time.Hour
+)
+
+// Relay publishes the comment events stored along with the changes, oldest
+// first; the store lets one relay of all instances publish at a time. An
+// event that fails to publish stops the batch and is tried again with
+// growing delays following retries, so events are published at least once
+// and in order.
+type Relay struct {
+	store     eventStore
+	publisher EventPublisher
+	interval  time.Duration
+	batch     int
+	retries   retry.Strategy
+	retention time.Duration
+	wake      chan struct{}
+	logger    *zlog.Zerolog
+}
+
+// NewRelay creates a relay that looks for new events every interval and
+// publishes up to batch of them at once. Published events are kept for
+// retention; 0 keeps them forever.
+func NewRelay(store eventStore, publisher EventPublisher, interval time.Duration, batch int, retries retry.Strategy, retention time.Duration, logger *zlog.Zerolog) *Relay {
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		interval:  interval,
+		batch:     batch,
+		retries:   retries,
+		retention: retention,
+		wake:      make(chan struct{}, 1),
+		logger:    logger,
+	}
+}
+
+// Wake makes the relay look for new events now rather than on its next poll.
+// It does not block.
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run publishes events until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	failures := 0
+	lastCleanup := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-r.wake:
+			if failures > 0 {
+				// Waking does not cut the delay after a failure short.
+				continue
+			}
+			timer.Stop()
+		}
+
+		published, err := r.store.PublishPending(ctx, r.batch, func(events []domain.CommentEvent) (int, error) {
+			return r.publish(ctx, events)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		delay := r.interval
+		switch {
+		case err != nil:
+			failures++
+			delay = retryDelay(r.retries, failures)
+			r.logger.Error().
+				Err(err).
+				Int("published", published).
+				Int("failures", failures).
+				Dur("retry_in", delay).
+				Msg("Failed to publish comment events")
+		case published == r.batch:
+			// A full batch means more events may be waiting.
+			failures = 0
+			delay = 0
+		default:
+			failures = 0
+		}
+
+		if r.retention > 0 && time.Since(lastCleanup) >= cleanupInterval {
+			r.cleanup(ctx)
+			lastCleanup = time.Now()
+		}
+
+		timer.Reset(delay)
+	}
+}
+
+// publish publishes events in order and returns how many were published
+// before one failed.
+func (r *Relay) publish(ctx context.Context, events []domain.CommentEvent) (int, error) {
+	for i, event := range events {
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
+
+func (r *Relay) cleanup(ctx context.Context) {
+	deleted, err := r.store.DeletePublished(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.Error().Err(err).Msg("Failed to delete published comment events")
+		}
+		return
+	}
+	if deleted > 0 {
+		r.logger.Debug().Int("deleted", deleted).Msg("Deleted published comment events")
+	}
+}
+
+// retryDelay is the delay after the given number of failures in a row: the
+// delay of retries, multiplied by its backoff for every failure before.
+func retryDelay(retries retry.Strategy, failures int) time.Duration {
+	delay := float64(retries.Delay) * math.Pow(retries.Backoff, float64(failures-1))
+	if delay > float64(maxRetryDelay) {
+		return maxRetryDelay
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,225 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/rs/zerolog"
+	"github.com/wb-go/wbf/retry"
+)
+
+var (
+	testRetries = retry.Strategy{Attempts: 3, Delay: time.Millisecond, Backoff: 1}
+	testLogger  = zerolog.Nop()
+)
+
+func testEvent(id int64) domain.CommentEvent {
+	return domain.CommentEvent{
+		ID:      uint64(id),
+		Key:     strconv.FormatInt(id, 10),
+		Type:    domain.EventCommentCreated,
+		SiteID:  1,
+		Comment: domain.Comment{ID: int(id)},
+	}
+}
+
+// recordingPublisher records every event it is given and fails the events of
+// failures as many times as given there.
+type recordingPublisher struct {
+	mu       sync.Mutex
+	calls    []string
+	failures map[string]int
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event domain.CommentEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, event.Key)
+	if p.failures[event.Key] > 0 {
+		p.failures[event.Key]--
+		return errors.New("publisher unavailable")
+	}
+	return nil
+}
+
+func (p *recordingPublisher) called() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.calls)
+}
+
+// memoryStore is an outbox in memory, kept the way the repository keeps it.
+type memoryStore struct {
+	mu        sync.Mutex
+	events    []domain.CommentEvent
+	published map[string]bool
+	attempts  map[string]int
+	lastError map[string]string
+	takes     int
+}
+
+func newMemoryStore(ids ...int64) *memoryStore {
+	s := &memoryStore{
+		published: make(map[string]bool),
+		attempts:  make(map[string]int),
+		lastError: make(map[string]string),
+	}
+	for _, id := range ids {
+		s.events = append(s.events, testEvent(id))
+	}
+	return s
+}
+
+func (s *memoryStore) PublishPending(_ context.Context, limit int, publish func(events []domain.CommentEvent) (int, error)) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []domain.CommentEvent
+	for _, event := range s.events {
+		if !s.published[event.Key] && len(pending) < limit {
+			pending = append(pending, event)
+		}
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	s.takes++
+
+	published, err := publish(pending)
+	for _, event := range pending[:published] {
+		s.published[event.Key] = true
+		s.attempts[event.Key]++
+	}
+	if err != nil && published < len(pending) {
+		s.attempts[pending[published].Key]++
+		s.lastError[pending[published].Key] = err.Error()
+	}
+	return published, err
+}
+
+func (s *memoryStore) DeletePublished(context.Context, time.Time) (int, error) {
+	return 0, nil
+}
+
+func (s *memoryStore) done() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.published) == len(s.events)
+}
+
+// runUntil runs run until done reports true or a second passes.
+func runUntil(t *testing.T, run func(ctx context.Context), done func() bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRelayPublishesFullBatchesAtOnce(t *testing.T) {
+	store := newMemoryStore(1, 2, 3, 4, 5)
+	publisher := &recordingPublisher{}
+	// With an hour between polls, only following up full batches right away
+	// publishes every event in time.
+	relay := NewRelay(store, publisher, time.Hour, 2, testRetries, 0, &testLogger)
+
+	runUntil(t, relay.Run, store.done)
+
+	if got, want := publisher.called(), []string{"1", "2", "3", "4", "5"}; !slices.Equal(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+	if store.takes != 3 {
+		t.Errorf("took %d batches, want 3", store.takes)
+	}
+}
+
+func TestRelayRetriesFailedEvent(t *testing.T) {
+	store := newMemoryStore(1, 2, 3, 4)
+	publisher := &recordingPublisher{failures: map[string]int{"2": 2}}
+	relay := NewRelay(store, publisher, time.Hour, 10, testRetries, 0, &testLogger)
+
+	runUntil(t, relay.Run, store.done)
+
+	// The failed event stops its batch, so no event is published before it.
+	if got, want := publisher.called(), []string{"1", "2", "2", "2", "3", "4"}; !slices.Equal(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+	if store.attempts["1"] != 1 || store.attempts["2"] != 3 || store.attempts["3"] != 1 {
+		t.Errorf("attempts = %v, want 1 for 1 and 3, 3 for 2", store.attempts)
+	}
+	if store.lastError["2"] != "publisher unavailable" {
+		t.Errorf("last error of 2 = %q", store.lastError["2"])
+	}
+}
+
+func TestRelayPublishStopsAtFailure(t *testing.T) {
+	publisher := &recordingPublisher{failures: map[string]int{"2": 1}}
+	relay := NewRelay(newMemoryStore(), publisher, time.Hour, 10, testRetries, 0, &testLogger)
+
+	events := []domain.CommentEvent{testEvent(1), testEvent(2), testEvent(3)}
+	published, err := relay.publish(context.Background(), events)
+	if err == nil {
+		t.Fatal("publish() error = nil")
+	}
+	if published != 1 {
+		t.Errorf("publish() = %d, want 1", published)
+	}
+	if got, want := publisher.called(), []string{"1", "2"}; !slices.Equal(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+
+	published, err = relay.publish(context.Background(), events[published:])
+	if err != nil || published != 2 {
+		t.Errorf("publish() = %d, %v, want 2, nil", published, err)
+	}
+}
+
+func TestPublishers(t *testing.T) {
+	failing := &recordingPublisher{failures: map[string]int{"1": 1}}
+	other := &recordingPublisher{}
+
+	if err := (Publishers{failing, other}).Publish(context.Background(), testEvent(1)); err == nil {
+		t.Error("Publish() error = nil, want the error of the failing publisher")
+	}
+	if got := other.called(); !slices.Equal(got, []string{"1"}) {
+		t.Errorf("other publisher got %v, want [1]", got)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	retries := retry.Strategy{Delay: time.Second, Backoff: 2}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{3, 4 * time.Second},
+		{20, maxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(retries, tt.failures); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+)
+
+// Tail follows the stored comment events from the time it starts and passes
+// every one of them to its publisher. Unlike the relay, which publishes each
+// event once for all instances, every instance runs its own tail, so the
+// live streams of each instance get all events.
+type Tail struct {
+	log       eventLog
+	publisher EventPublisher
+	interval  time.Duration
+	batch     int
+	retries   retry.Strategy
+	wake      chan struct{}
+	logger    *zlog.Zerolog
+}
+
+// NewTail creates a tail that looks for new events every interval and reads
+// up to batch of them at once.
+func NewTail(log eventLog, publisher EventPublisher, interval time.Duration, batch int, retries retry.Strategy, logger *zlog.Zerolog) *Tail {
+	return &Tail{
+		log:       log,
+		publisher: publisher,
+		interval:  interval,
+		batch:     batch,
+		retries:   retries,
+		wake:      make(chan struct{}, 1),
+		logger:    logger,
+	}
+}
+
+// Wake makes the tail look for new events now rather than on its next poll.
+// It does not block.
+func (t *Tail) Wake() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run follows the events until ctx is done.
+func (t *Tail) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	started := false
+	failures := 0
+	var pos domain.OutboxPosition
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-t.wake:
+			if failures > 0 {
+				// Waking does not cut the delay after a failure short.
+				continue
+			}
+			timer.Stop()
+		}
+
+		var read int
+		var err error
+		if !started {
+			pos, err = t.log.Head(ctx)
+			started = err == nil
+		} else {
+			read, pos, err = t.follow(ctx, pos)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		delay := t.interval
+		switch {
+		case err != nil:
+			failures++
+			delay = retryDelay(t.retries, failures)
+			t.logger.Error().
+				Err(err).
+				Int("failures", failures).
+				Dur("retry_in", delay).
+				Msg("Failed to follow comment events")
+		case read == t.batch:
+			// A full batch means more events may be waiting.
+			failures = 0
+			delay = 0
+		default:
+			failures = 0
+		}
+
+		timer.Reset(delay)
+	}
+}
+
+// follow publishes the events after pos and returns how many were read and
+// the position to go on from. After a failure the whole batch is published
+// again; the publisher drops the repeats.
+func (t *Tail) follow(ctx context.Context, pos domain.OutboxPosition) (int, domain.OutboxPosition, error) {
+	events, last, err := t.log.ListAfter(ctx, pos, t.batch)
+	if err != nil {
+		return 0, pos, err
+	}
+
+	for _, event := range events {
+		if err := t.publisher.Publish(ctx, event); err != nil {
+			return 0, pos, err
+		}
+	}
+
+	return len(events), last, nil
+}
@@ -0,0 +1,135 @@
+package outbox
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"comments-system/internal/domain"
+	"comments-system/internal/events"
+)
+
+// memoryLog is an outbox in memory whose events are ordered by transaction.
+type memoryLog struct {
+	mu     sync.Mutex
+	head   domain.OutboxPosition
+	events []domain.CommentEvent
+	txIDs  []int64
+	reads  int
+	headed chan struct{}
+}
+
+func newMemoryLog(head int64) *memoryLog {
+	return &memoryLog{head: domain.OutboxPosition{TxID: head}, headed: make(chan struct{})}
+}
+
+func (l *memoryLog) add(txID, id int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, testEvent(id))
+	l.txIDs = append(l.txIDs, txID)
+}
+
+func (l *memoryLog) Head(context.Context) (domain.OutboxPosition, error) {
+	close(l.headed)
+	return l.head, nil
+}
+
+func (l *memoryLog) ListAfter(_ context.Context, pos domain.OutboxPosition, limit int) ([]domain.CommentEvent, domain.OutboxPosition, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reads++
+
+	var events []domain.CommentEvent
+	last := pos
+	for i, event := range l.events {
+		at := domain.OutboxPosition{TxID: l.txIDs[i], ID: int64(event.ID)}
+		if at.TxID < pos.TxID || at.TxID == pos.TxID && at.ID <= pos.ID {
+			continue
+		}
+		if len(events) == limit {
+			break
+		}
+		events = append(events, event)
+		last = at
+	}
+	return events, last, nil
+}
+
+// follow runs tail, wakes it once it has found the head of log, as the
+// relay does after a change, and returns the keys of the first want events
+// the subscriber of bus gets.
+func follow(t *testing.T, tail *Tail, log *memoryLog, bus *events.Bus, want int) []string {
+	t.Helper()
+	sub, _, _ := bus.Subscribe(func(event domain.CommentEvent) (domain.CommentEvent, bool) { return event, true }, false, 0)
+	defer sub.Close()
+
+	var keys []string
+	runUntil(t, tail.Run, func() bool {
+		<-log.headed
+		tail.Wake()
+		keys = receive(t, sub, want)
+		return true
+	})
+	return keys
+}
+
+// receive collects the keys of the events of sub until want of them came.
+func receive(t *testing.T, sub *events.Subscription, want int) []string {
+	t.Helper()
+	var keys []string
+	timeout := time.After(time.Second)
+	for len(keys) < want {
+		select {
+		case event := <-sub.Events():
+			keys = append(keys, event.Key)
+		case <-timeout:
+			t.Fatalf("got %v, timed out waiting for %d events", keys, want)
+		}
+	}
+	return keys
+}
+
+func TestTailFollowsFromHead(t *testing.T) {
+	log := newMemoryLog(10)
+	log.add(5, 1) // stored before the tail started
+	log.add(10, 3)
+	log.add(11, 2) // committed after the transaction of 3
+	log.add(12, 4)
+	log.add(12, 5)
+	log.add(13, 6)
+
+	// With an hour between polls, only following up full batches right away
+	// passes every event on in time.
+	bus := events.NewBus(100)
+	got := follow(t, NewTail(log, bus, time.Hour, 2, testRetries, &testLogger), log, bus, 5)
+
+	if want := []string{"3", "2", "4", "5", "6"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if log.reads != 3 {
+		t.Errorf("read %d batches, want 3", log.reads)
+	}
+}
+
+func TestTailRepublishesBatchAfterFailure(t *testing.T) {
+	log := newMemoryLog(0)
+	log.add(1, 1)
+	log.add(1, 2)
+	log.add(2, 3)
+
+	bus := events.NewBus(100)
+	flaky := &recordingPublisher{failures: map[string]int{"2": 1}}
+	got := follow(t, NewTail(log, Publishers{bus, flaky}, time.Hour, 10, testRetries, &testLogger), log, bus, 3)
+
+	// The batch is published again from its start, and the bus drops the
+	// events it already has.
+	if want := []string{"1", "2", "1", "2", "3"}; !slices.Equal(flaky.called(), want) {
+		t.Errorf("publisher got %v, want %v", flaky.called(), want)
+	}
+	if want := []string{"1", "2", "3"}; !slices.Equal(got, want) {
+		t.Errorf("subscriber got %v, want %v", got, want)
+	}
+}
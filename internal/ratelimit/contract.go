@@ -0,0 +1,11 @@
+package ratelimit
+
+import (
+	"context"
+
+	"comments-system/internal/domain"
+)
+
+type store interface {
+	Take(ctx context.Context, key string, limit domain.RateLimit) (domain.RateLimitStatus, error)
+}
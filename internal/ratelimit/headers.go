@@ -0,0 +1,28 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+// SetHeaders describes the state of a limit in the RateLimit-* headers of
+// the IETF draft and, when the request was rejected, in Retry-After.
+func SetHeaders(h http.Header, status domain.RateLimitStatus) {
+	h.Set("RateLimit-Limit", strconv.Itoa(status.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(status.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(seconds(status.Reset)))
+	h.Set("RateLimit-Policy", strconv.Itoa(status.Limit)+";w="+strconv.Itoa(seconds(status.Window)))
+
+	if !status.Allowed {
+		h.Set("Retry-After", strconv.Itoa(max(seconds(status.RetryAfter), 1)))
+	}
+}
+
+// seconds rounds d up to whole seconds.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"comments-system/internal/domain"
+)
+
+// IPLimiter limits the requests of each client IP. Limiters sharing a store
+// and a name share their limits, e.g. for comments posted over HTTP and over
+// WebSocket.
+type IPLimiter struct {
+	store      store
+	name       string
+	limit      domain.RateLimit
+	trustProxy bool
+}
+
+// NewIPLimiter creates a limiter. Behind a reverse proxy, trustProxy takes
+// the client IP from X-Forwarded-For or X-Real-IP set by the proxy. A zero
+// limit allows everything.
+func NewIPLimiter(store store, name string, limit domain.RateLimit, trustProxy bool) *IPLimiter {
+	return &IPLimiter{
+		store:      store,
+		name:       name,
+		limit:      limit,
+		trustProxy: trustProxy,
+	}
+}
+
+// Enabled reports whether the limiter limits anything.
+func (l *IPLimiter) Enabled() bool {
+	return !l.limit.IsZero()
+}
+
+// Take counts a request of ip against its limit.
+func (l *IPLimiter) Take(ctx context.Context, ip string) (domain.RateLimitStatus, error) {
+	if !l.Enabled() {
+		return domain.RateLimitStatus{Allowed: true}, nil
+	}
+	return l.store.Take(ctx, l.name+":ip:"+ip, l.limit)
+}
+
+// ClientIP returns the IP address of the client that sent r. The proxy
+// headers are only believed when the limiter trusts the proxy, since clients
+// can send them too. For the same reason only the last X-Forwarded-For entry
+// counts: the proxy appends the address it sees to whatever the client sent.
+func (l *IPLimiter) ClientIP(r *http.Request) string {
+	if l.trustProxy {
+		forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); ip != "" {
+			return ip
+		}
+		if ip := r.Header.Get("X-Real-IP"); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
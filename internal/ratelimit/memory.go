@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+// sweepInterval is how often MemoryStore forgets buckets that have filled
+// up again.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	window  time.Duration
+}
+
+// MemoryStore keeps rate limit buckets in the memory of the process. Limits
+// are not shared between instances of the service.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Take counts a request against the bucket key.
+func (s *MemoryStore) Take(ctx context.Context, key string, limit domain.RateLimit) (domain.RateLimitStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Limit), updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens = refill(limit, b.tokens, now.Sub(b.updated))
+	b.updated = now
+	b.window = limit.Window
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	return status(limit, b.tokens, allowed), nil
+}
+
+// sweep removes the buckets that have been idle long enough to be full, so
+// they would behave like new ones anyway.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.window {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+// Rate limits are token buckets: a bucket holds up to Limit tokens, refills
+// at Limit tokens per Window and every request takes one token.
+
+// status describes a bucket left with tokens after a request.
+func status(limit domain.RateLimit, tokens float64, allowed bool) domain.RateLimitStatus {
+	perToken := limit.Window / time.Duration(limit.Limit)
+
+	st := domain.RateLimitStatus{
+		Allowed:   allowed,
+		Limit:     limit.Limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(limit.Limit) - tokens) * float64(perToken)),
+		Window:    limit.Window,
+	}
+	if !allowed {
+		st.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+
+	return st
+}
+
+// refill returns the tokens of a bucket that had tokens elapsed ago.
+func refill(limit domain.RateLimit, tokens float64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return tokens
+	}
+	tokens += float64(limit.Limit) * float64(elapsed) / float64(limit.Window)
+	return math.Min(tokens, float64(limit.Limit))
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"comments-system/internal/domain"
+
+	"github.com/go-redis/redis/v8"
+	wbfredis "github.com/wb-go/wbf/redis"
+)
+
+const redisKeyPrefix = "ratelimit:"
+
+// takeScript refills and takes from a bucket stored as a hash of its tokens
+// and the time of the last update in milliseconds. The Redis clock is used
+// so every instance of the service agrees on it. The bucket expires once it
+// would be full again.
+var takeScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or limit
+local ts = tonumber(state[2]) or now
+
+tokens = math.min(limit, tokens + math.max(0, now - ts) * limit / window)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], window)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps rate limit buckets in Redis so all instances of the
+// service share them.
+type RedisStore struct {
+	client *wbfredis.Client
+}
+
+func NewRedisStore(client *wbfredis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Take counts a request against the bucket key.
+func (s *RedisStore) Take(ctx context.Context, key string, limit domain.RateLimit) (domain.RateLimitStatus, error) {
+	res, err := takeScript.Run(ctx, s.client.Client, []string{redisKeyPrefix + key},
+		limit.Limit, limit.Window.Milliseconds()).Result()
+	if err != nil {
+		return domain.RateLimitStatus{}, fmt.Errorf("failed to take from rate limit bucket: %w", err)
+	}
+
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 2 {
+		return domain.RateLimitStatus{}, fmt.Errorf("unexpected rate limit script result: %v", res)
+	}
+	allowed, _ := values[0].(int64)
+	tokensStr, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return domain.RateLimitStatus{}, fmt.Errorf("failed to parse rate limit tokens: %w", err)
+	}
+
+	return status(limit, tokens, allowed == 1), nil
+}
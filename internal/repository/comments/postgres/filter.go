@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"comments-system/internal/domain"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// filterConditions appends the values of f to params and returns the SQL
+// conditions that select the matching rows of table.
+func filterConditions(params *[]interface{}, f domain.CommentFilter, table string) []string {
+	var conditions []string
+
+	add := func(format string, value interface{}) {
+		*params = append(*params, value)
+		conditions = append(conditions, fmt.Sprintf(format, table, len(*params)))
+	}
+
+	if f.Author != "" {
+		if f.AuthorPrefix {
+			add("%[1]s.author LIKE $%[2]d", likeEscaper.Replace(f.Author)+"%")
+		} else {
+			add("%[1]s.author = $%[2]d", f.Author)
+		}
+	}
+	if f.CreatedAfter != nil {
+		add("%[1]s.created_at >= $%[2]d", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		add("%[1]s.created_at < $%[2]d", *f.CreatedBefore)
+	}
+	if f.RootID != nil {
+		add("(%[1]s.id = $%[2]d OR %[1]s.root_id = $%[2]d)", *f.RootID)
+	}
+	if f.MinReplies > 0 {
+		add("(SELECT COUNT(*) FROM comments r WHERE r.parent_id = %[1]s.id) >= $%[2]d", f.MinReplies)
+	}
+	if f.IsRoot != nil {
+		if *f.IsRoot {
+			conditions = append(conditions, table+".parent_id IS NULL")
+		} else {
+			conditions = append(conditions, table+".parent_id IS NOT NULL")
+		}
+	}
+
+	return conditions
+}
@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"comments-system/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+const flagColumns = `id, comment_id, reporter, reason, note, created_at`
+
+// AddFlag records a flag on a comment. Once the comment has threshold open
+// flags an approved comment is moved to hideAs with a comment.moderated
+// event; a threshold of 0 never hides it. The second value is false if the
+// reporter has already flagged the comment.
+func (r *CommentsRepository) AddFlag(ctx context.Context, flag domain.Flag, threshold int, hideAs domain.CommentStatus) (domain.FlagResult, bool, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.FlagResult{}, false, err
+	}
+
+	// The comment row is locked first so concurrent flags are counted one
+	// after another and only one of them hides the comment.
+	lockQuery := `SELECT id FROM comments WHERE id = $1 AND site_id = $2 FOR UPDATE`
+
+	insertQuery := `INSERT INTO comment_flags (comment_id, reporter, reason, note, created_at)
+	                VALUES ($1, $2, $3, $4, NOW())
+	                ON CONFLICT (comment_id, reporter) DO NOTHING
+	                RETURNING id`
+
+	countQuery := `SELECT COUNT(*) FROM comment_flags WHERE comment_id = $1 AND resolved_at IS NULL`
+
+	hideQuery := `UPDATE comments SET status = $2 WHERE id = $1 AND status = 'approved'
+	              RETURNING ` + commentColumns
+
+	var result domain.FlagResult
+	added := true
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		result, added = domain.FlagResult{}, true
+
+		var lockedID int
+		err := tx.QueryRowContext(ctx, lockQuery, flag.CommentID, site).Scan(&lockedID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("comment not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock comment: %w", err)
+		}
+
+		var flagID int
+		err = tx.QueryRowContext(ctx, insertQuery, flag.CommentID, flag.Reporter, string(flag.Reason), flag.Note).Scan(&flagID)
+		if err == sql.ErrNoRows {
+			added = false
+		} else if err != nil {
+			return fmt.Errorf("failed to insert flag: %w", err)
+		}
+
+		err = tx.QueryRowContext(ctx, countQuery, flag.CommentID).Scan(&result.OpenFlags)
+		if err != nil {
+			return fmt.Errorf("failed to count flags: %w", err)
+		}
+
+		if !added || threshold <= 0 || result.OpenFlags < threshold {
+			return nil
+		}
+
+		hidden, err := scanComment(tx.QueryRowContext(ctx, hideQuery, flag.CommentID, string(hideAs)))
+		if err == sql.ErrNoRows {
+			// Only approved comments are hidden.
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to hide flagged comment: %w", err)
+		}
+		result.Hidden = true
+
+		return addEvent(ctx, tx, site, domain.EventCommentModerated, hidden)
+	})
+	if err != nil {
+		return domain.FlagResult{}, false, fmt.Errorf("failed to flag comment: %w", err)
+	}
+
+	return result, added, nil
+}
+
+// ListFlagged returns a page of the comments with open flags, the most
+// flagged first, and the total number of them.
+func (r *CommentsRepository) ListFlagged(ctx context.Context, limit, offset int) ([]domain.FlaggedComment, int, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	countQuery := `SELECT COUNT(DISTINCT f.comment_id)
+	               FROM comment_flags f
+	               INNER JOIN comments c ON c.id = f.comment_id
+	               WHERE c.site_id = $1 AND f.resolved_at IS NULL`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, countQuery, site)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count flagged comments: %w", err)
+	}
+
+	var total int
+	err = row.Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to scan count: %w", err)
+	}
+
+	query := `
+	SELECT ` + commentColumnsOf("c") + `
+	FROM comments c
+	INNER JOIN (
+		SELECT comment_id, COUNT(*) AS open_flags, MIN(created_at) AS first_flagged_at
+		FROM comment_flags
+		WHERE resolved_at IS NULL
+		GROUP BY comment_id
+	) f ON f.comment_id = c.id
+	WHERE c.site_id = $1
+	ORDER BY f.open_flags DESC, f.first_flagged_at ASC, c.id ASC
+	LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, site, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query flagged comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments, err := scanComments(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(comments) == 0 {
+		return nil, total, nil
+	}
+
+	ids := make([]int, len(comments))
+	for i, c := range comments {
+		ids[i] = c.ID
+	}
+
+	flags, err := r.openFlags(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	flagged := make([]domain.FlaggedComment, len(comments))
+	for i, c := range comments {
+		flagged[i] = domain.FlaggedComment{Comment: c, Flags: flags[c.ID]}
+	}
+
+	return flagged, total, nil
+}
+
+// ResolveFlags closes the open flags of the given comments and returns how
+// many flags were closed.
+func (r *CommentsRepository) ResolveFlags(ctx context.Context, ids []int) (int, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := r.db.ExecWithRetry(ctx, r.retries, resolveFlagsQuery, site, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve flags: %w", err)
+	}
+
+	resolved, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return int(resolved), nil
+}
+
+// resolveFlagsQuery closes the open flags of the comments $2 of site $1.
+const resolveFlagsQuery = `UPDATE comment_flags f SET resolved_at = NOW()
+	FROM comments c
+	WHERE c.id = f.comment_id AND c.site_id = $1 AND f.comment_id = ANY($2) AND f.resolved_at IS NULL`
+
+// openFlags returns the open flags of the given comments by comment ID,
+// newest first.
+func (r *CommentsRepository) openFlags(ctx context.Context, ids []int) (map[int][]domain.Flag, error) {
+	query := `SELECT ` + flagColumns + ` FROM comment_flags
+	          WHERE comment_id = ANY($1) AND resolved_at IS NULL
+	          ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := make(map[int][]domain.Flag)
+	for rows.Next() {
+		var f domain.Flag
+		var reason string
+		err := rows.Scan(&f.ID, &f.CommentID, &f.Reporter, &reason, &f.Note, &f.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan flag: %w", err)
+		}
+		f.Reason = domain.FlagReason(reason)
+
+		flags[f.CommentID] = append(flags[f.CommentID], f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating flags: %w", err)
+	}
+
+	return flags, nil
+}
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+// ListByStatus returns a page of the comments with the given status, oldest
+// first, and the total number of them.
+func (r *CommentsRepository) ListByStatus(ctx context.Context, status domain.CommentStatus, limit, offset int) ([]domain.Comment, int, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	countQuery := `SELECT COUNT(*) FROM comments WHERE site_id = $1 AND status = $2`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, countQuery, site, string(status))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count comments by status: %w", err)
+	}
+
+	var total int
+	err = row.Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to scan count: %w", err)
+	}
+
+	query := `SELECT ` + commentColumns + ` FROM comments
+	          WHERE site_id = $1 AND status = $2
+	          ORDER BY created_at ASC, id ASC
+	          LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, site, string(status), limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query comments by status: %w", err)
+	}
+	defer rows.Close()
+
+	comments, err := scanComments(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return comments, total, nil
+}
+
+// SetStatus moves the given comments to status and returns how many of them
+// were found. Open flags on the comments are resolved by the decision, and a
+// comment.moderated event is recorded for each comment.
+func (r *CommentsRepository) SetStatus(ctx context.Context, ids []int, status domain.CommentStatus) (int, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `UPDATE comments SET status = $3, moderated_at = NOW()
+	          WHERE site_id = $1 AND id = ANY($2)
+	          RETURNING ` + commentColumns
+
+	var updated []domain.Comment
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, site, pq.Array(ids), string(status))
+		if err != nil {
+			return fmt.Errorf("failed to update comments: %w", err)
+		}
+
+		updated, err = scanComments(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, resolveFlagsQuery, site, pq.Array(ids))
+		if err != nil {
+			return fmt.Errorf("failed to resolve flags: %w", err)
+		}
+
+		for _, c := range updated {
+			if err := addEvent(ctx, tx, site, domain.EventCommentModerated, c); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to set comment status: %w", err)
+	}
+
+	return len(updated), nil
+}
+
+// GetByIDs returns the comments of the site with the given IDs.
+func (r *CommentsRepository) GetByIDs(ctx context.Context, ids []int) ([]domain.Comment, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + commentColumns + ` FROM comments WHERE site_id = $1 AND id = ANY($2) ORDER BY id`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, site, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	return scanComments(rows)
+}
+
+// RecentByAuthor returns up to limit comments posted since the given time,
+// newest first, by the user with userID or, for guests, under the author
+// name.
+func (r *CommentsRepository) RecentByAuthor(ctx context.Context, userID *int, author string, since time.Time, limit int) ([]domain.Comment, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params := []interface{}{site, since, limit}
+	authorCondition := "user_id IS NULL AND author = $4"
+	if userID != nil {
+		params = append(params, *userID)
+		authorCondition = "user_id = $4"
+	} else {
+		params = append(params, author)
+	}
+
+	query := `SELECT ` + commentColumns + ` FROM comments
+	          WHERE site_id = $1 AND ` + authorCondition + ` AND created_at >= $2 AND deleted_at IS NULL
+	          ORDER BY created_at DESC
+	          LIMIT $3`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent comments: %w", err)
+	}
+	defer rows.Close()
+
+	return scanComments(rows)
+}
@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+const insertEventQuery = `INSERT INTO outbox (site_id, event_type, payload, created_at)
+                          VALUES ($1, $2, $3, NOW())`
+
+// addEvent records the event of a change to a comment in the transaction of
+// the change. Secrets of the comment are left out and a deleted comment is
+// marked deleted whatever the delete mode.
+func addEvent(ctx context.Context, tx *sql.Tx, site int, eventType domain.EventType, comment domain.Comment) error {
+	comment.ManageToken, comment.ManageTokenHash = "", ""
+	comment.Children = nil
+	if eventType == domain.EventCommentDeleted && comment.DeletedAt == nil {
+		now := time.Now()
+		comment.DeletedAt = &now
+	}
+
+	payload, err := json.Marshal(comment)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	_, err = tx.ExecContext(ctx, insertEventQuery, site, string(eventType), string(payload))
+	if err != nil {
+		return fmt.Errorf("failed to record %s event: %w", eventType, err)
+	}
+
+	return nil
+}
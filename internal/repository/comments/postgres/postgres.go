@@ -4,8 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strconv"
-	"strings"
 	"time"
 
 	"comments-system/internal/domain"
@@ -15,270 +13,331 @@ import (
 )
 
 type CommentsRepository struct {
-	db      *dbpg.DB
-	retries retry.Strategy
+	db             *dbpg.DB
+	retries        retry.Strategy
+	searchLanguage string
 }
 
-func NewCommentsRepository(db *dbpg.DB, retries retry.Strategy) *CommentsRepository {
+// NewCommentsRepository creates the repository. searchLanguage is the text
+// search configuration used to parse search queries.
+func NewCommentsRepository(db *dbpg.DB, retries retry.Strategy, searchLanguage string) *CommentsRepository {
 	return &CommentsRepository{
-		db:      db,
-		retries: retries,
+		db:             db,
+		retries:        retries,
+		searchLanguage: searchLanguage,
 	}
 }
 
+// Create stores a new comment and records its comment.created event.
 func (r *CommentsRepository) Create(ctx context.Context, comment domain.Comment) (domain.Comment, error) {
 	var id int
+	var rootID sql.NullInt32
 	var createdAt, updatedAt time.Time
 
-	query := `INSERT INTO comments (parent_id, content, author, created_at, updated_at) 
-	          VALUES ($1, $2, $3, NOW(), NOW()) 
-	          RETURNING id, created_at, updated_at`
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+
+	// The thread is created on its first comment.
+	query := `WITH thread AS (
+	              INSERT INTO threads (site_id, key) VALUES ($5, $4) ON CONFLICT (site_id, key) DO NOTHING
+	          )
+	          INSERT INTO comments (site_id, parent_id, root_id, thread_key, content, author, user_id, manage_token_hash, status, created_at, updated_at) 
+	          VALUES ($5, $1, (SELECT COALESCE(root_id, id) FROM comments WHERE id = $1 AND site_id = $5), $4, $2, $3, $6, NULLIF($7, ''), $8, NOW(), NOW()) 
+	          RETURNING id, root_id, created_at, updated_at`
 
-	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, comment.ParentID, comment.Content, comment.Author)
+	var created domain.Comment
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, query, comment.ParentID, comment.Content, comment.Author, comment.ThreadKey, site, comment.UserID, comment.ManageTokenHash, string(comment.Status))
+
+		err := row.Scan(&id, &rootID, &createdAt, &updatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to scan created comment: %w", err)
+		}
+
+		created = comment
+		created.ID = id
+		created.RootID = id
+		if rootID.Valid {
+			created.RootID = int(rootID.Int32)
+		}
+		created.CreatedAt = createdAt
+		created.UpdatedAt = updatedAt
+
+		return addEvent(ctx, tx, site, domain.EventCommentCreated, created)
+	})
 	if err != nil {
 		return domain.Comment{}, fmt.Errorf("failed to create comment: %w", err)
 	}
 
-	err = row.Scan(&id, &createdAt, &updatedAt)
+	return created, nil
+}
+
+func (r *CommentsRepository) Exists(ctx context.Context, id int) (bool, error) {
+	site, err := siteID(ctx)
 	if err != nil {
-		return domain.Comment{}, fmt.Errorf("failed to scan created comment: %w", err)
+		return false, err
 	}
 
-	comment.ID = id
-	comment.CreatedAt = createdAt
-	comment.UpdatedAt = updatedAt
-	return comment, nil
-}
+	query := `SELECT EXISTS(SELECT 1 FROM comments WHERE id = $1 AND site_id = $2)`
 
-func (r *CommentsRepository) GetTree(ctx context.Context, rootID *int, page, pageSize int, searchQuery, sortBy, sortOrder string) ([]domain.Comment, int, error) {
-	allComments, total, err := r.getAllComments(ctx, rootID, page, pageSize, searchQuery, sortBy, sortOrder)
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, id, site)
 	if err != nil {
-		return nil, 0, err
+		return false, fmt.Errorf("failed to check comment existence: %w", err)
 	}
 
-	comments := r.buildCommentTree(allComments, rootID)
+	var exists bool
+	err = row.Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to scan existence: %w", err)
+	}
 
-	return comments, total, nil
+	return exists, nil
 }
 
-func (r *CommentsRepository) getAllComments(ctx context.Context, rootID *int, page, pageSize int, searchQuery, sortBy, sortOrder string) ([]domain.Comment, int, error) {
-	var whereConditions []string
-	var params []interface{}
-
-	if rootID != nil {
-		query := `
-		WITH RECURSIVE comment_tree AS (
-			SELECT id, parent_id, content, author, created_at, updated_at 
-			FROM comments 
-			WHERE id = $1
-			
-			UNION ALL
-			
-			SELECT c.id, c.parent_id, c.content, c.author, c.created_at, c.updated_at 
-			FROM comments c 
-			INNER JOIN comment_tree ct ON c.parent_id = ct.id
-		)
-		SELECT id, parent_id, content, author, created_at, updated_at 
-		FROM comment_tree
-		WHERE 1=1
-		`
-
-		whereConditions = append(whereConditions, "1=1")
-		params = append(params, *rootID)
-
-		if searchQuery != "" {
-			whereConditions = append(whereConditions, fmt.Sprintf("content ILIKE $%d", len(params)+1))
-			params = append(params, "%"+searchQuery+"%")
-		}
+func (r *CommentsRepository) GetByID(ctx context.Context, id int) (domain.Comment, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.Comment{}, err
+	}
 
-		whereClause := "WHERE " + strings.Join(whereConditions, " AND ")
-		query = strings.Replace(query, "WHERE 1=1", whereClause, 1)
+	query := `SELECT ` + commentColumns + `, COALESCE(manage_token_hash, '') FROM comments WHERE id = $1 AND site_id = $2`
 
-		rows, err := r.db.QueryWithRetry(ctx, r.retries, query, params...)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to query comment tree: %w", err)
-		}
-		defer rows.Close()
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, id, site)
+	if err != nil {
+		return domain.Comment{}, fmt.Errorf("failed to query comment: %w", err)
+	}
 
-		var comments []domain.Comment
-		for rows.Next() {
-			var c domain.Comment
-			var pid sql.NullInt32
+	var manageTokenHash string
+	c, err := scanComment(row, &manageTokenHash)
+	if err == sql.ErrNoRows {
+		return domain.Comment{}, fmt.Errorf("comment not found")
+	}
+	if err != nil {
+		return domain.Comment{}, fmt.Errorf("failed to scan comment: %w", err)
+	}
+	c.ManageTokenHash = manageTokenHash
 
-			err := rows.Scan(&c.ID, &pid, &c.Content, &c.Author, &c.CreatedAt, &c.UpdatedAt)
-			if err != nil {
-				return nil, 0, fmt.Errorf("failed to scan comment row: %w", err)
-			}
+	return c, nil
+}
 
-			if pid.Valid {
-				pidInt := int(pid.Int32)
-				c.ParentID = &pidInt
-			}
+// CountReplies returns the number of direct replies to a comment and the
+// size of its whole descendant set, counting only what the caller may see.
+func (r *CommentsRepository) CountReplies(ctx context.Context, id int) (int, int, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
 
-			comments = append(comments, c)
-		}
+	params := []interface{}{id, site}
+	v := visibilityFor(ctx, &params)
 
-		if err := rows.Err(); err != nil {
-			return nil, 0, fmt.Errorf("error iterating comment tree: %w", err)
-		}
+	query := `
+	WITH RECURSIVE descendants AS (
+		SELECT id, 1 AS depth FROM comments WHERE parent_id = $1 AND site_id = $2 AND ` + v.on("comments") + `
 
-		return comments, len(comments), nil
-	} else {
-		whereConditions = append(whereConditions, "1=1")
+		UNION ALL
 
-		if searchQuery != "" {
-			whereConditions = append(whereConditions, "content ILIKE $1")
-			params = append(params, "%"+searchQuery+"%")
-		}
+		SELECT c.id, d.depth + 1 FROM comments c
+		INNER JOIN descendants d ON c.parent_id = d.id
+		WHERE ` + v.on("c") + `
+	)
+	SELECT COUNT(*) FILTER (WHERE depth = 1), COUNT(*) FROM descendants
+	`
 
-		whereClause := "WHERE " + strings.Join(whereConditions, " AND ")
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, params...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count replies: %w", err)
+	}
 
-		countQuery := `SELECT COUNT(*) FROM comments ` + whereClause
-		row, err := r.db.QueryRowWithRetry(ctx, r.retries, countQuery, params...)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to count comments: %w", err)
-		}
+	var direct, total int
+	err = row.Scan(&direct, &total)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to scan reply counts: %w", err)
+	}
 
-		var total int
-		err = row.Scan(&total)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to scan count: %w", err)
-		}
+	return direct, total, nil
+}
 
-		sortField := "created_at"
-		switch sortBy {
-		case "id":
-			sortField = "id"
-		case "updated_at":
-			sortField = "updated_at"
-		}
+// GetAncestors returns the ancestors of a comment ordered from the thread
+// root down to the direct parent.
+func (r *CommentsRepository) GetAncestors(ctx context.Context, id int) ([]domain.Comment, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		sortDir := "DESC"
-		if sortOrder == "asc" {
-			sortDir = "ASC"
-		}
+	query := `
+	WITH RECURSIVE ancestors AS (
+		SELECT ` + commentColumnsOf("p") + `, 1 AS depth
+		FROM comments c
+		INNER JOIN comments p ON p.id = c.parent_id
+		WHERE c.id = $1 AND c.site_id = $2
 
-		query := `SELECT id, parent_id, content, author, created_at, updated_at 
-				  FROM comments ` + whereClause +
-			` ORDER BY ` + sortField + ` ` + sortDir +
-			` LIMIT $` + strconv.Itoa(len(params)+1) +
-			` OFFSET $` + strconv.Itoa(len(params)+2)
+		UNION ALL
 
-		params = append(params, pageSize, (page-1)*pageSize)
+		SELECT ` + commentColumnsOf("p") + `, a.depth + 1
+		FROM comments p
+		INNER JOIN ancestors a ON p.id = a.parent_id
+	)
+	SELECT ` + commentColumns + `
+	FROM ancestors
+	ORDER BY depth DESC
+	`
 
-		rows, err := r.db.QueryWithRetry(ctx, r.retries, query, params...)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to query comments: %w", err)
-		}
-		defer rows.Close()
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, id, site)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comment ancestors: %w", err)
+	}
+	defer rows.Close()
 
-		var comments []domain.Comment
-		for rows.Next() {
-			var c domain.Comment
-			var pid sql.NullInt32
+	ancestors, err := scanComments(rows)
+	if err != nil {
+		return nil, err
+	}
 
-			err := rows.Scan(&c.ID, &pid, &c.Content, &c.Author, &c.CreatedAt, &c.UpdatedAt)
-			if err != nil {
-				return nil, 0, fmt.Errorf("failed to scan comment row: %w", err)
-			}
+	return ancestors, nil
+}
 
-			if pid.Valid {
-				pidInt := int(pid.Int32)
-				c.ParentID = &pidInt
-			}
+// GetSubtree returns a comment with the replies the caller may see down to
+// maxDepth levels.
+func (r *CommentsRepository) GetSubtree(ctx context.Context, id, maxDepth int) (domain.Comment, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.Comment{}, err
+	}
 
-			comments = append(comments, c)
-		}
+	params := []interface{}{id, maxDepth, site}
+	v := visibilityFor(ctx, &params)
 
-		if err := rows.Err(); err != nil {
-			return nil, 0, fmt.Errorf("error iterating comments: %w", err)
-		}
+	query := `
+	WITH RECURSIVE comment_tree AS (
+		SELECT ` + commentColumns + `, 0 AS depth
+		FROM comments
+		WHERE id = $1 AND site_id = $3
 
-		return comments, total, nil
-	}
-}
+		UNION ALL
 
-func (r *CommentsRepository) buildCommentTree(comments []domain.Comment, rootID *int) []domain.Comment {
-	commentMap := make(map[int]*domain.Comment)
-	var roots []domain.Comment
+		SELECT ` + commentColumnsOf("c") + `, ct.depth + 1
+		FROM comments c
+		INNER JOIN comment_tree ct ON c.parent_id = ct.id
+		WHERE ct.depth < $2 AND ` + v.on("c") + `
+	)
+	SELECT ` + commentColumns + `
+	FROM comment_tree
+	`
 
-	for i := range comments {
-		commentMap[comments[i].ID] = &comments[i]
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, params...)
+	if err != nil {
+		return domain.Comment{}, fmt.Errorf("failed to query comment subtree: %w", err)
 	}
+	defer rows.Close()
 
-	for i := range comments {
-		comment := &comments[i]
-
-		if rootID != nil && comment.ID == *rootID {
-			roots = append(roots, *comment)
-		} else if rootID == nil && comment.ParentID == nil {
-			roots = append(roots, *comment)
-		}
-
-		if comment.ParentID != nil {
-			if parent, ok := commentMap[*comment.ParentID]; ok {
-				parent.Children = append(parent.Children, *comment)
-			}
-		}
+	comments, err := scanComments(rows)
+	if err != nil {
+		return domain.Comment{}, err
 	}
 
-	if rootID != nil && len(roots) == 0 {
-		if rootComment, ok := commentMap[*rootID]; ok {
-			roots = append(roots, *rootComment)
-		}
+	tree := r.buildCommentTree(comments, chronological, &id)
+	if len(tree) == 0 {
+		return domain.Comment{}, fmt.Errorf("comment not found")
 	}
 
-	return roots
+	return tree[0], nil
 }
 
-func (r *CommentsRepository) Exists(ctx context.Context, id int) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM comments WHERE id = $1)`
-
-	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, id)
+// Update replaces the content of a comment and stores the previous content
+// as a revision in the same statement, recording a comment.updated event.
+func (r *CommentsRepository) Update(ctx context.Context, id int, content string) (domain.Comment, error) {
+	site, err := siteID(ctx)
 	if err != nil {
-		return false, fmt.Errorf("failed to check comment existence: %w", err)
+		return domain.Comment{}, err
 	}
 
-	var exists bool
-	err = row.Scan(&exists)
+	query := `
+	WITH old AS (
+		SELECT id, content, updated_at FROM comments WHERE id = $1 AND site_id = $3 FOR UPDATE
+	),
+	revision AS (
+		INSERT INTO comment_revisions (comment_id, content, created_at)
+		SELECT id, content, updated_at FROM old
+	)
+	UPDATE comments c SET content = $2, updated_at = NOW()
+	FROM old
+	WHERE c.id = old.id
+	RETURNING ` + commentColumnsOf("c")
+
+	var c domain.Comment
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		c, err = scanComment(tx.QueryRowContext(ctx, query, id, content, site))
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("comment not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to scan updated comment: %w", err)
+		}
+
+		return addEvent(ctx, tx, site, domain.EventCommentUpdated, c)
+	})
 	if err != nil {
-		return false, fmt.Errorf("failed to scan existence: %w", err)
+		return domain.Comment{}, fmt.Errorf("failed to update comment: %w", err)
 	}
 
-	return exists, nil
+	return c, nil
 }
 
-func (r *CommentsRepository) GetByID(ctx context.Context, id int) (domain.Comment, error) {
-	var c domain.Comment
-	var pid sql.NullInt32
+func (r *CommentsRepository) GetRevisions(ctx context.Context, commentID int) ([]domain.CommentRevision, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	query := `SELECT id, parent_id, content, author, created_at, updated_at FROM comments WHERE id = $1`
+	query := `SELECT r.id, r.comment_id, r.content, r.created_at 
+	          FROM comment_revisions r
+	          INNER JOIN comments c ON c.id = r.comment_id
+	          WHERE r.comment_id = $1 AND c.site_id = $2
+	          ORDER BY r.created_at DESC, r.id DESC`
 
-	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, id)
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, commentID, site)
 	if err != nil {
-		return domain.Comment{}, fmt.Errorf("failed to query comment: %w", err)
+		return nil, fmt.Errorf("failed to query comment revisions: %w", err)
 	}
+	defer rows.Close()
 
-	err = row.Scan(&c.ID, &pid, &c.Content, &c.Author, &c.CreatedAt, &c.UpdatedAt)
-	if err == sql.ErrNoRows {
-		return domain.Comment{}, fmt.Errorf("comment not found")
-	}
-	if err != nil {
-		return domain.Comment{}, fmt.Errorf("failed to scan comment: %w", err)
+	revisions := []domain.CommentRevision{}
+	for rows.Next() {
+		var rev domain.CommentRevision
+
+		err := rows.Scan(&rev.ID, &rev.CommentID, &rev.Content, &rev.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment revision: %w", err)
+		}
+
+		revisions = append(revisions, rev)
 	}
 
-	if pid.Valid {
-		pidInt := int(pid.Int32)
-		c.ParentID = &pidInt
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comment revisions: %w", err)
 	}
 
-	return c, nil
+	return revisions, nil
 }
 
+// Delete removes a comment with all its replies and records a
+// comment.deleted event for the comment itself.
 func (r *CommentsRepository) Delete(ctx context.Context, id int) error {
+	site, err := siteID(ctx)
+	if err != nil {
+		return err
+	}
+
+	lockQuery := `SELECT ` + commentColumns + ` FROM comments WHERE id = $1 AND site_id = $2 FOR UPDATE`
+
 	query := `
 	WITH RECURSIVE descendants AS (
-		SELECT id FROM comments WHERE id = $1
+		SELECT id FROM comments WHERE id = $1 AND site_id = $2
 		
 		UNION
 		
@@ -288,10 +347,105 @@ func (r *CommentsRepository) Delete(ctx context.Context, id int) error {
 	DELETE FROM comments WHERE id IN (SELECT id FROM descendants)
 	`
 
-	_, err := r.db.ExecWithRetry(ctx, r.retries, query, id)
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		c, err := scanComment(tx.QueryRowContext(ctx, lockQuery, id, site))
+		if err == sql.ErrNoRows {
+			// Already gone, e.g. with a deleted ancestor.
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock comment: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, query, id, site)
+		if err != nil {
+			return err
+		}
+
+		return addEvent(ctx, tx, site, domain.EventCommentDeleted, c)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to delete comment tree: %w", err)
 	}
 
 	return nil
 }
+
+// RevokeManageToken stops the management token of a guest comment from
+// working.
+func (r *CommentsRepository) RevokeManageToken(ctx context.Context, id int) error {
+	site, err := siteID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE comments SET manage_token_hash = NULL WHERE id = $1 AND site_id = $2`
+
+	_, err = r.db.ExecWithRetry(ctx, r.retries, query, id, site)
+	if err != nil {
+		return fmt.Errorf("failed to revoke manage token: %w", err)
+	}
+
+	return nil
+}
+
+// SoftDelete marks a comment as deleted while keeping it and its replies in
+// the tree, recording a comment.deleted event.
+func (r *CommentsRepository) SoftDelete(ctx context.Context, id int) error {
+	site, err := siteID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE comments SET deleted_at = NOW() WHERE id = $1 AND site_id = $2 AND deleted_at IS NULL
+	          RETURNING ` + commentColumns
+
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		c, err := scanComment(tx.QueryRowContext(ctx, query, id, site))
+		if err == sql.ErrNoRows {
+			// Already deleted.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return addEvent(ctx, tx, site, domain.EventCommentDeleted, c)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to soft delete comment: %w", err)
+	}
+
+	return nil
+}
+
+// Restore brings back a soft deleted comment and records a comment.updated
+// event.
+func (r *CommentsRepository) Restore(ctx context.Context, id int) (domain.Comment, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+
+	query := `UPDATE comments SET deleted_at = NULL WHERE id = $1 AND site_id = $2 
+	          RETURNING ` + commentColumns
+
+	var c domain.Comment
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		c, err = scanComment(tx.QueryRowContext(ctx, query, id, site))
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("comment not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to scan restored comment: %w", err)
+		}
+
+		return addEvent(ctx, tx, site, domain.EventCommentUpdated, c)
+	})
+	if err != nil {
+		return domain.Comment{}, fmt.Errorf("failed to restore comment: %w", err)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"comments-system/internal/domain"
+)
+
+// commentColumns is the column list every comment query selects, in the
+// order expected by scanComment.
+const commentColumns = `id, parent_id, thread_key, content, author, created_at, updated_at, deleted_at, upvotes, downvotes, user_id, status, root_id`
+
+// commentColumnsOf returns commentColumns qualified with a table alias.
+func commentColumnsOf(alias string) string {
+	columns := strings.Split(commentColumns, ", ")
+	for i, column := range columns {
+		columns[i] = alias + "." + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanComment reads commentColumns from row. Any extra destinations receive
+// the columns selected after them.
+func scanComment(row rowScanner, extra ...interface{}) (domain.Comment, error) {
+	var c domain.Comment
+	var pid sql.NullInt32
+	var deletedAt sql.NullTime
+	var userID sql.NullInt32
+	var status string
+	var rootID sql.NullInt32
+
+	dest := append([]interface{}{&c.ID, &pid, &c.ThreadKey, &c.Content, &c.Author, &c.CreatedAt, &c.UpdatedAt, &deletedAt, &c.Upvotes, &c.Downvotes, &userID, &status, &rootID}, extra...)
+
+	err := row.Scan(dest...)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+
+	if pid.Valid {
+		pidInt := int(pid.Int32)
+		c.ParentID = &pidInt
+	}
+	if deletedAt.Valid {
+		deletedAtTime := deletedAt.Time
+		c.DeletedAt = &deletedAtTime
+	}
+	c.Status = domain.CommentStatus(status)
+	c.RootID = c.ID
+	if rootID.Valid {
+		c.RootID = int(rootID.Int32)
+	}
+	if userID.Valid {
+		userIDInt := int(userID.Int32)
+		c.UserID = &userIDInt
+	}
+
+	return c, nil
+}
+
+func scanComments(rows *sql.Rows) ([]domain.Comment, error) {
+	var comments []domain.Comment
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment row: %w", err)
+		}
+
+		comments = append(comments, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comments: %w", err)
+	}
+
+	return comments, nil
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"comments-system/internal/domain"
+)
+
+// Search returns a page of comments matching q.Search and q.Filter, each with
+// the chain of its ancestors the caller may see. Deleted comments never
+// match. The second value is the total number of matches if the query asked
+// for it, the third tells whether more matches follow the page.
+func (r *CommentsRepository) Search(ctx context.Context, q domain.CommentsQuery) ([]domain.SearchHit, int, bool, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	params := []interface{}{site}
+	v := visibilityFor(ctx, &params)
+	whereConditions := []string{"site_id = $1", "deleted_at IS NULL", v.on("comments")}
+
+	if q.ThreadKey != "" {
+		params = append(params, q.ThreadKey)
+		whereConditions = append(whereConditions, fmt.Sprintf("thread_key = $%d", len(params)))
+	}
+
+	// Without a text query only the comments selected by the filter count as
+	// matched, and there is nothing to highlight.
+	tsQuery := ""
+	headline := "NULL::text"
+	matchedColumn, ancestorMatchedColumn := "TRUE", "FALSE"
+	if q.Search != "" {
+		tsQuery, headline = r.searchExprs(&params, q.Search)
+		whereConditions = append(whereConditions, "search_vector @@ "+tsQuery)
+		matchedColumn = "c.search_vector @@ " + tsQuery
+		ancestorMatchedColumn = matchedColumn
+	}
+	whereConditions = append(whereConditions, filterConditions(&params, q.Filter, "comments")...)
+
+	whereClause := "WHERE " + strings.Join(whereConditions, " AND ")
+
+	var total int
+	if q.WithTotal {
+		countQuery := `SELECT COUNT(*) FROM comments ` + whereClause
+		row, err := r.db.QueryRowWithRetry(ctx, r.retries, countQuery, params...)
+		if err != nil {
+			return nil, 0, false, fmt.Errorf("failed to count search matches: %w", err)
+		}
+
+		err = row.Scan(&total)
+		if err != nil {
+			return nil, 0, false, fmt.Errorf("failed to scan count: %w", err)
+		}
+	}
+
+	spec := sortSpecFor(q.SortBy, tsQuery)
+
+	sortDir := "DESC"
+	if q.SortOrder == "asc" {
+		sortDir = "ASC"
+	}
+	orderBy := spec.expr + ` ` + sortDir + `, id ` + sortDir
+
+	limitParamN := len(params) + 1
+
+	query := `
+	WITH RECURSIVE matches AS (
+		SELECT id AS match_id, ROW_NUMBER() OVER (ORDER BY ` + orderBy + `) AS match_pos
+		FROM (
+			SELECT id, created_at, updated_at, upvotes, downvotes, search_vector
+			FROM comments ` + whereClause + `
+			ORDER BY ` + orderBy + `
+			LIMIT $` + strconv.Itoa(limitParamN) + ` OFFSET $` + strconv.Itoa(limitParamN+1) + `
+		) page
+	),
+	path AS (
+		SELECT m.match_id, m.match_pos, 0 AS depth,
+		       ` + commentColumnsOf("c") + `,
+		       ` + matchedColumn + ` AS matched
+		FROM matches m
+		INNER JOIN comments c ON c.id = m.match_id
+
+		UNION ALL
+
+		SELECT p.match_id, p.match_pos, p.depth + 1,
+		       ` + commentColumnsOf("c") + `,
+		       ` + ancestorMatchedColumn + `
+		FROM path p
+		INNER JOIN comments c ON c.id = p.parent_id AND ` + v.on("c") + `
+	)
+	SELECT ` + commentColumns + `, match_id, depth, matched,
+	       CASE WHEN depth = 0 THEN ` + headline + ` END AS headline
+	FROM path
+	ORDER BY match_pos, depth DESC
+	`
+
+	// One extra match tells whether there is a next page.
+	params = append(params, q.PageSize+1, (q.Page-1)*q.PageSize)
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, params...)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("failed to query search matches: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []domain.SearchHit
+	for rows.Next() {
+		var matchID, depth int
+		var matched bool
+		var headlineText *string
+
+		c, err := scanComment(rows, &matchID, &depth, &matched, &headlineText)
+		if err != nil {
+			return nil, 0, false, fmt.Errorf("failed to scan search row: %w", err)
+		}
+		c.Matched = matched
+
+		// Rows of one match come root first and end with the match itself.
+		// The chain stops below an ancestor hidden from the caller, so the
+		// root is taken from the comment rather than the first row.
+		if len(hits) == 0 || hits[len(hits)-1].Comment.ID != 0 {
+			hits = append(hits, domain.SearchHit{RootID: c.RootID})
+		}
+		hit := &hits[len(hits)-1]
+
+		if depth == 0 {
+			if headlineText != nil {
+				c.Headline = *headlineText
+			}
+			hit.Comment = c
+		} else {
+			hit.Ancestors = append(hit.Ancestors, c)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, false, fmt.Errorf("error iterating search matches: %w", err)
+	}
+
+	hasMore := len(hits) > q.PageSize
+	if hasMore {
+		hits = hits[:q.PageSize]
+	}
+
+	return hits, total, hasMore, nil
+}
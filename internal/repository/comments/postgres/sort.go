@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+
+	"comments-system/internal/domain"
+)
+
+// sortSpec describes how comments are ordered for a sort_by value.
+type sortSpec struct {
+	// expr is the SQL expression over comments columns to order by. For
+	// specs that need a search it contains a %[1]s verb for the tsquery.
+	expr string
+	// keyType is the SQL type a keyset value is cast back to.
+	keyType string
+	// needsSearch marks orders that only make sense for a search.
+	needsSearch bool
+	// byVotes marks orders based on votes, which apply to replies as well.
+	byVotes bool
+	// compare orders two comments the same way expr does, ascending. It is
+	// nil for orders that cannot be computed from a loaded comment.
+	compare func(a, b domain.Comment) int
+}
+
+var sortSpecs = map[string]sortSpec{
+	"created_at": {expr: "created_at", keyType: "timestamp", compare: func(a, b domain.Comment) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}},
+	"updated_at": {expr: "updated_at", keyType: "timestamp", compare: func(a, b domain.Comment) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	}},
+	"id": {expr: "id", keyType: "integer", compare: func(a, b domain.Comment) int {
+		return cmp.Compare(a.ID, b.ID)
+	}},
+	"relevance": {expr: "ts_rank_cd(search_vector, %[1]s)", keyType: "real", needsSearch: true},
+
+	"score": {expr: "(upvotes - downvotes)", keyType: "integer", byVotes: true, compare: func(a, b domain.Comment) int {
+		return cmp.Compare(a.Score(), b.Score())
+	}},
+	// best is the lower bound of the Wilson score interval for the share of
+	// upvotes at 95% confidence, so a few votes do not outrank many.
+	"best": {expr: `(CASE WHEN upvotes + downvotes = 0 THEN 0 ELSE
+		((upvotes + 1.9208) / (upvotes + downvotes)::float8
+		 - 1.96 * SQRT((upvotes * downvotes) / (upvotes + downvotes)::float8 + 0.9604) / (upvotes + downvotes))
+		/ (1 + 3.8416 / (upvotes + downvotes)) END)`, keyType: "float8", byVotes: true, compare: compareBy(wilsonLowerBound)},
+	// hot adds the order of magnitude of the score to the creation time, so
+	// every tenfold of score is worth 12.5 hours of age.
+	"hot": {expr: `(SIGN(upvotes - downvotes) * LOG(GREATEST(ABS(upvotes - downvotes), 1))
+		+ EXTRACT(EPOCH FROM created_at)::float8 / 45000)`, keyType: "float8", byVotes: true, compare: compareBy(hotRank)},
+	// controversial favours comments with many votes split evenly.
+	"controversial": {expr: `(CASE WHEN upvotes = 0 OR downvotes = 0 THEN 0 ELSE
+		POWER((upvotes + downvotes)::float8, LEAST(upvotes, downvotes)::float8 / GREATEST(upvotes, downvotes)) END)`,
+		keyType: "float8", byVotes: true, compare: compareBy(controversy)},
+}
+
+// sortSpecFor returns the spec for sortBy with tsQuery substituted where
+// needed. Orders that need a search fall back to created_at without one.
+func sortSpecFor(sortBy, tsQuery string) sortSpec {
+	spec, ok := sortSpecs[sortBy]
+	if !ok || (spec.needsSearch && tsQuery == "") {
+		return sortSpecs["created_at"]
+	}
+	if spec.needsSearch {
+		spec.expr = fmt.Sprintf(spec.expr, tsQuery)
+	}
+	return spec
+}
+
+// childSort is the order of the replies under every comment of a tree.
+type childSort struct {
+	spec sortSpec
+	dir  string
+}
+
+// chronological keeps replies in the order they were written.
+var chronological = childSort{spec: sortSpecs["created_at"], dir: "ASC"}
+
+// childSortFor picks the order of replies. An explicit child sort wins and
+// defaults to ascending for dates and IDs and descending for votes. Without
+// one, orders based on votes apply to replies as well and any other root
+// order leaves replies in the order they were written.
+func childSortFor(sortBy, sortOrder, childSortBy, childSortOrder string) childSort {
+	if spec, ok := sortSpecs[childSortBy]; ok && spec.compare != nil {
+		if childSortOrder == "" {
+			childSortOrder = "asc"
+			if spec.byVotes {
+				childSortOrder = "desc"
+			}
+		}
+		return childSort{spec: spec, dir: sqlDirection(childSortOrder)}
+	}
+
+	if spec, ok := sortSpecs[sortBy]; ok && spec.byVotes {
+		return childSort{spec: spec, dir: sqlDirection(sortOrder)}
+	}
+
+	return chronological
+}
+
+// orderBy returns the SQL ORDER BY list for the replies of a comment.
+func (s childSort) orderBy() string {
+	return s.spec.expr + " " + s.dir + ", id " + s.dir
+}
+
+// compare orders two sibling comments the same way orderBy does, with the ID
+// breaking ties so the order is always the same.
+func (s childSort) compare(a, b domain.Comment) int {
+	c := s.spec.compare(a, b)
+	if c == 0 {
+		c = cmp.Compare(a.ID, b.ID)
+	}
+	if s.dir == "DESC" {
+		c = -c
+	}
+	return c
+}
+
+func sqlDirection(sortOrder string) string {
+	if sortOrder == "asc" {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+func compareBy(value func(c domain.Comment) float64) func(a, b domain.Comment) int {
+	return func(a, b domain.Comment) int {
+		return cmp.Compare(value(a), value(b))
+	}
+}
+
+// wilsonLowerBound, hotRank and controversy mirror the SQL of the best, hot
+// and controversial sorts.
+
+func wilsonLowerBound(c domain.Comment) float64 {
+	up, down := float64(c.Upvotes), float64(c.Downvotes)
+	n := up + down
+	if n == 0 {
+		return 0
+	}
+	return ((up+1.9208)/n - 1.96*math.Sqrt(up*down/n+0.9604)/n) / (1 + 3.8416/n)
+}
+
+func hotRank(c domain.Comment) float64 {
+	score := float64(c.Score())
+	sign := 0.0
+	switch {
+	case score > 0:
+		sign = 1
+	case score < 0:
+		sign = -1
+	}
+	return sign*math.Log10(math.Max(math.Abs(score), 1)) + float64(c.CreatedAt.UnixNano())/1e9/45000
+}
+
+func controversy(c domain.Comment) float64 {
+	if c.Upvotes == 0 || c.Downvotes == 0 {
+		return 0
+	}
+	up, down := float64(c.Upvotes), float64(c.Downvotes)
+	return math.Pow(up+down, math.Min(up, down)/math.Max(up, down))
+}
@@ -0,0 +1,442 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"comments-system/internal/domain"
+)
+
+// treeRow is a comment scanned from a comment_tree query together with the
+// columns needed to work out how many of its replies were left out.
+type treeRow struct {
+	comment  domain.Comment
+	depth    int
+	sortKey  sql.NullString
+	hasMore  bool
+	headline sql.NullString
+}
+
+// walkReplies is the recursive member of a comment_tree CTE. It loads the
+// replies visible with v of every row already in the tree in the given order,
+// taking at most $maxChildren of them per comment and stopping once $maxDepth
+// is reached. NULL parameters mean no limit. Each reply gets its position
+// among the loaded siblings as sibling_pos, the last column.
+func walkReplies(extraColumns, order string, v visibility, maxChildrenParam, maxDepthParam int) string {
+	return `
+		SELECT ` + commentColumnsOf("c") + `,
+		       ct.depth + 1` + extraColumns + `, c.sibling_pos
+		FROM comment_tree ct
+		CROSS JOIN LATERAL (
+			SELECT ` + commentColumns + `, ROW_NUMBER() OVER (ORDER BY ` + order + `) AS sibling_pos
+			FROM comments
+			WHERE parent_id = ct.id AND ` + v.on("comments") + `
+			ORDER BY ` + order + `
+			LIMIT $` + strconv.Itoa(maxChildrenParam) + `
+		) c
+		WHERE $` + strconv.Itoa(maxDepthParam) + `::int IS NULL OR ct.depth < $` + strconv.Itoa(maxDepthParam)
+}
+
+// replyCountColumn counts the direct replies of a comment_tree row that are
+// visible with v.
+func replyCountColumn(v visibility) string {
+	return `(SELECT COUNT(*) FROM comments r WHERE r.parent_id = comment_tree.id AND ` + v.on("r") + `) AS reply_count`
+}
+
+// noKeyColumns stands in for the sort_key and has_more columns in tree
+// queries that do not page over roots.
+const noKeyColumns = `NULL::text AS sort_key, FALSE AS has_more`
+
+// noHeadlineColumn stands in for the headline column when there is no search.
+const noHeadlineColumn = `NULL::text AS headline`
+
+func (r *CommentsRepository) GetTree(ctx context.Context, q domain.CommentsQuery) (domain.CommentsPage, error) {
+	var rows []treeRow
+	var page domain.CommentsPage
+	var err error
+
+	if q.ParentID != nil {
+		rows, err = r.getSubtreeRows(ctx, q)
+		// The parent itself is not one of its replies.
+		for _, row := range rows {
+			if row.comment.ID != *q.ParentID {
+				page.Total++
+			}
+		}
+	} else {
+		rows, page.Total, err = r.getThreadRows(ctx, q)
+	}
+	if err != nil {
+		return domain.CommentsPage{}, err
+	}
+
+	comments := make([]domain.Comment, len(rows))
+	for i, row := range rows {
+		comments[i] = withRemainingChildren(row, q.MaxDepth, q.MaxChildren)
+
+		if row.hasMore {
+			page.HasMore = true
+		}
+		if row.sortKey.Valid {
+			key := &domain.Keyset{Value: row.sortKey.String, ID: row.comment.ID}
+			if page.First == nil {
+				page.First = key
+			}
+			page.Last = key
+		}
+	}
+
+	page.Comments = r.buildCommentTree(comments, childSortFor(q.SortBy, q.SortOrder, q.ChildSort, q.ChildOrder), q.ParentID)
+
+	return page, nil
+}
+
+// GetReplies returns a slice of the direct replies to a comment, starting at
+// q.Offset, each with its own replies loaded within the given limits. The
+// second value is the total number of direct replies.
+func (r *CommentsRepository) GetReplies(ctx context.Context, q domain.RepliesQuery) ([]domain.Comment, int, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort := childSortFor(q.SortBy, q.SortOrder, q.ChildSort, q.ChildOrder)
+	order := sort.orderBy()
+
+	params := []interface{}{q.ParentID, limitParam(q.MaxChildren), q.Offset, limitParam(q.MaxDepth), site}
+	v := visibilityFor(ctx, &params)
+
+	query := `
+	WITH RECURSIVE replies AS (
+		SELECT ` + commentColumns + `, ROW_NUMBER() OVER (ORDER BY ` + order + `) AS sibling_pos
+		FROM comments
+		WHERE parent_id = $1 AND site_id = $5 AND ` + v.on("comments") + `
+		ORDER BY ` + order + `
+		LIMIT $2 OFFSET $3
+	),
+	comment_tree AS (
+		SELECT ` + commentColumns + `, 1 AS depth, sibling_pos
+		FROM replies
+
+		UNION ALL
+		` + walkReplies("", order, v, 2, 4) + `
+	)
+	SELECT ` + commentColumns + `, depth, ` + replyCountColumn(v) + `, ` + noKeyColumns + `, ` + noHeadlineColumn + `
+	FROM comment_tree
+	ORDER BY depth ASC, sibling_pos ASC
+	`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, params...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query replies: %w", err)
+	}
+	defer rows.Close()
+
+	treeRows, err := scanTreeRows(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	comments := make([]domain.Comment, len(treeRows))
+	for i, row := range treeRows {
+		comments[i] = withRemainingChildren(row, q.MaxDepth, q.MaxChildren)
+	}
+
+	total, _, err := r.CountReplies(ctx, q.ParentID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return r.buildCommentTree(comments, sort, nil, q.ParentID), total, nil
+}
+
+func (r *CommentsRepository) getSubtreeRows(ctx context.Context, q domain.CommentsQuery) ([]treeRow, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params := []interface{}{*q.ParentID, limitParam(q.MaxChildren), limitParam(q.MaxDepth), site}
+	v := visibilityFor(ctx, &params)
+
+	conditions := []string{"s.id = comment_tree.id"}
+	headlineColumn := noHeadlineColumn
+	if q.Search != "" {
+		tsQuery, headline := r.searchExprs(&params, q.Search)
+		conditions = append(conditions, "s.search_vector @@ "+tsQuery)
+		headlineColumn = headline + ` AS headline`
+	}
+	conditions = append(conditions, filterConditions(&params, q.Filter, "s")...)
+
+	whereClause := ""
+	if len(conditions) > 1 {
+		whereClause = `WHERE EXISTS (
+		SELECT 1 FROM comments s WHERE ` + strings.Join(conditions, " AND ") + `
+	)`
+	}
+
+	query := `
+	WITH RECURSIVE comment_tree AS (
+		SELECT ` + commentColumns + `, 0 AS depth, 1::bigint AS sibling_pos
+		FROM comments
+		WHERE id = $1 AND site_id = $4 AND ` + v.on("comments") + `
+
+		UNION ALL
+		` + walkReplies("", childSortFor(q.SortBy, q.SortOrder, q.ChildSort, q.ChildOrder).orderBy(), v, 2, 3) + `
+	)
+	SELECT ` + commentColumns + `, depth, ` + replyCountColumn(v) + `, ` + noKeyColumns + `, ` + headlineColumn + `
+	FROM comment_tree
+	` + whereClause + `
+	ORDER BY depth ASC, sibling_pos ASC
+	`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comment tree: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTreeRows(rows)
+}
+
+// getThreadRows returns a page of root comments, each followed by its
+// replies. The page is selected by offset or, when the query carries a
+// keyset, by seeking past it. The second value is the total number of
+// matching roots if the query asked for it.
+func (r *CommentsRepository) getThreadRows(ctx context.Context, q domain.CommentsQuery) ([]treeRow, int, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	params := []interface{}{site, q.ThreadKey}
+	v := visibilityFor(ctx, &params)
+	whereConditions := []string{"site_id = $1", "thread_key = $2", "parent_id IS NULL", v.on("comments")}
+
+	tsQuery := ""
+	headlineColumn := noHeadlineColumn
+	if q.Search != "" {
+		var headline string
+		tsQuery, headline = r.searchExprs(&params, q.Search)
+		whereConditions = append(whereConditions, "search_vector @@ "+tsQuery)
+		headlineColumn = headline + ` AS headline`
+	}
+	whereConditions = append(whereConditions, filterConditions(&params, q.Filter, "comments")...)
+
+	var total int
+	if q.WithTotal {
+		countQuery := `SELECT COUNT(*) FROM comments WHERE ` + strings.Join(whereConditions, " AND ")
+		row, err := r.db.QueryRowWithRetry(ctx, r.retries, countQuery, params...)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count root comments: %w", err)
+		}
+
+		err = row.Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan count: %w", err)
+		}
+	}
+
+	spec := sortSpecFor(q.SortBy, tsQuery)
+
+	ascending := q.SortOrder == "asc"
+	key := q.AfterKey
+	if q.BeforeKey != nil {
+		// Walk backwards from the cursor and restore the order at the end.
+		ascending = !ascending
+		key = q.BeforeKey
+	}
+
+	sortDir, seekOp := "DESC", "<"
+	if ascending {
+		sortDir, seekOp = "ASC", ">"
+	}
+
+	offset := (q.Page - 1) * q.PageSize
+	if key != nil {
+		whereConditions = append(whereConditions, fmt.Sprintf("(%s, id) %s ($%d::%s, $%d)",
+			spec.expr, seekOp, len(params)+1, spec.keyType, len(params)+2))
+		params = append(params, key.Value, key.ID)
+		offset = 0
+	}
+
+	rootOrder := "ASC"
+	if q.BeforeKey != nil {
+		rootOrder = "DESC"
+	}
+
+	orderBy := spec.expr + ` ` + sortDir + `, id ` + sortDir
+	whereClause := "WHERE " + strings.Join(whereConditions, " AND ")
+
+	pageSizeParamN := len(params) + 1
+	offsetParamN := len(params) + 2
+	maxChildrenParamN := len(params) + 3
+	maxDepthParamN := len(params) + 4
+
+	query := `
+	WITH RECURSIVE page AS (
+		SELECT ` + commentColumns + `, sort_key, headline,
+		       ROW_NUMBER() OVER (ORDER BY ` + orderBy + `) AS page_pos
+		FROM (
+			SELECT ` + commentColumns + `, search_vector,
+			       (` + spec.expr + `)::text AS sort_key,
+			       ` + headlineColumn + `
+			FROM comments ` + whereClause + `
+			ORDER BY ` + orderBy + `
+			LIMIT $` + strconv.Itoa(pageSizeParamN) + ` + 1 OFFSET $` + strconv.Itoa(offsetParamN) + `
+		) candidates
+	),
+	roots AS (
+		SELECT ` + commentColumns + `, sort_key, headline, page_pos AS root_pos
+		FROM page
+		WHERE page_pos <= $` + strconv.Itoa(pageSizeParamN) + `
+	),
+	comment_tree AS (
+		SELECT ` + commentColumns + `, 0 AS depth, root_pos, sort_key, headline, 1::bigint AS sibling_pos
+		FROM roots
+
+		UNION ALL
+		` + walkReplies(", ct.root_pos, NULL::text, NULL::text", childSortFor(q.SortBy, q.SortOrder, q.ChildSort, q.ChildOrder).orderBy(), v, maxChildrenParamN, maxDepthParamN) + `
+	)
+	SELECT ` + commentColumns + `, depth, ` + replyCountColumn(v) + `, sort_key,
+	       (SELECT COUNT(*) > $` + strconv.Itoa(pageSizeParamN) + ` FROM page) AS has_more,
+	       headline
+	FROM comment_tree
+	ORDER BY root_pos ` + rootOrder + `, depth ASC, sibling_pos ASC
+	`
+
+	params = append(params, q.PageSize, offset, limitParam(q.MaxChildren), limitParam(q.MaxDepth))
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, params...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query comment threads: %w", err)
+	}
+	defer rows.Close()
+
+	treeRows, err := scanTreeRows(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return treeRows, total, nil
+}
+
+func scanTreeRows(rows *sql.Rows) ([]treeRow, error) {
+	var treeRows []treeRow
+	for rows.Next() {
+		var row treeRow
+		var replyCount int
+
+		c, err := scanComment(rows, &row.depth, &replyCount, &row.sortKey, &row.hasMore, &row.headline)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment row: %w", err)
+		}
+		c.ReplyCount = replyCount
+		c.Headline = row.headline.String
+		row.comment = c
+
+		treeRows = append(treeRows, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comment tree: %w", err)
+	}
+
+	return treeRows, nil
+}
+
+// withRemainingChildren fills in how many replies of the row were not loaded
+// because of the depth and breadth limits. Zero limits mean no limit.
+func withRemainingChildren(row treeRow, maxDepth, maxChildren int) domain.Comment {
+	c := row.comment
+
+	loaded := c.ReplyCount
+	if maxDepth > 0 && row.depth >= maxDepth {
+		loaded = 0
+	} else if maxChildren > 0 && loaded > maxChildren {
+		loaded = maxChildren
+	}
+	c.RemainingChildren = c.ReplyCount - loaded
+
+	return c
+}
+
+// searchExprs appends the search language and text to params and returns the
+// tsquery expression built from them together with the expression for a
+// content snippet with the matching words highlighted.
+func (r *CommentsRepository) searchExprs(params *[]interface{}, search string) (string, string) {
+	*params = append(*params, r.searchLanguage, search)
+	language := fmt.Sprintf("$%d::regconfig", len(*params)-1)
+	tsQuery := fmt.Sprintf("websearch_to_tsquery(%s, $%d)", language, len(*params))
+	headline := `ts_headline(` + language + `, content, ` + tsQuery + `,
+		'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10')`
+	return tsQuery, headline
+}
+
+// limitParam turns a zero limit into NULL, which the queries treat as
+// "no limit".
+func limitParam(limit int) interface{} {
+	if limit <= 0 {
+		return nil
+	}
+	return limit
+}
+
+// buildCommentTree assembles rows into nested comments. Roots keep the order
+// in which they were returned, the children of every comment are sorted with
+// sort. With a rootID the tree under that comment is returned, with parentIDs
+// the replies to those comments, sorted as children too, otherwise every
+// comment without a parent is a root.
+func (r *CommentsRepository) buildCommentTree(comments []domain.Comment, sort childSort, rootID *int, parentIDs ...int) []domain.Comment {
+	isRootParent := make(map[int]bool, len(parentIDs))
+	for _, id := range parentIDs {
+		isRootParent[id] = true
+	}
+
+	childrenByParent := make(map[int][]int)
+	var rootIdx []int
+
+	for i := range comments {
+		comment := comments[i]
+
+		switch {
+		case rootID != nil && comment.ID == *rootID:
+			rootIdx = append(rootIdx, i)
+			continue
+		case len(parentIDs) > 0 && comment.ParentID != nil && isRootParent[*comment.ParentID]:
+			rootIdx = append(rootIdx, i)
+			continue
+		case rootID == nil && len(parentIDs) == 0 && comment.ParentID == nil:
+			rootIdx = append(rootIdx, i)
+			continue
+		}
+
+		if comment.ParentID != nil {
+			childrenByParent[*comment.ParentID] = append(childrenByParent[*comment.ParentID], i)
+		}
+	}
+
+	var assemble func(i int) domain.Comment
+	assemble = func(i int) domain.Comment {
+		comment := comments[i]
+		for _, childIdx := range childrenByParent[comment.ID] {
+			comment.Children = append(comment.Children, assemble(childIdx))
+		}
+		slices.SortFunc(comment.Children, sort.compare)
+		return comment
+	}
+
+	roots := make([]domain.Comment, 0, len(rootIdx))
+	for _, i := range rootIdx {
+		roots = append(roots, assemble(i))
+	}
+	if len(parentIDs) > 0 {
+		slices.SortFunc(roots, sort.compare)
+	}
+
+	return roots
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"comments-system/internal/domain"
+)
+
+// visibility selects the comments the caller of a query may see by their
+// status. Approved comments are shown to everyone and pending ones to their
+// author and moderators; a guest sees the pending comment whose management
+// token they present. Rejected comments and spam stay out of listings.
+type visibility struct {
+	// format is the SQL condition with a %[1]s verb for the table.
+	format string
+}
+
+// visibilityFor returns the visibility of the caller of ctx, appending the
+// user ID or comment token hash it needs to params.
+func visibilityFor(ctx context.Context, params *[]interface{}) visibility {
+	user, ok := domain.UserFromContext(ctx)
+	switch {
+	case ok && user.CanModerate():
+		return visibility{format: `%[1]s.status IN ('approved', 'pending')`}
+	case ok:
+		*params = append(*params, user.ID)
+		return visibility{format: fmt.Sprintf(`(%%[1]s.status = 'approved' OR (%%[1]s.status = 'pending' AND %%[1]s.user_id = $%d))`, len(*params))}
+	case domain.CommentTokenFromContext(ctx) != "":
+		*params = append(*params, domain.HashCommentToken(domain.CommentTokenFromContext(ctx)))
+		return visibility{format: fmt.Sprintf(`(%%[1]s.status = 'approved' OR (%%[1]s.status = 'pending' AND %%[1]s.manage_token_hash = $%d))`, len(*params))}
+	default:
+		return visibility{format: `%[1]s.status = 'approved'`}
+	}
+}
+
+// on returns the condition for the rows of table.
+func (v visibility) on(table string) string {
+	return fmt.Sprintf(v.format, table)
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"comments-system/internal/domain"
+)
+
+// Vote records the vote of voter on a comment and returns the comment with
+// updated totals. A value of 1 or -1 casts or changes the vote, 0 withdraws
+// it.
+func (r *CommentsRepository) Vote(ctx context.Context, id int, voter string, value int) (domain.Comment, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+
+	// The comment row is locked first so concurrent votes on it see each
+	// other's rows in comment_votes and keep the totals in sync.
+	lockQuery := `SELECT id FROM comments WHERE id = $1 AND site_id = $2 FOR UPDATE`
+
+	voteQuery := `
+	WITH old AS (
+		SELECT value FROM comment_votes WHERE comment_id = $1 AND voter = $2
+	),
+	upserted AS (
+		INSERT INTO comment_votes (comment_id, voter, value, created_at)
+		SELECT $1, $2, $3::smallint, NOW()
+		WHERE $3::smallint <> 0
+		ON CONFLICT (comment_id, voter) DO UPDATE SET value = EXCLUDED.value, created_at = EXCLUDED.created_at
+	),
+	removed AS (
+		DELETE FROM comment_votes WHERE comment_id = $1 AND voter = $2 AND $3::smallint = 0
+	)
+	UPDATE comments SET
+		upvotes = upvotes + (CASE WHEN $3::smallint = 1 THEN 1 ELSE 0 END) - (SELECT COUNT(*) FROM old WHERE value = 1),
+		downvotes = downvotes + (CASE WHEN $3::smallint = -1 THEN 1 ELSE 0 END) - (SELECT COUNT(*) FROM old WHERE value = -1)
+	WHERE id = $1
+	RETURNING ` + commentColumns
+
+	var c domain.Comment
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		var lockedID int
+		err := tx.QueryRowContext(ctx, lockQuery, id, site).Scan(&lockedID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("comment not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock comment: %w", err)
+		}
+
+		c, err = scanComment(tx.QueryRowContext(ctx, voteQuery, id, voter, value))
+		if err != nil {
+			return fmt.Errorf("failed to scan voted comment: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return domain.Comment{}, fmt.Errorf("failed to vote on comment: %w", err)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+// ClaimEmails takes up to limit users with a confirmed address and unread
+// notifications not emailed yet whose next email is due: with the instant
+// mode once instantEvery has passed since their last email, with the digest
+// mode once digestEvery has, and after a failure once its retry is due. The
+// claim counts as their last email, so other senders skip them. The
+// notifications are returned oldest first.
+func (r *NotificationsRepository) ClaimEmails(ctx context.Context, limit int, instantEvery, digestEvery time.Duration) ([]domain.NotificationEmail, error) {
+	claimQuery := `UPDATE notification_preferences np SET last_emailed_at = NOW()
+	               FROM users u JOIN sites s ON s.id = u.site_id
+	               WHERE u.id = np.user_id AND np.user_id IN (
+	                   SELECT p.user_id FROM notification_preferences p
+	                   WHERE p.email_mode <> 'off' AND p.email <> '' AND p.email_confirmed
+	                     AND (p.last_emailed_at IS NULL OR p.last_emailed_at <= NOW() - make_interval(secs =>
+	                         CASE p.email_mode WHEN 'digest' THEN $3::float8 ELSE $2::float8 END))
+	                     AND (p.email_retry_at IS NULL OR p.email_retry_at <= NOW())
+	                     AND EXISTS (
+	                         SELECT 1 ` + visibleNotifications + `
+	                           AND n.user_id = p.user_id AND n.read_at IS NULL AND n.emailed_at IS NULL
+	                     )
+	                   ORDER BY p.last_emailed_at NULLS FIRST
+	                   LIMIT $1
+	                   FOR UPDATE OF p SKIP LOCKED
+	               )
+	               RETURNING np.user_id, np.email, u.username, s.name`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, claimQuery, limit, instantEvery.Seconds(), digestEvery.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notification emails: %w", err)
+	}
+	defer rows.Close()
+
+	var emails []domain.NotificationEmail
+	byUser := make(map[int]int)
+	var userIDs []int64
+	for rows.Next() {
+		var e domain.NotificationEmail
+		if err := rows.Scan(&e.UserID, &e.Email, &e.Username, &e.SiteName); err != nil {
+			return nil, fmt.Errorf("failed to scan notification email: %w", err)
+		}
+
+		byUser[e.UserID] = len(emails)
+		userIDs = append(userIDs, int64(e.UserID))
+		emails = append(emails, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification emails: %w", err)
+	}
+	if len(emails) == 0 {
+		return nil, nil
+	}
+
+	query := `SELECT ` + notificationColumns + ` ` + visibleNotifications + `
+	            AND n.user_id = ANY($1) AND n.read_at IS NULL AND n.emailed_at IS NULL
+	          ORDER BY n.user_id, n.id`
+
+	nrows, err := r.db.QueryWithRetry(ctx, r.retries, query, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications to email: %w", err)
+	}
+	defer nrows.Close()
+
+	notifications, err := scanNotifications(nrows)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range notifications {
+		e := &emails[byUser[n.UserID]]
+		e.Notifications = append(e.Notifications, n)
+	}
+
+	return emails, nil
+}
+
+// MarkEmailed records that the given notifications were emailed to a user,
+// which ends the failures of their email.
+func (r *NotificationsRepository) MarkEmailed(ctx context.Context, userID int, ids []int64) error {
+	query := `WITH emailed AS (
+	              UPDATE notifications SET emailed_at = NOW() WHERE id = ANY($2)
+	          )
+	          UPDATE notification_preferences SET email_failures = 0, email_retry_at = NULL
+	          WHERE user_id = $1 AND email_failures > 0`
+
+	_, err := r.db.ExecWithRetry(ctx, r.retries, query, userID, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to mark notifications emailed: %w", err)
+	}
+
+	return nil
+}
+
+// FailEmail records that sending the email of a user failed. The email is
+// due again after retryDelay, doubled for every failure in a row before;
+// after maxFailures in a row email is turned off for the user instead, and
+// the returned value is true.
+func (r *NotificationsRepository) FailEmail(ctx context.Context, userID int, retryDelay time.Duration, maxFailures int) (bool, error) {
+	query := `UPDATE notification_preferences SET
+	              email_failures = email_failures + 1,
+	              last_emailed_at = NULL,
+	              email_retry_at = NOW() + make_interval(secs => $2::float8 * power(2, email_failures)),
+	              email_mode = CASE WHEN email_failures + 1 >= $3 THEN 'off' ELSE email_mode END
+	          WHERE user_id = $1
+	          RETURNING email_mode = 'off'`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, userID, retryDelay.Seconds(), maxFailures)
+	if err != nil {
+		return false, fmt.Errorf("failed to record notification email failure: %w", err)
+	}
+
+	var disabled bool
+	if err := row.Scan(&disabled); err != nil && err != sql.ErrNoRows {
+		return false, fmt.Errorf("failed to scan notification email failure: %w", err)
+	}
+
+	return disabled, nil
+}
@@ -0,0 +1,294 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
+)
+
+const preferencesColumns = `user_id, replies, mentions, email, email_confirmed, email_token_hash, email_token_sent_at, email_mode, updated_at`
+
+const notificationColumns = `n.id, n.user_id, n.kind, n.read_at, n.created_at,
+	c.id, c.parent_id, c.root_id, c.thread_key, c.content, c.author, c.user_id, c.status, c.created_at, c.updated_at`
+
+// visibleNotifications joins the notifications to their comments, leaving
+// out those of comments that were deleted or hidden since.
+const visibleNotifications = `FROM notifications n JOIN comments c ON c.id = n.comment_id
+	          WHERE c.status = 'approved' AND c.deleted_at IS NULL`
+
+type NotificationsRepository struct {
+	db      *dbpg.DB
+	retries retry.Strategy
+}
+
+func NewNotificationsRepository(db *dbpg.DB, retries retry.Strategy) *NotificationsRepository {
+	return &NotificationsRepository{
+		db:      db,
+		retries: retries,
+	}
+}
+
+// CreateForComment notifies the registered author of the parent comment
+// about a reply and the users of the site named in mentions, lowercased,
+// about the mention, unless they turned that kind off. The author of the
+// comment is never notified and a user is notified about a comment only
+// once, so calling it again for the comment adds nothing. The site is given
+// explicitly since notifications are created outside of requests. It
+// returns how many notifications were created.
+func (r *NotificationsRepository) CreateForComment(ctx context.Context, siteID int, comment domain.Comment, mentions []string) (int, error) {
+	query := `INSERT INTO notifications (site_id, user_id, kind, comment_id, created_at)
+	          SELECT DISTINCT ON (rcpt.user_id) $1, rcpt.user_id, rcpt.kind, $2, NOW()
+	          FROM (
+	              SELECT p.user_id, 'reply' AS kind, 1 AS rank FROM comments p
+	              WHERE p.id = $3 AND p.site_id = $1 AND p.user_id IS NOT NULL
+	              UNION ALL
+	              SELECT u.id, 'mention', 2 FROM users u
+	              WHERE u.site_id = $1 AND LOWER(u.username) = ANY($4)
+	          ) rcpt
+	          LEFT JOIN notification_preferences np ON np.user_id = rcpt.user_id
+	          WHERE rcpt.user_id IS DISTINCT FROM $5::integer
+	            AND CASE rcpt.kind WHEN 'reply' THEN COALESCE(np.replies, TRUE) ELSE COALESCE(np.mentions, TRUE) END
+	            AND EXISTS (SELECT 1 FROM comments c WHERE c.id = $2 AND c.status = 'approved' AND c.deleted_at IS NULL)
+	          ORDER BY rcpt.user_id, rcpt.rank
+	          ON CONFLICT (user_id, comment_id) DO NOTHING`
+
+	res, err := r.db.ExecWithRetry(ctx, r.retries, query, siteID, comment.ID, comment.ParentID, pq.Array(mentions), comment.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create notifications: %w", err)
+	}
+
+	created, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return int(created), nil
+}
+
+// List returns a page of the notifications of a user, newest first, with
+// how many match and how many are unread in all.
+func (r *NotificationsRepository) List(ctx context.Context, userID int, unreadOnly bool, limit, offset int) ([]domain.Notification, int, int, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	where := visibleNotifications + ` AND n.site_id = $1 AND n.user_id = $2`
+
+	countQuery := `SELECT COUNT(*) FILTER (WHERE NOT $3 OR n.read_at IS NULL), COUNT(*) FILTER (WHERE n.read_at IS NULL) ` + where
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, countQuery, site, userID, unreadOnly)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	var total, unread int
+	err = row.Scan(&total, &unread)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to scan count: %w", err)
+	}
+
+	query := `SELECT ` + notificationColumns + ` ` + where + ` AND (NOT $3 OR n.read_at IS NULL)
+	          ORDER BY n.id DESC
+	          LIMIT $4 OFFSET $5`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, site, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications, err := scanNotifications(rows)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	return notifications, total, unread, nil
+}
+
+// MarkRead marks the given notifications of a user read, or all of them
+// when all is set, and returns how many were unread.
+func (r *NotificationsRepository) MarkRead(ctx context.Context, userID int, ids []int64, all bool) (int, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `UPDATE notifications SET read_at = NOW()
+	          WHERE site_id = $1 AND user_id = $2 AND read_at IS NULL AND ($3 OR id = ANY($4))`
+
+	res, err := r.db.ExecWithRetry(ctx, r.retries, query, site, userID, all, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return int(updated), nil
+}
+
+// GetPreferences returns the notification preferences of a user. The second
+// value is false if the user never changed them.
+func (r *NotificationsRepository) GetPreferences(ctx context.Context, userID int) (domain.NotificationPreferences, bool, error) {
+	query := `SELECT ` + preferencesColumns + ` FROM notification_preferences WHERE user_id = $1`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, userID)
+	if err != nil {
+		return domain.NotificationPreferences{}, false, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+
+	prefs, err := scanPreferences(row)
+	if err == sql.ErrNoRows {
+		return domain.NotificationPreferences{}, false, nil
+	}
+	if err != nil {
+		return domain.NotificationPreferences{}, false, fmt.Errorf("failed to scan notification preferences: %w", err)
+	}
+
+	return prefs, true, nil
+}
+
+// SavePreferences stores the notification preferences of a user.
+func (r *NotificationsRepository) SavePreferences(ctx context.Context, prefs domain.NotificationPreferences) (domain.NotificationPreferences, error) {
+	query := `INSERT INTO notification_preferences (user_id, replies, mentions, email, email_confirmed,
+	              email_token_hash, email_token_sent_at, email_mode, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, NOW())
+	          ON CONFLICT (user_id) DO UPDATE SET
+	              replies = EXCLUDED.replies,
+	              mentions = EXCLUDED.mentions,
+	              email = EXCLUDED.email,
+	              email_confirmed = EXCLUDED.email_confirmed,
+	              email_token_hash = EXCLUDED.email_token_hash,
+	              email_token_sent_at = EXCLUDED.email_token_sent_at,
+	              email_mode = EXCLUDED.email_mode,
+	              email_failures = 0,
+	              email_retry_at = NULL,
+	              updated_at = EXCLUDED.updated_at
+	          RETURNING ` + preferencesColumns
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query,
+		prefs.UserID, prefs.Replies, prefs.Mentions, prefs.Email, prefs.EmailConfirmed,
+		prefs.EmailTokenHash, prefs.EmailTokenSentAt, string(prefs.EmailMode))
+	if err != nil {
+		return domain.NotificationPreferences{}, fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+
+	saved, err := scanPreferences(row)
+	if err != nil {
+		return domain.NotificationPreferences{}, fmt.Errorf("failed to scan notification preferences: %w", err)
+	}
+
+	return saved, nil
+}
+
+// ConfirmEmail marks the email address of a user confirmed if tokenHash is
+// the hash of the last token sent to it, no more than validFor ago. The
+// second value is false otherwise.
+func (r *NotificationsRepository) ConfirmEmail(ctx context.Context, userID int, tokenHash string, validFor time.Duration) (domain.NotificationPreferences, bool, error) {
+	query := `UPDATE notification_preferences
+	          SET email_confirmed = TRUE, email_token_hash = NULL, updated_at = NOW()
+	          WHERE user_id = $1 AND email_token_hash = $2
+	            AND email_token_sent_at > NOW() - make_interval(secs => $3::float8)
+	          RETURNING ` + preferencesColumns
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, userID, tokenHash, validFor.Seconds())
+	if err != nil {
+		return domain.NotificationPreferences{}, false, fmt.Errorf("failed to confirm email: %w", err)
+	}
+
+	prefs, err := scanPreferences(row)
+	if err == sql.ErrNoRows {
+		return domain.NotificationPreferences{}, false, nil
+	}
+	if err != nil {
+		return domain.NotificationPreferences{}, false, fmt.Errorf("failed to scan notification preferences: %w", err)
+	}
+
+	return prefs, true, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanNotifications(rows *sql.Rows) ([]domain.Notification, error) {
+	notifications := []domain.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+func scanNotification(row rowScanner) (domain.Notification, error) {
+	var n domain.Notification
+	var kind, status string
+	var readAt sql.NullTime
+	var parentID, rootID, userID sql.NullInt32
+
+	err := row.Scan(&n.ID, &n.UserID, &kind, &readAt, &n.CreatedAt,
+		&n.Comment.ID, &parentID, &rootID, &n.Comment.ThreadKey, &n.Comment.Content, &n.Comment.Author,
+		&userID, &status, &n.Comment.CreatedAt, &n.Comment.UpdatedAt)
+	if err != nil {
+		return domain.Notification{}, err
+	}
+
+	n.Kind = domain.NotificationKind(kind)
+	if readAt.Valid {
+		n.ReadAt = &readAt.Time
+	}
+	n.Comment.Status = domain.CommentStatus(status)
+	if parentID.Valid {
+		pid := int(parentID.Int32)
+		n.Comment.ParentID = &pid
+	}
+	n.Comment.RootID = n.Comment.ID
+	if rootID.Valid {
+		n.Comment.RootID = int(rootID.Int32)
+	}
+	if userID.Valid {
+		uid := int(userID.Int32)
+		n.Comment.UserID = &uid
+	}
+
+	return n, nil
+}
+
+func scanPreferences(row rowScanner) (domain.NotificationPreferences, error) {
+	var prefs domain.NotificationPreferences
+	var mode string
+	var tokenHash sql.NullString
+	var tokenSentAt sql.NullTime
+
+	err := row.Scan(&prefs.UserID, &prefs.Replies, &prefs.Mentions, &prefs.Email, &prefs.EmailConfirmed,
+		&tokenHash, &tokenSentAt, &mode, &prefs.UpdatedAt)
+	if err != nil {
+		return domain.NotificationPreferences{}, err
+	}
+
+	prefs.EmailMode = domain.EmailMode(mode)
+	prefs.EmailTokenHash = tokenHash.String
+	if tokenSentAt.Valid {
+		prefs.EmailTokenSentAt = &tokenSentAt.Time
+	}
+	return prefs, nil
+}
@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
+)
+
+// OutboxRepository reads the comment events that the comments repository
+// records along with the changes.
+type OutboxRepository struct {
+	db      *dbpg.DB
+	retries retry.Strategy
+}
+
+func NewOutboxRepository(db *dbpg.DB, retries retry.Strategy) *OutboxRepository {
+	return &OutboxRepository{
+		db:      db,
+		retries: retries,
+	}
+}
+
+// PublishPending passes up to limit unpublished events, oldest first, to
+// publish, which returns how many of them it published before failing. The
+// published events are marked published and the failure is recorded on the
+// event that failed. Only one relay publishes at a time: while another one
+// does, PublishPending returns 0 without taking any events, so relays of
+// several instances neither publish the same events nor interleave them. It
+// returns how many events were published.
+func (r *OutboxRepository) PublishPending(ctx context.Context, limit int, publish func(events []domain.CommentEvent) (int, error)) (int, error) {
+	tx, err := r.db.Master.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	query := `SELECT pg_try_advisory_xact_lock(hashtext('outbox_relay'))`
+	if err := tx.QueryRowContext(ctx, query).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	events, err := takePending(ctx, tx, limit)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	published, publishErr := publish(events)
+
+	if published > 0 {
+		ids := make([]int64, published)
+		for i, event := range events[:published] {
+			ids[i] = int64(event.ID)
+		}
+
+		query = `UPDATE outbox SET published_at = NOW(), attempts = attempts + 1 WHERE id = ANY($1)`
+		if _, err := tx.ExecContext(ctx, query, pq.Array(ids)); err != nil {
+			return 0, fmt.Errorf("failed to mark events published: %w", err)
+		}
+	}
+
+	if publishErr != nil && published < len(events) {
+		query = `UPDATE outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, int64(events[published].ID), publishErr.Error()); err != nil {
+			return 0, fmt.Errorf("failed to record event failure: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return published, publishErr
+}
+
+// DeletePublished removes the events published before olderThan and returns
+// how many were removed.
+func (r *OutboxRepository) DeletePublished(ctx context.Context, olderThan time.Time) (int, error) {
+	query := `DELETE FROM outbox WHERE published_at < $1`
+
+	res, err := r.db.ExecWithRetry(ctx, r.retries, query, olderThan)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published events: %w", err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return int(deleted), nil
+}
+
+// Head returns the position after the events of the transactions that have
+// ended, so following from it passes on only the events stored from now on.
+func (r *OutboxRepository) Head(ctx context.Context) (domain.OutboxPosition, error) {
+	query := `SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query)
+	if err != nil {
+		return domain.OutboxPosition{}, fmt.Errorf("failed to get outbox head: %w", err)
+	}
+
+	var pos domain.OutboxPosition
+	if err := row.Scan(&pos.TxID); err != nil {
+		return domain.OutboxPosition{}, fmt.Errorf("failed to scan outbox head: %w", err)
+	}
+
+	return pos, nil
+}
+
+// ListAfter returns up to limit events stored after pos, whether published
+// or not, and the position after them. Only events of transactions older
+// than every transaction still in progress are returned: no event can show
+// up before them later, so none is passed over.
+func (r *OutboxRepository) ListAfter(ctx context.Context, pos domain.OutboxPosition, limit int) ([]domain.CommentEvent, domain.OutboxPosition, error) {
+	query := `SELECT ` + eventColumns + ` FROM outbox
+	          WHERE (tx_id, id) > ($1, $2)
+	            AND tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+	          ORDER BY tx_id, id
+	          LIMIT $3`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, pos.TxID, pos.ID, limit)
+	if err != nil {
+		return nil, pos, fmt.Errorf("failed to get events: %w", err)
+	}
+	defer rows.Close()
+
+	events, last, err := scanEvents(rows)
+	if err != nil {
+		return nil, pos, err
+	}
+	if len(events) == 0 {
+		return nil, pos, nil
+	}
+
+	return events, last, nil
+}
+
+const eventColumns = `id, tx_id, site_id, event_type, payload, created_at`
+
+// takePending locks up to limit unpublished events for the transaction. Like
+// ListAfter, it takes only events of transactions older than every one still
+// in progress, in the order of their transactions, so an event committed late
+// is not passed over by later ones.
+func takePending(ctx context.Context, tx *sql.Tx, limit int) ([]domain.CommentEvent, error) {
+	query := `SELECT ` + eventColumns + ` FROM outbox
+	          WHERE published_at IS NULL
+	            AND tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+	          ORDER BY tx_id, id
+	          LIMIT $1
+	          FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending events: %w", err)
+	}
+	defer rows.Close()
+
+	events, _, err := scanEvents(rows)
+	return events, err
+}
+
+// scanEvents reads events and the position of the last of them. The ID of an
+// outbox row is the key of its event.
+func scanEvents(rows *sql.Rows) ([]domain.CommentEvent, domain.OutboxPosition, error) {
+	var events []domain.CommentEvent
+	var last domain.OutboxPosition
+	for rows.Next() {
+		var (
+			event     domain.CommentEvent
+			eventType string
+			payload   []byte
+		)
+		if err := rows.Scan(&last.ID, &last.TxID, &event.SiteID, &eventType, &payload, &event.OccurredAt); err != nil {
+			return nil, last, fmt.Errorf("failed to scan event row: %w", err)
+		}
+		if err := json.Unmarshal(payload, &event.Comment); err != nil {
+			return nil, last, fmt.Errorf("failed to decode event %d: %w", last.ID, err)
+		}
+
+		event.ID = uint64(last.ID)
+		event.Key = strconv.FormatInt(last.ID, 10)
+		event.Type = domain.EventType(eventType)
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, last, fmt.Errorf("error iterating events: %w", err)
+	}
+
+	return events, last, nil
+}
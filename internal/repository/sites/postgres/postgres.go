@@ -0,0 +1,214 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"comments-system/internal/domain"
+
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
+)
+
+const siteColumns = `id, name, allowed_origins, max_content_length, max_author_length, moderation_mode, created_at, updated_at`
+
+type SitesRepository struct {
+	db      *dbpg.DB
+	retries retry.Strategy
+}
+
+func NewSitesRepository(db *dbpg.DB, retries retry.Strategy) *SitesRepository {
+	return &SitesRepository{
+		db:      db,
+		retries: retries,
+	}
+}
+
+// Create stores a new site together with the hash of its API key.
+func (r *SitesRepository) Create(ctx context.Context, site domain.Site, apiKeyHash string) (domain.Site, error) {
+	query := `INSERT INTO sites (name, api_key_hash, allowed_origins, max_content_length, max_author_length, moderation_mode, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+	          RETURNING ` + siteColumns
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query,
+		site.Name, apiKeyHash, pq.Array(site.AllowedOrigins), site.MaxContentLength, site.MaxAuthorLength, string(site.ModerationMode))
+	if err != nil {
+		return domain.Site{}, fmt.Errorf("failed to create site: %w", err)
+	}
+
+	created, err := scanSite(row)
+	if err != nil {
+		return domain.Site{}, fmt.Errorf("failed to scan created site: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *SitesRepository) Exists(ctx context.Context, id int) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM sites WHERE id = $1)`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to check site existence: %w", err)
+	}
+
+	var exists bool
+	err = row.Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to scan existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+func (r *SitesRepository) GetByID(ctx context.Context, id int) (domain.Site, error) {
+	query := `SELECT ` + siteColumns + ` FROM sites WHERE id = $1`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, id)
+	if err != nil {
+		return domain.Site{}, fmt.Errorf("failed to query site: %w", err)
+	}
+
+	site, err := scanSite(row)
+	if err == sql.ErrNoRows {
+		return domain.Site{}, fmt.Errorf("site not found")
+	}
+	if err != nil {
+		return domain.Site{}, fmt.Errorf("failed to scan site: %w", err)
+	}
+
+	return site, nil
+}
+
+// GetByAPIKeyHash returns the site whose API key has the given hash. The
+// second value is false if there is no such site.
+func (r *SitesRepository) GetByAPIKeyHash(ctx context.Context, apiKeyHash string) (domain.Site, bool, error) {
+	query := `SELECT ` + siteColumns + ` FROM sites WHERE api_key_hash = $1`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, apiKeyHash)
+	if err != nil {
+		return domain.Site{}, false, fmt.Errorf("failed to query site: %w", err)
+	}
+
+	site, err := scanSite(row)
+	if err == sql.ErrNoRows {
+		return domain.Site{}, false, nil
+	}
+	if err != nil {
+		return domain.Site{}, false, fmt.Errorf("failed to scan site: %w", err)
+	}
+
+	return site, true, nil
+}
+
+func (r *SitesRepository) List(ctx context.Context) ([]domain.Site, error) {
+	query := `SELECT ` + siteColumns + ` FROM sites ORDER BY id`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sites: %w", err)
+	}
+	defer rows.Close()
+
+	sites := []domain.Site{}
+	for rows.Next() {
+		site, err := scanSite(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan site: %w", err)
+		}
+
+		sites = append(sites, site)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sites: %w", err)
+	}
+
+	return sites, nil
+}
+
+// Update changes the settings set in upd and keeps the others.
+func (r *SitesRepository) Update(ctx context.Context, id int, upd domain.SiteUpdate) (domain.Site, error) {
+	var origins interface{}
+	if upd.AllowedOrigins != nil {
+		origins = pq.Array(*upd.AllowedOrigins)
+	}
+	var moderationMode *string
+	if upd.ModerationMode != nil {
+		mode := string(*upd.ModerationMode)
+		moderationMode = &mode
+	}
+
+	query := `UPDATE sites SET
+	              name = COALESCE($2, name),
+	              allowed_origins = COALESCE($3::text[], allowed_origins),
+	              max_content_length = COALESCE($4, max_content_length),
+	              max_author_length = COALESCE($5, max_author_length),
+	              moderation_mode = COALESCE($6, moderation_mode),
+	              updated_at = NOW()
+	          WHERE id = $1
+	          RETURNING ` + siteColumns
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query,
+		id, upd.Name, origins, upd.MaxContentLength, upd.MaxAuthorLength, moderationMode)
+	if err != nil {
+		return domain.Site{}, fmt.Errorf("failed to update site: %w", err)
+	}
+
+	site, err := scanSite(row)
+	if err == sql.ErrNoRows {
+		return domain.Site{}, fmt.Errorf("site not found")
+	}
+	if err != nil {
+		return domain.Site{}, fmt.Errorf("failed to scan updated site: %w", err)
+	}
+
+	return site, nil
+}
+
+// SetAPIKeyHash replaces the API key of a site; the old key stops working.
+func (r *SitesRepository) SetAPIKeyHash(ctx context.Context, id int, apiKeyHash string) error {
+	query := `UPDATE sites SET api_key_hash = $2, updated_at = NOW() WHERE id = $1`
+
+	_, err := r.db.ExecWithRetry(ctx, r.retries, query, id, apiKeyHash)
+	if err != nil {
+		return fmt.Errorf("failed to set site API key: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a site with all its threads and comments.
+func (r *SitesRepository) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM sites WHERE id = $1`
+
+	_, err := r.db.ExecWithRetry(ctx, r.retries, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete site: %w", err)
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSite(row rowScanner) (domain.Site, error) {
+	var site domain.Site
+	var origins []string
+	var moderationMode string
+
+	err := row.Scan(&site.ID, &site.Name, pq.Array(&origins), &site.MaxContentLength, &site.MaxAuthorLength,
+		&moderationMode, &site.CreatedAt, &site.UpdatedAt)
+	if err != nil {
+		return domain.Site{}, err
+	}
+
+	site.AllowedOrigins = origins
+	site.ModerationMode = domain.ModerationMode(moderationMode)
+
+	return site, nil
+}
@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"comments-system/internal/domain"
+
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
+)
+
+// SpamRepository stores the spam model of each site.
+type SpamRepository struct {
+	db      *dbpg.DB
+	retries retry.Strategy
+}
+
+func NewSpamRepository(db *dbpg.DB, retries retry.Strategy) *SpamRepository {
+	return &SpamRepository{
+		db:      db,
+		retries: retries,
+	}
+}
+
+// Counts returns the counts of the given tokens that have been seen in
+// training and the number of spam and ham comments trained on.
+func (r *SpamRepository) Counts(ctx context.Context, tokens []string) (map[string]domain.SpamTokenCount, domain.SpamTokenCount, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, domain.SpamTokenCount{}, err
+	}
+
+	var docs domain.SpamTokenCount
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, `SELECT spam, ham FROM spam_docs WHERE site_id = $1`, site)
+	if err != nil {
+		return nil, domain.SpamTokenCount{}, fmt.Errorf("failed to query spam model size: %w", err)
+	}
+
+	err = row.Scan(&docs.Spam, &docs.Ham)
+	if err == sql.ErrNoRows {
+		return map[string]domain.SpamTokenCount{}, domain.SpamTokenCount{}, nil
+	}
+	if err != nil {
+		return nil, domain.SpamTokenCount{}, fmt.Errorf("failed to scan spam model size: %w", err)
+	}
+
+	query := `SELECT token, spam, ham FROM spam_tokens WHERE site_id = $1 AND token = ANY($2)`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, site, pq.Array(tokens))
+	if err != nil {
+		return nil, domain.SpamTokenCount{}, fmt.Errorf("failed to query spam tokens: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]domain.SpamTokenCount)
+	for rows.Next() {
+		var token string
+		var count domain.SpamTokenCount
+		if err := rows.Scan(&token, &count.Spam, &count.Ham); err != nil {
+			return nil, domain.SpamTokenCount{}, fmt.Errorf("failed to scan spam token: %w", err)
+		}
+		counts[token] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, domain.SpamTokenCount{}, fmt.Errorf("error iterating spam tokens: %w", err)
+	}
+
+	return counts, docs, nil
+}
+
+// Train adds a comment to the model as spam or ham. A comment trained
+// before with the other label is moved to the new one; training it again
+// with the same label changes nothing.
+func (r *SpamRepository) Train(ctx context.Context, commentID int, tokens []string, isSpam bool) error {
+	site, err := siteID(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The earlier training row is locked so concurrent decisions on the
+	// same comment are applied one after another.
+	previousQuery := `SELECT is_spam, tokens FROM spam_training WHERE comment_id = $1 AND site_id = $2 FOR UPDATE`
+
+	tokensQuery := `INSERT INTO spam_tokens (site_id, token, spam, ham)
+	                SELECT $1, t, $3, $4 FROM unnest($2::text[]) AS t
+	                ON CONFLICT (site_id, token) DO UPDATE SET
+	                    spam = spam_tokens.spam + EXCLUDED.spam,
+	                    ham = spam_tokens.ham + EXCLUDED.ham`
+
+	docsQuery := `INSERT INTO spam_docs (site_id, spam, ham) VALUES ($1, $2, $3)
+	              ON CONFLICT (site_id) DO UPDATE SET
+	                  spam = spam_docs.spam + EXCLUDED.spam,
+	                  ham = spam_docs.ham + EXCLUDED.ham`
+
+	trainingQuery := `INSERT INTO spam_training (comment_id, site_id, is_spam, tokens, trained_at)
+	                  VALUES ($1, $2, $3, $4, NOW())
+	                  ON CONFLICT (comment_id) DO UPDATE SET
+	                      is_spam = EXCLUDED.is_spam,
+	                      tokens = EXCLUDED.tokens,
+	                      trained_at = EXCLUDED.trained_at`
+
+	// add counts tokens once more (delta 1) or once less (delta -1) in
+	// the class.
+	add := func(tx *sql.Tx, tokens []string, isSpam bool, delta int) error {
+		spam, ham := 0, delta
+		if isSpam {
+			spam, ham = delta, 0
+		}
+
+		if _, err := tx.ExecContext(ctx, tokensQuery, site, pq.Array(tokens), spam, ham); err != nil {
+			return fmt.Errorf("failed to update spam tokens: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, docsQuery, site, spam, ham); err != nil {
+			return fmt.Errorf("failed to update spam model size: %w", err)
+		}
+		return nil
+	}
+
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		var wasSpam bool
+		var previous []string
+		err := tx.QueryRowContext(ctx, previousQuery, commentID, site).Scan(&wasSpam, pq.Array(&previous))
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return fmt.Errorf("failed to query previous training: %w", err)
+		case wasSpam == isSpam:
+			return nil
+		default:
+			if err := add(tx, previous, wasSpam, -1); err != nil {
+				return err
+			}
+		}
+
+		if err := add(tx, tokens, isSpam, 1); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, trainingQuery, commentID, site, isSpam, pq.Array(tokens)); err != nil {
+			return fmt.Errorf("failed to record training: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to train spam model: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/wb-go/wbf/retry"
+)
+
+// inTx runs fn in a transaction on the master database and commits it if fn
+// succeeds. The whole transaction is retried with the repository strategy.
+func (r *SpamRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return retry.DoContext(ctx, r.retries, func() error {
+		tx, err := r.db.Master.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(tx); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		return tx.Commit()
+	})
+}
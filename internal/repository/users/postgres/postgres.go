@@ -0,0 +1,248 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
+)
+
+const userColumns = `id, site_id, username, role, created_at`
+
+type UsersRepository struct {
+	db      *dbpg.DB
+	retries retry.Strategy
+}
+
+func NewUsersRepository(db *dbpg.DB, retries retry.Strategy) *UsersRepository {
+	return &UsersRepository{
+		db:      db,
+		retries: retries,
+	}
+}
+
+// Create registers a user. The second value is false if the username is
+// already taken on the site.
+func (r *UsersRepository) Create(ctx context.Context, username, passwordHash string) (domain.User, bool, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.User{}, false, err
+	}
+
+	query := `INSERT INTO users (site_id, username, password_hash, created_at)
+	          VALUES ($1, $2, $3, NOW())
+	          ON CONFLICT (site_id, LOWER(username)) DO NOTHING
+	          RETURNING ` + userColumns
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, site, username, passwordHash)
+	if err != nil {
+		return domain.User{}, false, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return domain.User{}, false, nil
+	}
+	if err != nil {
+		return domain.User{}, false, fmt.Errorf("failed to scan created user: %w", err)
+	}
+
+	return user, true, nil
+}
+
+// GetByID returns a user of the site. The second value is false if there is
+// no such user.
+func (r *UsersRepository) GetByID(ctx context.Context, id int) (domain.User, bool, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.User{}, false, err
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND site_id = $2`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, id, site)
+	if err != nil {
+		return domain.User{}, false, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return domain.User{}, false, nil
+	}
+	if err != nil {
+		return domain.User{}, false, fmt.Errorf("failed to scan user: %w", err)
+	}
+
+	return user, true, nil
+}
+
+// GetCredentials returns a user of the site by username, ignoring case,
+// together with the hash of their password.
+func (r *UsersRepository) GetCredentials(ctx context.Context, username string) (domain.User, string, bool, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.User{}, "", false, err
+	}
+
+	query := `SELECT ` + userColumns + `, password_hash FROM users WHERE site_id = $1 AND LOWER(username) = LOWER($2)`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, site, username)
+	if err != nil {
+		return domain.User{}, "", false, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	var passwordHash string
+	user, err := scanUser(row, &passwordHash)
+	if err == sql.ErrNoRows {
+		return domain.User{}, "", false, nil
+	}
+	if err != nil {
+		return domain.User{}, "", false, fmt.Errorf("failed to scan user: %w", err)
+	}
+
+	return user, passwordHash, true, nil
+}
+
+// CreateSession stores a session of a user under the hash of its token and
+// drops the expired sessions of the user.
+func (r *UsersRepository) CreateSession(ctx context.Context, tokenHash string, userID int, expiresAt time.Time) error {
+	query := `
+	WITH expired AS (
+		DELETE FROM sessions WHERE user_id = $2 AND expires_at <= NOW()
+	)
+	INSERT INTO sessions (token_hash, user_id, created_at, expires_at)
+	VALUES ($1, $2, NOW(), $3)
+	`
+
+	_, err := r.db.ExecWithRetry(ctx, r.retries, query, tokenHash, userID, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return nil
+}
+
+// GetSessionUser returns the user of an unexpired session of the site. The
+// second value is false if there is no such session.
+func (r *UsersRepository) GetSessionUser(ctx context.Context, tokenHash string) (domain.User, bool, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.User{}, false, err
+	}
+
+	query := `SELECT u.id, u.site_id, u.username, u.role, u.created_at
+	          FROM sessions s
+	          INNER JOIN users u ON u.id = s.user_id
+	          WHERE s.token_hash = $1 AND s.expires_at > NOW() AND u.site_id = $2`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, tokenHash, site)
+	if err != nil {
+		return domain.User{}, false, fmt.Errorf("failed to query session: %w", err)
+	}
+
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return domain.User{}, false, nil
+	}
+	if err != nil {
+		return domain.User{}, false, fmt.Errorf("failed to scan session user: %w", err)
+	}
+
+	return user, true, nil
+}
+
+func (r *UsersRepository) DeleteSession(ctx context.Context, tokenHash string) error {
+	query := `DELETE FROM sessions WHERE token_hash = $1`
+
+	_, err := r.db.ExecWithRetry(ctx, r.retries, query, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the users of the site, oldest first.
+func (r *UsersRepository) List(ctx context.Context) ([]domain.User, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE site_id = $1 ORDER BY id`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, site)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []domain.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
+// SetRole changes the role of a user of the site. The second value is false
+// if there is no such user.
+func (r *UsersRepository) SetRole(ctx context.Context, id int, role domain.Role) (domain.User, bool, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.User{}, false, err
+	}
+
+	query := `UPDATE users SET role = $3 WHERE id = $1 AND site_id = $2 RETURNING ` + userColumns
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, id, site, string(role))
+	if err != nil {
+		return domain.User{}, false, fmt.Errorf("failed to set user role: %w", err)
+	}
+
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return domain.User{}, false, nil
+	}
+	if err != nil {
+		return domain.User{}, false, fmt.Errorf("failed to scan user: %w", err)
+	}
+
+	return user, true, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser reads userColumns from row. Any extra destinations receive the
+// columns selected after them.
+func scanUser(row rowScanner, extra ...interface{}) (domain.User, error) {
+	var user domain.User
+	var role string
+
+	dest := append([]interface{}{&user.ID, &user.SiteID, &user.Username, &role, &user.CreatedAt}, extra...)
+
+	err := row.Scan(dest...)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	user.Role = domain.Role(role)
+
+	return user, nil
+}
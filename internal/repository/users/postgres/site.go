@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"comments-system/internal/domain"
+)
+
+var errNoSite = errors.New("no site in context")
+
+// siteID returns the ID of the site ctx is scoped to. Users belong to a
+// single site and are never visible from another one.
+func siteID(ctx context.Context) (int, error) {
+	site, ok := domain.SiteFromContext(ctx)
+	if !ok {
+		return 0, errNoSite
+	}
+	return site.ID, nil
+}
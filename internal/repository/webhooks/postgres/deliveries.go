@@ -0,0 +1,234 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at,
+	d.last_status_code, d.last_error, d.created_at, d.delivered_at`
+
+// Enqueue schedules the delivery of an event to the active webhooks of a
+// site that want it and returns how many deliveries were scheduled. The site
+// is given explicitly since events are not delivered in a request. An event
+// enqueued again under the same key is not scheduled twice.
+func (r *WebhooksRepository) Enqueue(ctx context.Context, siteID int, eventType domain.EventType, eventKey string, payload []byte) (int, error) {
+	query := `INSERT INTO webhook_deliveries (webhook_id, event_type, event_key, payload, created_at, next_attempt_at)
+	          SELECT id, $2::text, $3, $4::jsonb, NOW(), NOW() FROM webhooks
+	          WHERE site_id = $1 AND active AND (cardinality(events) = 0 OR $2::text = ANY(events))
+	          ON CONFLICT (webhook_id, event_key) DO NOTHING`
+
+	res, err := r.db.ExecWithRetry(ctx, r.retries, query, siteID, string(eventType), eventKey, string(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+
+	enqueued, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return int(enqueued), nil
+}
+
+// ClaimDue takes up to limit pending deliveries of active webhooks whose
+// attempt is due and moves their next attempt lease into the future, so
+// other workers skip them while they are sent. A delivery whose worker dies
+// is picked up again when the lease runs out.
+func (r *WebhooksRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]domain.DueDelivery, error) {
+	query := `UPDATE webhook_deliveries d SET next_attempt_at = NOW() + make_interval(secs => $2)
+	          FROM webhooks w
+	          WHERE w.id = d.webhook_id AND d.id IN (
+	              SELECT pd.id FROM webhook_deliveries pd
+	              JOIN webhooks pw ON pw.id = pd.webhook_id
+	              WHERE pd.status = 'pending' AND pd.next_attempt_at <= NOW() AND pw.active
+	              ORDER BY pd.next_attempt_at
+	              LIMIT $1
+	              FOR UPDATE OF pd SKIP LOCKED
+	          )
+	          RETURNING ` + deliveryColumns + `, w.url, w.secret`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	due := []domain.DueDelivery{}
+	for rows.Next() {
+		var d domain.DueDelivery
+		d.Delivery, err = scanDelivery(rows, &d.URL, &d.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+
+		due = append(due, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return due, nil
+}
+
+// MarkDelivered records the successful attempt of a delivery.
+func (r *WebhooksRepository) MarkDelivered(ctx context.Context, id int64, statusCode int) error {
+	query := `UPDATE webhook_deliveries SET
+	              status = 'delivered',
+	              attempts = attempts + 1,
+	              last_status_code = $2,
+	              last_error = '',
+	              delivered_at = NOW()
+	          WHERE id = $1`
+
+	_, err := r.db.ExecWithRetry(ctx, r.retries, query, id, statusCode)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivery delivered: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed records a failed attempt of a delivery and schedules the next
+// one after retryIn. statusCode is 0 when the receiver did not answer.
+func (r *WebhooksRepository) MarkFailed(ctx context.Context, id int64, statusCode int, errText string, retryIn time.Duration) error {
+	query := `UPDATE webhook_deliveries SET
+	              attempts = attempts + 1,
+	              last_status_code = NULLIF($2, 0),
+	              last_error = $3,
+	              next_attempt_at = NOW() + make_interval(secs => $4)
+	          WHERE id = $1`
+
+	_, err := r.db.ExecWithRetry(ctx, r.retries, query, id, statusCode, errText, retryIn.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivery failed: %w", err)
+	}
+
+	return nil
+}
+
+// MarkDead records the last failed attempt of a delivery, which is not
+// retried any more.
+func (r *WebhooksRepository) MarkDead(ctx context.Context, id int64, statusCode int, errText string) error {
+	query := `UPDATE webhook_deliveries SET
+	              status = 'dead',
+	              attempts = attempts + 1,
+	              last_status_code = NULLIF($2, 0),
+	              last_error = $3
+	          WHERE id = $1`
+
+	_, err := r.db.ExecWithRetry(ctx, r.retries, query, id, statusCode, errText)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivery dead: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeliveries returns a page of the deliveries of a webhook of the site of
+// ctx, newest first, and the total number of them. An empty status lists
+// deliveries in any status.
+func (r *WebhooksRepository) ListDeliveries(ctx context.Context, webhookID int, status domain.DeliveryStatus, limit, offset int) ([]domain.WebhookDelivery, int, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where := `FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+	          WHERE w.site_id = $1 AND d.webhook_id = $2 AND ($3::text = '' OR d.status = $3::text)`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, `SELECT COUNT(*) `+where, site, webhookID, string(status))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	var total int
+	err = row.Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to scan count: %w", err)
+	}
+
+	query := `SELECT ` + deliveryColumns + ` ` + where + `
+	          ORDER BY d.created_at DESC, d.id DESC
+	          LIMIT $4 OFFSET $5`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, site, webhookID, string(status), limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, total, nil
+}
+
+// Redeliver makes a delivery of a webhook of the site of ctx pending again
+// with a fresh set of attempts, whatever its status. It returns false if
+// there is no such delivery.
+func (r *WebhooksRepository) Redeliver(ctx context.Context, webhookID int, deliveryID int64) (bool, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	query := `UPDATE webhook_deliveries d SET
+	              status = 'pending',
+	              attempts = 0,
+	              next_attempt_at = NOW(),
+	              delivered_at = NULL
+	          FROM webhooks w
+	          WHERE w.id = d.webhook_id AND w.site_id = $1 AND d.webhook_id = $2 AND d.id = $3`
+
+	res, err := r.db.ExecWithRetry(ctx, r.retries, query, site, webhookID, deliveryID)
+	if err != nil {
+		return false, fmt.Errorf("failed to redeliver webhook delivery: %w", err)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return updated > 0, nil
+}
+
+// scanDelivery scans the delivery columns of a row followed by extra.
+func scanDelivery(row rowScanner, extra ...interface{}) (domain.WebhookDelivery, error) {
+	var d domain.WebhookDelivery
+	var eventType, status string
+	var statusCode sql.NullInt64
+	var deliveredAt sql.NullTime
+
+	dest := []interface{}{&d.ID, &d.WebhookID, &eventType, &d.Payload, &status, &d.Attempts, &d.NextAttemptAt,
+		&statusCode, &d.LastError, &d.CreatedAt, &deliveredAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+
+	d.EventType = domain.EventType(eventType)
+	d.Status = domain.DeliveryStatus(status)
+	d.LastStatusCode = int(statusCode.Int64)
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+
+	return d, nil
+}
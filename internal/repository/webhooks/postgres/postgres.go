@@ -0,0 +1,230 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"comments-system/internal/domain"
+
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/wb-go/wbf/retry"
+)
+
+const webhookColumns = `id, site_id, url, secret, events, active, created_at, updated_at`
+
+type WebhooksRepository struct {
+	db      *dbpg.DB
+	retries retry.Strategy
+}
+
+func NewWebhooksRepository(db *dbpg.DB, retries retry.Strategy) *WebhooksRepository {
+	return &WebhooksRepository{
+		db:      db,
+		retries: retries,
+	}
+}
+
+// Create stores a new webhook of the site of ctx.
+func (r *WebhooksRepository) Create(ctx context.Context, hook domain.Webhook) (domain.Webhook, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+
+	query := `INSERT INTO webhooks (site_id, url, secret, events, active, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+	          RETURNING ` + webhookColumns
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query,
+		site, hook.URL, hook.Secret, pq.Array(eventNames(hook.Events)), hook.Active)
+	if err != nil {
+		return domain.Webhook{}, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	created, err := scanWebhook(row)
+	if err != nil {
+		return domain.Webhook{}, fmt.Errorf("failed to scan created webhook: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *WebhooksRepository) Exists(ctx context.Context, id int) (bool, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	query := `SELECT EXISTS(SELECT 1 FROM webhooks WHERE site_id = $1 AND id = $2)`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, site, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to check webhook existence: %w", err)
+	}
+
+	var exists bool
+	err = row.Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to scan existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+func (r *WebhooksRepository) GetByID(ctx context.Context, id int) (domain.Webhook, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE site_id = $1 AND id = $2`
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, site, id)
+	if err != nil {
+		return domain.Webhook{}, fmt.Errorf("failed to query webhook: %w", err)
+	}
+
+	hook, err := scanWebhook(row)
+	if err == sql.ErrNoRows {
+		return domain.Webhook{}, fmt.Errorf("webhook not found")
+	}
+	if err != nil {
+		return domain.Webhook{}, fmt.Errorf("failed to scan webhook: %w", err)
+	}
+
+	return hook, nil
+}
+
+func (r *WebhooksRepository) List(ctx context.Context) ([]domain.Webhook, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE site_id = $1 ORDER BY id`
+
+	rows, err := r.db.QueryWithRetry(ctx, r.retries, query, site)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []domain.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+
+		hooks = append(hooks, hook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhooks: %w", err)
+	}
+
+	return hooks, nil
+}
+
+// Update changes the settings set in upd and keeps the others.
+func (r *WebhooksRepository) Update(ctx context.Context, id int, upd domain.WebhookUpdate) (domain.Webhook, error) {
+	site, err := siteID(ctx)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+
+	var events interface{}
+	if upd.Events != nil {
+		events = pq.Array(eventNames(*upd.Events))
+	}
+
+	query := `UPDATE webhooks SET
+	              url = COALESCE($3, url),
+	              events = COALESCE($4::text[], events),
+	              active = COALESCE($5, active),
+	              updated_at = NOW()
+	          WHERE site_id = $1 AND id = $2
+	          RETURNING ` + webhookColumns
+
+	row, err := r.db.QueryRowWithRetry(ctx, r.retries, query, site, id, upd.URL, events, upd.Active)
+	if err != nil {
+		return domain.Webhook{}, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	hook, err := scanWebhook(row)
+	if err == sql.ErrNoRows {
+		return domain.Webhook{}, fmt.Errorf("webhook not found")
+	}
+	if err != nil {
+		return domain.Webhook{}, fmt.Errorf("failed to scan updated webhook: %w", err)
+	}
+
+	return hook, nil
+}
+
+// SetSecret replaces the signing secret of a webhook. Deliveries sent from
+// then on are signed with the new one.
+func (r *WebhooksRepository) SetSecret(ctx context.Context, id int, secret string) error {
+	site, err := siteID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE webhooks SET secret = $3, updated_at = NOW() WHERE site_id = $1 AND id = $2`
+
+	_, err = r.db.ExecWithRetry(ctx, r.retries, query, site, id, secret)
+	if err != nil {
+		return fmt.Errorf("failed to set webhook secret: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a webhook with its delivery log.
+func (r *WebhooksRepository) Delete(ctx context.Context, id int) error {
+	site, err := siteID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM webhooks WHERE site_id = $1 AND id = $2`
+
+	_, err = r.db.ExecWithRetry(ctx, r.retries, query, site, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhook(row rowScanner) (domain.Webhook, error) {
+	var hook domain.Webhook
+	var events []string
+
+	err := row.Scan(&hook.ID, &hook.SiteID, &hook.URL, &hook.Secret, pq.Array(&events), &hook.Active,
+		&hook.CreatedAt, &hook.UpdatedAt)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+
+	hook.Events = make([]domain.EventType, 0, len(events))
+	for _, event := range events {
+		hook.Events = append(hook.Events, domain.EventType(event))
+	}
+
+	return hook, nil
+}
+
+func eventNames(events []domain.EventType) []string {
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, string(event))
+	}
+	return names
+}
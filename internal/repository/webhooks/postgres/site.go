@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"comments-system/internal/domain"
+)
+
+var errNoSite = errors.New("no site in context")
+
+// siteID returns the ID of the site ctx is scoped to. Site admins only see
+// the webhooks of their own site.
+func siteID(ctx context.Context) (int, error) {
+	site, ok := domain.SiteFromContext(ctx)
+	if !ok {
+		return 0, errNoSite
+	}
+	return site.ID, nil
+}
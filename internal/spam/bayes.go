@@ -0,0 +1,73 @@
+package spam
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"comments-system/internal/domain"
+)
+
+// BayesClassifier is a naive Bayes classifier trained per site on the
+// comments moderators approve or mark as spam.
+type BayesClassifier struct {
+	store   modelStore
+	minDocs int
+}
+
+// NewBayesClassifier creates a classifier that stays silent until it has
+// been trained on at least minDocs spam and minDocs ham comments.
+func NewBayesClassifier(store modelStore, minDocs int) *BayesClassifier {
+	return &BayesClassifier{
+		store:   store,
+		minDocs: minDocs,
+	}
+}
+
+// Check scores a comment by how far its spam probability exceeds one half,
+// so a comment the model cannot tell apart scores 0.
+func (c *BayesClassifier) Check(ctx context.Context, comment domain.Comment) (Result, error) {
+	tokens := Tokenize(comment.Content)
+	if len(tokens) == 0 {
+		return Result{}, nil
+	}
+
+	counts, docs, err := c.store.Counts(ctx, tokens)
+	if err != nil {
+		return Result{}, err
+	}
+	if docs.Spam < c.minDocs || docs.Ham < c.minDocs {
+		return Result{}, nil
+	}
+
+	p := spamProbability(tokens, counts, docs)
+	if p <= 0.5 {
+		return Result{}, nil
+	}
+
+	return Result{Score: 2*p - 1, Reason: fmt.Sprintf("bayes: spam probability %.2f", p)}, nil
+}
+
+func (c *BayesClassifier) Learn(ctx context.Context, comment domain.Comment, isSpam bool) error {
+	return c.store.Train(ctx, comment.ID, Tokenize(comment.Content), isSpam)
+}
+
+// spamProbability combines the class priors with the likelihood of every
+// known token in a comment of each class. Token likelihoods are Laplace
+// smoothed; tokens never seen in training are left out.
+func spamProbability(tokens []string, counts map[string]domain.SpamTokenCount, docs domain.SpamTokenCount) float64 {
+	logOdds := math.Log(float64(docs.Spam) / float64(docs.Ham))
+
+	for _, token := range tokens {
+		count, ok := counts[token]
+		if !ok || count.Spam+count.Ham == 0 {
+			continue
+		}
+
+		pSpam := (float64(count.Spam) + 1) / (float64(docs.Spam) + 2)
+		pHam := (float64(count.Ham) + 1) / (float64(docs.Ham) + 2)
+		logOdds += math.Log(pSpam / pHam)
+	}
+
+	return 1 / (1 + math.Exp(-logOdds))
+}
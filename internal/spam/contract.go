@@ -0,0 +1,17 @@
+package spam
+
+import (
+	"context"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+type recentComments interface {
+	RecentByAuthor(ctx context.Context, userID *int, author string, since time.Time, limit int) ([]domain.Comment, error)
+}
+
+type modelStore interface {
+	Counts(ctx context.Context, tokens []string) (map[string]domain.SpamTokenCount, domain.SpamTokenCount, error)
+	Train(ctx context.Context, commentID int, tokens []string, isSpam bool) error
+}
@@ -0,0 +1,84 @@
+package spam
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+const (
+	// minDuplicateTokens keeps short replies like "thanks!" from counting
+	// as duplicates.
+	minDuplicateTokens = 5
+	// duplicateSimilarity is how much of their words two comments must
+	// share to count as the same text.
+	duplicateSimilarity = 0.9
+)
+
+// DuplicateChecker flags comments that repeat one of the recent comments of
+// the same author. Authenticated users are matched by account, guests by
+// the author name they give.
+type DuplicateChecker struct {
+	repo   recentComments
+	window time.Duration
+	limit  int
+	now    func() time.Time
+}
+
+// NewDuplicateChecker creates a checker comparing a comment with at most
+// limit comments its author posted within window.
+func NewDuplicateChecker(repo recentComments, window time.Duration, limit int) *DuplicateChecker {
+	return &DuplicateChecker{
+		repo:   repo,
+		window: window,
+		limit:  limit,
+		now:    time.Now,
+	}
+}
+
+func (c *DuplicateChecker) Check(ctx context.Context, comment domain.Comment) (Result, error) {
+	tokens := Tokenize(comment.Content)
+	if len(tokens) < minDuplicateTokens {
+		return Result{}, nil
+	}
+
+	recent, err := c.repo.RecentByAuthor(ctx, comment.UserID, comment.Author, c.now().Add(-c.window), c.limit)
+	if err != nil {
+		return Result{}, err
+	}
+
+	best, bestID := 0.0, 0
+	for _, prev := range recent {
+		if s := similarity(tokens, Tokenize(prev.Content)); s > best {
+			best, bestID = s, prev.ID
+		}
+	}
+	if best < duplicateSimilarity {
+		return Result{}, nil
+	}
+
+	return Result{Score: best, Reason: fmt.Sprintf("duplicate of comment %d", bestID)}, nil
+}
+
+// similarity is the Jaccard index of two token sets.
+func similarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[t] = true
+	}
+
+	shared := 0
+	for _, t := range b {
+		if set[t] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
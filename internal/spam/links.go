@@ -0,0 +1,27 @@
+package spam
+
+import (
+	"context"
+	"fmt"
+
+	"comments-system/internal/domain"
+)
+
+// LinkChecker flags comments with more links than a site normally sees in
+// one comment.
+type LinkChecker struct {
+	maxLinks int
+}
+
+func NewLinkChecker(maxLinks int) *LinkChecker {
+	return &LinkChecker{maxLinks: maxLinks}
+}
+
+func (c *LinkChecker) Check(ctx context.Context, comment domain.Comment) (Result, error) {
+	links := countLinks(comment.Content) + countLinks(comment.Author)
+	if links <= c.maxLinks {
+		return Result{}, nil
+	}
+
+	return Result{Score: 1, Reason: fmt.Sprintf("links: %d > %d", links, c.maxLinks)}, nil
+}
@@ -0,0 +1,103 @@
+package spam
+
+import (
+	"context"
+	"fmt"
+
+	"comments-system/internal/domain"
+)
+
+// Checker scores a new comment for spam.
+type Checker interface {
+	// Check returns a score between 0 (clean) and 1 (certainly spam) and,
+	// for a non-zero score, the reason for it.
+	Check(ctx context.Context, comment domain.Comment) (Result, error)
+}
+
+// Learner is a Checker that learns from the decisions of moderators.
+type Learner interface {
+	Learn(ctx context.Context, comment domain.Comment, isSpam bool) error
+}
+
+type Result struct {
+	Score  float64
+	Reason string
+}
+
+type weightedChecker struct {
+	checker Checker
+	weight  float64
+}
+
+// Pipeline runs checkers one after another and adds up their weighted
+// scores. A comment scoring queueAt or more is queued for a moderator, one
+// scoring rejectAt or more is rejected.
+type Pipeline struct {
+	checkers []weightedChecker
+	queueAt  float64
+	rejectAt float64
+}
+
+func NewPipeline(queueAt, rejectAt float64) *Pipeline {
+	return &Pipeline{
+		queueAt:  queueAt,
+		rejectAt: rejectAt,
+	}
+}
+
+// Add appends a checker whose score counts weight times.
+func (p *Pipeline) Add(checker Checker, weight float64) *Pipeline {
+	p.checkers = append(p.checkers, weightedChecker{checker: checker, weight: weight})
+	return p
+}
+
+// Check runs the checkers until the comment is certain to be rejected and
+// returns the combined verdict.
+func (p *Pipeline) Check(ctx context.Context, comment domain.Comment) (domain.SpamVerdict, error) {
+	verdict := domain.SpamVerdict{Decision: domain.SpamAccept}
+
+	for _, c := range p.checkers {
+		if verdict.Score >= p.rejectAt {
+			break
+		}
+
+		res, err := c.checker.Check(ctx, comment)
+		if err != nil {
+			return domain.SpamVerdict{}, fmt.Errorf("spam check failed: %w", err)
+		}
+		if res.Score <= 0 {
+			continue
+		}
+
+		verdict.Score += res.Score * c.weight
+		verdict.Reasons = append(verdict.Reasons, res.Reason)
+	}
+
+	switch {
+	case verdict.Score >= p.rejectAt:
+		verdict.Decision = domain.SpamReject
+	case verdict.Score >= p.queueAt:
+		verdict.Decision = domain.SpamQueue
+	}
+
+	return verdict, nil
+}
+
+// Learn passes a moderator's decision on comments to the checkers that
+// learn from them.
+func (p *Pipeline) Learn(ctx context.Context, comments []domain.Comment, isSpam bool) error {
+	for _, c := range p.checkers {
+		learner, ok := c.checker.(Learner)
+		if !ok {
+			continue
+		}
+
+		for _, comment := range comments {
+			if err := learner.Learn(ctx, comment, isSpam); err != nil {
+				return fmt.Errorf("failed to learn from comment %d: %w", comment.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
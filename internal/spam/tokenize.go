@@ -0,0 +1,69 @@
+package spam
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	minTokenLength = 2
+	maxTokenLength = 32
+	// maxTokens bounds how much of a long comment the classifier looks at.
+	maxTokens = 200
+)
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+
+// Tokenize splits text into the distinct lowercase words it contains. Every
+// link becomes a single "host:" token so links to one site count as one
+// feature wherever they point.
+func Tokenize(text string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	add := func(token string) {
+		if len(tokens) < maxTokens && !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	for _, link := range linkPattern.FindAllString(text, -1) {
+		if host := linkHost(link); host != "" {
+			add("host:" + host)
+		}
+	}
+	text = linkPattern.ReplaceAllString(text, " ")
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if n := len([]rune(word)); n >= minTokenLength && n <= maxTokenLength {
+			add(word)
+		}
+	}
+
+	return tokens
+}
+
+// countLinks returns how many links text contains.
+func countLinks(text string) int {
+	return len(linkPattern.FindAllStringIndex(text, -1))
+}
+
+func linkHost(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if len(host) > maxTokenLength {
+		host = host[len(host)-maxTokenLength:]
+	}
+	return host
+}
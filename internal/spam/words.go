@@ -0,0 +1,51 @@
+package spam
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"comments-system/internal/domain"
+)
+
+// BannedWordsChecker flags comments whose content or author name contains a
+// banned word. Entries with spaces are matched as phrases anywhere in the
+// text, single words only as whole words.
+type BannedWordsChecker struct {
+	words   map[string]bool
+	phrases []string
+}
+
+func NewBannedWordsChecker(words []string) *BannedWordsChecker {
+	c := &BannedWordsChecker{words: make(map[string]bool)}
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		switch {
+		case w == "":
+		case strings.ContainsAny(w, " \t"):
+			c.phrases = append(c.phrases, strings.Join(strings.Fields(w), " "))
+		default:
+			c.words[w] = true
+		}
+	}
+	return c
+}
+
+func (c *BannedWordsChecker) Check(ctx context.Context, comment domain.Comment) (Result, error) {
+	text := comment.Author + " " + comment.Content
+
+	for _, token := range Tokenize(text) {
+		if c.words[token] {
+			return Result{Score: 1, Reason: fmt.Sprintf("banned word: %q", token)}, nil
+		}
+	}
+
+	normalized := strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	for _, phrase := range c.phrases {
+		if strings.Contains(normalized, phrase) {
+			return Result{Score: 1, Reason: fmt.Sprintf("banned phrase: %q", phrase)}, nil
+		}
+	}
+
+	return Result{}, nil
+}
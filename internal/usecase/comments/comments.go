@@ -2,46 +2,177 @@ package comments_usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"comments-system/internal/domain"
 
 	"github.com/wb-go/wbf/zlog"
 )
 
+// DeleteMode selects what DeleteComment does with a comment and its replies.
+type DeleteMode string
+
+const (
+	// DeleteModeCascade removes the comment together with all its replies.
+	DeleteModeCascade DeleteMode = "cascade"
+	// DeleteModeTombstone hides the comment but keeps its replies visible.
+	DeleteModeTombstone DeleteMode = "tombstone"
+)
+
+const (
+	// DefaultContextDepth is how many levels of replies GetCommentContext
+	// loads when the caller does not ask for a specific depth.
+	DefaultContextDepth = 1
+	maxContextDepth     = 10
+)
+
+// TreeLimits bound the subtrees loaded with a listing. The defaults apply
+// when the client asks for no limit of its own, and larger limits asked for
+// are lowered to the maxima. Zero switches the respective bound off.
+type TreeLimits struct {
+	DefaultDepth    int
+	DefaultChildren int
+	MaxDepth        int
+	MaxChildren     int
+}
+
+// apply returns the depth and the number of children to load for the limits
+// asked for by the client, zero or less meaning none.
+func (l TreeLimits) apply(depth, children int) (int, int) {
+	return clampLimit(depth, l.DefaultDepth, l.MaxDepth), clampLimit(children, l.DefaultChildren, l.MaxChildren)
+}
+
+func clampLimit(n, def, max int) int {
+	if n <= 0 {
+		n = def
+	}
+	if max > 0 && (n <= 0 || n > max) {
+		n = max
+	}
+	return n
+}
+
+// threadKeyPattern limits thread keys to characters that are safe in a URL
+// path segment, e.g. "article-42" or "shop:product:1337".
+var threadKeyPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,200}$`)
+
 type CommentsUsecase struct {
-	repo   commentsRepo
-	logger *zlog.Zerolog
+	repo       commentsRepo
+	deleteMode DeleteMode
+	policy     *Policy
+	flags      FlagSettings
+	tree       TreeLimits
+	spam       spamFilter
+	limits     RateLimits
+	relay      eventRelay
+	logger     *zlog.Zerolog
 }
 
-func NewCommentsUsecase(repo commentsRepo, logger *zlog.Zerolog) *CommentsUsecase {
+// NewCommentsUsecase creates the usecase. A nil spam filter accepts every
+// comment. Events of the changes are stored by the repository along with
+// them; relay, if not nil, is woken after a change to publish them at once
+// rather than on its next poll.
+func NewCommentsUsecase(repo commentsRepo, deleteMode DeleteMode, policy *Policy, flags FlagSettings, tree TreeLimits, spam spamFilter, limits RateLimits, relay eventRelay, logger *zlog.Zerolog) *CommentsUsecase {
 	return &CommentsUsecase{
-		repo:   repo,
-		logger: logger,
+		repo:       repo,
+		deleteMode: deleteMode,
+		policy:     policy,
+		flags:      flags,
+		tree:       tree,
+		spam:       spam,
+		limits:     limits,
+		relay:      relay,
+		logger:     logger,
 	}
 }
 
+// CreateComment posts a comment. Comments of an authenticated user are
+// signed with their username; guests give an author name of their own and
+// get a management token for the comment in the result. On pre-moderated
+// sites the comment waits for a moderator unless one posted it. Comments of
+// everyone but moderators are rate limited and go through the spam filter,
+// which may queue or reject them.
 func (u *CommentsUsecase) CreateComment(ctx context.Context, comment domain.Comment) (domain.Comment, error) {
+	comment.UserID = nil
+	comment.ManageToken, comment.ManageTokenHash = "", ""
+	user, isUser := domain.UserFromContext(ctx)
+	if isUser {
+		comment.Author = user.Username
+		comment.UserID = &user.ID
+	}
+
 	if comment.Content == "" {
 		return domain.Comment{}, ErrContentRequired
 	}
 	if comment.Author == "" {
 		return domain.Comment{}, ErrAuthorRequired
 	}
-	if len(comment.Content) > 1000 {
+	maxContent, maxAuthor := lengthLimits(ctx)
+	if len(comment.Content) > maxContent {
 		return domain.Comment{}, ErrContentTooLong
 	}
-	if len(comment.Author) > 50 {
+	if len(comment.Author) > maxAuthor {
 		return domain.Comment{}, ErrAuthorTooLong
 	}
 
+	if comment.ThreadKey != "" && !threadKeyPattern.MatchString(comment.ThreadKey) {
+		return domain.Comment{}, ErrInvalidThreadKey
+	}
+
+	// A reply lives in the thread of its parent.
 	if comment.ParentID != nil {
-		exists, err := u.repo.Exists(ctx, *comment.ParentID)
+		parent, err := u.getExisting(ctx, *comment.ParentID)
+		if errors.Is(err, ErrCommentNotFound) {
+			return domain.Comment{}, fmt.Errorf("%w: parent comment %d not found", ErrInvalidParentID, *comment.ParentID)
+		}
 		if err != nil {
 			return domain.Comment{}, err
 		}
-		if !exists {
-			return domain.Comment{}, fmt.Errorf("%w: parent comment %d not found", ErrInvalidParentID, *comment.ParentID)
+		if comment.ThreadKey != "" && comment.ThreadKey != parent.ThreadKey {
+			return domain.Comment{}, fmt.Errorf("%w: parent comment %d is in another thread", ErrInvalidParentID, *comment.ParentID)
+		}
+		comment.ThreadKey = parent.ThreadKey
+	}
+	if comment.ThreadKey == "" {
+		comment.ThreadKey = domain.DefaultThreadKey
+	}
+
+	isModerator := isUser && user.CanModerate()
+	comment.Status = domain.StatusApproved
+	if site, ok := domain.SiteFromContext(ctx); ok && site.ModerationMode == domain.ModerationPre && !isModerator {
+		comment.Status = domain.StatusPending
+	}
+
+	if !isModerator {
+		if err := u.checkRateLimits(ctx, comment); err != nil {
+			return domain.Comment{}, err
+		}
+	}
+
+	if u.spam != nil && !isModerator {
+		verdict, err := u.spam.Check(ctx, comment)
+		if err != nil {
+			return domain.Comment{}, err
+		}
+
+		switch verdict.Decision {
+		case domain.SpamReject:
+			return domain.Comment{}, fmt.Errorf("%w: score %.2f (%s)", ErrSpamDetected, verdict.Score, strings.Join(verdict.Reasons, "; "))
+		case domain.SpamQueue:
+			comment.Status = domain.StatusPending
+		}
+	}
+
+	var manageToken string
+	if !isUser {
+		var err error
+		manageToken, comment.ManageTokenHash, err = newManageToken()
+		if err != nil {
+			return domain.Comment{}, err
 		}
 	}
 
@@ -49,51 +180,416 @@ func (u *CommentsUsecase) CreateComment(ctx context.Context, comment domain.Comm
 	if err != nil {
 		return domain.Comment{}, err
 	}
+	u.wakeRelay()
+
+	createdComment.ManageToken = manageToken
+	createdComment.ManageTokenHash = ""
 
 	return createdComment, nil
 }
 
-func (u *CommentsUsecase) GetComments(ctx context.Context, parentID *int, page, pageSize int, searchQuery, sortBy, sortOrder string) (domain.CommentTree, error) {
-	if page < 1 {
-		page = 1
+func (u *CommentsUsecase) GetComments(ctx context.Context, q domain.CommentsQuery) (domain.CommentTree, error) {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = 10
 	}
-	if pageSize < 1 {
-		pageSize = 10
+	if q.PageSize > 100 {
+		q.PageSize = 100
 	}
-	if pageSize > 100 {
-		pageSize = 100
+	q.MaxDepth, q.MaxChildren = u.tree.apply(q.MaxDepth, q.MaxChildren)
+	if q.Filter.MinReplies < 0 {
+		q.Filter.MinReplies = 0
 	}
 
-	if parentID != nil {
-		exists, err := u.repo.Exists(ctx, *parentID)
+	if q.After != "" && q.Before != "" {
+		return domain.CommentTree{}, fmt.Errorf("%w: after and before are mutually exclusive", ErrInvalidCursor)
+	}
+	if q.After != "" {
+		key, err := decodeKeyset(q.After, q.SortBy, q.SortOrder)
 		if err != nil {
 			return domain.CommentTree{}, err
 		}
-		if !exists {
+		q.AfterKey = key
+	}
+	if q.Before != "" {
+		key, err := decodeKeyset(q.Before, q.SortBy, q.SortOrder)
+		if err != nil {
+			return domain.CommentTree{}, err
+		}
+		q.BeforeKey = key
+	}
+
+	if q.ThreadKey != "" && !threadKeyPattern.MatchString(q.ThreadKey) {
+		return domain.CommentTree{}, ErrInvalidThreadKey
+	}
+
+	if q.ParentID != nil {
+		parent, err := u.getExisting(ctx, *q.ParentID)
+		if err != nil {
+			return domain.CommentTree{}, err
+		}
+		if q.ThreadKey != "" && q.ThreadKey != parent.ThreadKey {
 			return domain.CommentTree{}, ErrCommentNotFound
 		}
 	}
+	if q.ThreadKey == "" {
+		q.ThreadKey = domain.DefaultThreadKey
+	}
 
-	comments, total, err := u.repo.GetTree(ctx, parentID, page, pageSize, searchQuery, sortBy, sortOrder)
+	page, err := u.repo.GetTree(ctx, q)
 	if err != nil {
 		return domain.CommentTree{}, err
 	}
 
-	return domain.CommentTree{
-		Comments: comments,
+	setRepliesCursors(page.Comments, domain.RepliesQuery{
+		MaxDepth:    q.MaxDepth,
+		MaxChildren: q.MaxChildren,
+		SortBy:      q.SortBy,
+		SortOrder:   q.SortOrder,
+		ChildSort:   q.ChildSort,
+		ChildOrder:  q.ChildOrder,
+	})
+
+	tree := domain.CommentTree{
+		Comments: page.Comments,
+		Total:    page.Total,
+		HasTotal: q.WithTotal || q.ParentID != nil,
+		Page:     q.Page,
+		PageSize: q.PageSize,
+	}
+
+	switch {
+	case q.BeforeKey != nil:
+		tree.HasPrev = page.HasMore
+		tree.HasNext = true
+	case q.AfterKey != nil:
+		tree.HasNext = page.HasMore
+		tree.HasPrev = true
+	default:
+		tree.HasNext = page.HasMore
+		tree.HasPrev = q.Page > 1
+	}
+
+	if tree.HasNext && page.Last != nil {
+		tree.NextCursor = encodeKeyset(page.Last, q.SortBy, q.SortOrder)
+	}
+	if tree.HasPrev && page.First != nil {
+		tree.PrevCursor = encodeKeyset(page.First, q.SortBy, q.SortOrder)
+	}
+
+	return tree, nil
+}
+
+// SearchComments returns comments matching q.Search and q.Filter, each with
+// its ancestors up to the thread root so the match can be shown in its
+// conversation.
+func (u *CommentsUsecase) SearchComments(ctx context.Context, q domain.CommentsQuery) (domain.SearchResults, error) {
+	if q.Search == "" && q.Filter.IsEmpty() {
+		return domain.SearchResults{}, ErrSearchRequired
+	}
+	if q.ThreadKey != "" && !threadKeyPattern.MatchString(q.ThreadKey) {
+		return domain.SearchResults{}, ErrInvalidThreadKey
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = 10
+	}
+	if q.PageSize > 100 {
+		q.PageSize = 100
+	}
+
+	hits, total, hasMore, err := u.repo.Search(ctx, q)
+	if err != nil {
+		return domain.SearchResults{}, err
+	}
+
+	return domain.SearchResults{
+		Hits:     hits,
 		Total:    total,
-		Page:     page,
-		PageSize: pageSize,
-		HasNext:  (page * pageSize) < total,
-		HasPrev:  page > 1,
+		HasTotal: q.WithTotal,
+		Page:     q.Page,
+		PageSize: q.PageSize,
+		HasNext:  hasMore,
+		HasPrev:  q.Page > 1,
 	}, nil
 }
 
+// GetReplies returns the next slice of direct replies to q.ParentID. With a
+// cursor the slice continues where the cursor points and the limits and sort
+// stored in it are used; otherwise it starts from the first reply.
+func (u *CommentsUsecase) GetReplies(ctx context.Context, cursor string, q domain.RepliesQuery) (domain.CommentReplies, error) {
+	if q.ParentID <= 0 {
+		return domain.CommentReplies{}, ErrInvalidCommentID
+	}
+
+	pos := newRepliesCursor(q)
+	pos.Offset = 0
+	if cursor != "" {
+		if err := decodeCursor(cursor, &pos); err != nil {
+			return domain.CommentReplies{}, err
+		}
+		if pos.ParentID != q.ParentID || pos.Offset < 0 {
+			return domain.CommentReplies{}, ErrInvalidCursor
+		}
+	}
+	pos.MaxDepth, pos.MaxChildren = u.tree.apply(pos.MaxDepth, pos.MaxChildren)
+
+	if _, err := u.getExisting(ctx, q.ParentID); err != nil {
+		return domain.CommentReplies{}, err
+	}
+
+	replies, total, err := u.repo.GetReplies(ctx, pos.query())
+	if err != nil {
+		return domain.CommentReplies{}, err
+	}
+
+	setRepliesCursors(replies, pos.query())
+
+	result := domain.CommentReplies{
+		Comments:  replies,
+		Remaining: total - pos.Offset - len(replies),
+	}
+	if result.Remaining < 0 {
+		result.Remaining = 0
+	}
+	if result.Remaining > 0 {
+		next := pos
+		next.Offset += len(replies)
+		result.NextCursor = encodeCursor(next)
+	}
+
+	return result, nil
+}
+
+func (u *CommentsUsecase) GetComment(ctx context.Context, id int) (domain.Comment, error) {
+	if id <= 0 {
+		return domain.Comment{}, ErrInvalidCommentID
+	}
+
+	comment, err := u.getExisting(ctx, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+
+	comment.ReplyCount, comment.DescendantCount, err = u.repo.CountReplies(ctx, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+
+	return comment, nil
+}
+
+// GetCommentContext returns a comment with all its ancestors up to the thread
+// root and depth levels of its replies.
+func (u *CommentsUsecase) GetCommentContext(ctx context.Context, id, depth int) (domain.CommentContext, error) {
+	if id <= 0 {
+		return domain.CommentContext{}, ErrInvalidCommentID
+	}
+	if depth < 0 {
+		depth = 0
+	}
+	if depth > maxContextDepth {
+		depth = maxContextDepth
+	}
+
+	if _, err := u.getExisting(ctx, id); err != nil {
+		return domain.CommentContext{}, err
+	}
+
+	ancestors, err := u.repo.GetAncestors(ctx, id)
+	if err != nil {
+		return domain.CommentContext{}, err
+	}
+	// A reply under a hidden comment is hidden with it.
+	for _, ancestor := range ancestors {
+		if !u.policy.CanView(ctx, ancestor) {
+			return domain.CommentContext{}, ErrCommentNotFound
+		}
+	}
+
+	comment, err := u.repo.GetSubtree(ctx, id, depth)
+	if err != nil {
+		return domain.CommentContext{}, err
+	}
+
+	comment.ReplyCount, comment.DescendantCount, err = u.repo.CountReplies(ctx, id)
+	if err != nil {
+		return domain.CommentContext{}, err
+	}
+
+	return domain.CommentContext{
+		Ancestors: ancestors,
+		Comment:   comment,
+	}, nil
+}
+
+func (u *CommentsUsecase) UpdateComment(ctx context.Context, id int, content string) (domain.Comment, error) {
+	if id <= 0 {
+		return domain.Comment{}, ErrInvalidCommentID
+	}
+	if content == "" {
+		return domain.Comment{}, ErrContentRequired
+	}
+	if maxContent, _ := lengthLimits(ctx); len(content) > maxContent {
+		return domain.Comment{}, ErrContentTooLong
+	}
+
+	comment, err := u.getExisting(ctx, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	if comment.IsDeleted() {
+		return domain.Comment{}, ErrCommentDeleted
+	}
+	if err := u.policy.Authorize(ctx, ActionEdit, comment); err != nil {
+		return domain.Comment{}, err
+	}
+
+	updatedComment, err := u.repo.Update(ctx, id, content)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	u.wakeRelay()
+
+	return updatedComment, nil
+}
+
+// VoteComment records the vote of the caller on a comment: 1 for an upvote,
+// -1 for a downvote and 0 to withdraw an earlier vote. Authenticated users
+// vote under their account, guests under their IP address. Each voter has at
+// most one vote per comment; voting again replaces it.
+func (u *CommentsUsecase) VoteComment(ctx context.Context, id int, value int) (domain.Comment, error) {
+	if id <= 0 {
+		return domain.Comment{}, ErrInvalidCommentID
+	}
+	voter := callerKey(ctx)
+	if voter == "" {
+		return domain.Comment{}, ErrVoterRequired
+	}
+	if value < -1 || value > 1 {
+		return domain.Comment{}, ErrInvalidVote
+	}
+
+	comment, err := u.getExisting(ctx, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	if comment.IsDeleted() {
+		return domain.Comment{}, ErrCommentDeleted
+	}
+
+	return u.repo.Vote(ctx, id, voter, value)
+}
+
+// callerKey identifies the caller of ctx for votes and flags: the account of
+// an authenticated user or the IP address of a guest. The prefixes keep the
+// two apart.
+func callerKey(ctx context.Context) string {
+	if user, ok := domain.UserFromContext(ctx); ok {
+		return "user:" + strconv.Itoa(user.ID)
+	}
+	if ip := domain.ClientIPFromContext(ctx); ip != "" {
+		return "ip:" + ip
+	}
+	return ""
+}
+
+func (u *CommentsUsecase) GetRevisions(ctx context.Context, id int) ([]domain.CommentRevision, error) {
+	if id <= 0 {
+		return nil, ErrInvalidCommentID
+	}
+
+	comment, err := u.getExisting(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.IsDeleted() {
+		return nil, ErrCommentDeleted
+	}
+
+	return u.repo.GetRevisions(ctx, id)
+}
+
 func (u *CommentsUsecase) DeleteComment(ctx context.Context, id int) error {
 	if id <= 0 {
 		return ErrInvalidCommentID
 	}
 
+	comment, err := u.getExisting(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := u.policy.Authorize(ctx, ActionDelete, comment); err != nil {
+		return err
+	}
+
+	if u.deleteMode == DeleteModeTombstone {
+		err = u.repo.SoftDelete(ctx, id)
+	} else {
+		err = u.repo.Delete(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	u.wakeRelay()
+	return nil
+}
+
+func (u *CommentsUsecase) RestoreComment(ctx context.Context, id int) (domain.Comment, error) {
+	if id <= 0 {
+		return domain.Comment{}, ErrInvalidCommentID
+	}
+
+	comment, err := u.getExisting(ctx, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	if !comment.IsDeleted() {
+		return domain.Comment{}, ErrCommentNotDeleted
+	}
+	if err := u.policy.Authorize(ctx, ActionRestore, comment); err != nil {
+		return domain.Comment{}, err
+	}
+
+	restoredComment, err := u.repo.Restore(ctx, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	u.wakeRelay()
+
+	return restoredComment, nil
+}
+
+// RevokeManageToken stops the management token of a guest comment from
+// working. Only moderators may do so.
+func (u *CommentsUsecase) RevokeManageToken(ctx context.Context, id int) error {
+	if id <= 0 {
+		return ErrInvalidCommentID
+	}
+
+	comment, err := u.getExisting(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := u.policy.Authorize(ctx, ActionRevokeToken, comment); err != nil {
+		return err
+	}
+
+	return u.repo.RevokeManageToken(ctx, id)
+}
+
+// PurgeComment removes a comment and all its replies regardless of the
+// configured delete mode.
+func (u *CommentsUsecase) PurgeComment(ctx context.Context, id int) error {
+	if id <= 0 {
+		return ErrInvalidCommentID
+	}
+
 	exists, err := u.repo.Exists(ctx, id)
 	if err != nil {
 		return err
@@ -102,10 +598,42 @@ func (u *CommentsUsecase) DeleteComment(ctx context.Context, id int) error {
 		return ErrCommentNotFound
 	}
 
-	err = u.repo.Delete(ctx, id)
-	if err != nil {
+	if err := u.repo.Delete(ctx, id); err != nil {
 		return err
 	}
 
+	u.wakeRelay()
 	return nil
 }
+
+// getExisting returns a comment the caller may see. Comments hidden from
+// the caller are reported as not found.
+func (u *CommentsUsecase) getExisting(ctx context.Context, id int) (domain.Comment, error) {
+	exists, err := u.repo.Exists(ctx, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	if !exists {
+		return domain.Comment{}, ErrCommentNotFound
+	}
+
+	comment, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return domain.Comment{}, err
+	}
+	if !u.policy.CanView(ctx, comment) {
+		return domain.Comment{}, ErrCommentNotFound
+	}
+
+	return comment, nil
+}
+
+// lengthLimits returns the content and author length limits of the site the
+// request belongs to.
+func lengthLimits(ctx context.Context) (int, int) {
+	site, ok := domain.SiteFromContext(ctx)
+	if !ok {
+		return domain.DefaultMaxContentLength, domain.DefaultMaxAuthorLength
+	}
+	return site.MaxContentLength, site.MaxAuthorLength
+}
@@ -0,0 +1,33 @@
+package comments_usecase
+
+import "testing"
+
+func TestTreeLimitsApply(t *testing.T) {
+	limits := TreeLimits{DefaultDepth: 5, DefaultChildren: 50, MaxDepth: 20, MaxChildren: 200}
+
+	tests := []struct {
+		name         string
+		limits       TreeLimits
+		depth        int
+		children     int
+		wantDepth    int
+		wantChildren int
+	}{
+		{"defaults when none asked", limits, 0, 0, 5, 50},
+		{"defaults for negative", limits, -1, -3, 5, 50},
+		{"asked within maxima", limits, 3, 100, 3, 100},
+		{"asked above maxima", limits, 1000, 100000, 20, 200},
+		{"default above maximum", TreeLimits{DefaultDepth: 30, MaxDepth: 20}, 0, 0, 20, 0},
+		{"no default but maximum", TreeLimits{MaxDepth: 20, MaxChildren: 200}, 0, 0, 20, 200},
+		{"no limits", TreeLimits{}, 0, 1000, 0, 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			depth, children := tt.limits.apply(tt.depth, tt.children)
+			if depth != tt.wantDepth || children != tt.wantChildren {
+				t.Errorf("apply(%d, %d) = %d, %d, want %d, %d", tt.depth, tt.children, depth, children, tt.wantDepth, tt.wantChildren)
+			}
+		})
+	}
+}
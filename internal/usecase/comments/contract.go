@@ -8,8 +8,38 @@ import (
 
 type commentsRepo interface {
 	Create(ctx context.Context, comment domain.Comment) (domain.Comment, error)
-	GetTree(ctx context.Context, rootID *int, page, pageSize int, searchQuery, sortBy, sortOrder string) ([]domain.Comment, int, error)
+	GetTree(ctx context.Context, q domain.CommentsQuery) (domain.CommentsPage, error)
+	Search(ctx context.Context, q domain.CommentsQuery) ([]domain.SearchHit, int, bool, error)
+	GetReplies(ctx context.Context, q domain.RepliesQuery) ([]domain.Comment, int, error)
+	Update(ctx context.Context, id int, content string) (domain.Comment, error)
+	Vote(ctx context.Context, id int, voter string, value int) (domain.Comment, error)
+	GetRevisions(ctx context.Context, commentID int) ([]domain.CommentRevision, error)
 	Delete(ctx context.Context, id int) error
+	SoftDelete(ctx context.Context, id int) error
+	RevokeManageToken(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) (domain.Comment, error)
 	Exists(ctx context.Context, id int) (bool, error)
 	GetByID(ctx context.Context, id int) (domain.Comment, error)
+	CountReplies(ctx context.Context, id int) (int, int, error)
+	GetAncestors(ctx context.Context, id int) ([]domain.Comment, error)
+	GetSubtree(ctx context.Context, id, maxDepth int) (domain.Comment, error)
+	ListByStatus(ctx context.Context, status domain.CommentStatus, limit, offset int) ([]domain.Comment, int, error)
+	SetStatus(ctx context.Context, ids []int, status domain.CommentStatus) (int, error)
+	AddFlag(ctx context.Context, flag domain.Flag, threshold int, hideAs domain.CommentStatus) (domain.FlagResult, bool, error)
+	ListFlagged(ctx context.Context, limit, offset int) ([]domain.FlaggedComment, int, error)
+	ResolveFlags(ctx context.Context, ids []int) (int, error)
+	GetByIDs(ctx context.Context, ids []int) ([]domain.Comment, error)
+}
+
+type spamFilter interface {
+	Check(ctx context.Context, comment domain.Comment) (domain.SpamVerdict, error)
+	Learn(ctx context.Context, comments []domain.Comment, isSpam bool) error
+}
+
+type limiter interface {
+	Take(ctx context.Context, key string, limit domain.RateLimit) (domain.RateLimitStatus, error)
+}
+
+type eventRelay interface {
+	Wake()
 }
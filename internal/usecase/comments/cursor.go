@@ -0,0 +1,112 @@
+package comments_usecase
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"comments-system/internal/domain"
+)
+
+// repliesCursor points at the next slice of replies to a comment. It carries
+// the limits and the sort of the original request so a client can follow it
+// without repeating them.
+type repliesCursor struct {
+	ParentID    int    `json:"p"`
+	Offset      int    `json:"o"`
+	MaxDepth    int    `json:"d,omitempty"`
+	MaxChildren int    `json:"c,omitempty"`
+	SortBy      string `json:"s,omitempty"`
+	SortOrder   string `json:"r,omitempty"`
+	ChildSort   string `json:"cs,omitempty"`
+	ChildOrder  string `json:"co,omitempty"`
+}
+
+func newRepliesCursor(q domain.RepliesQuery) repliesCursor {
+	return repliesCursor{
+		ParentID:    q.ParentID,
+		Offset:      q.Offset,
+		MaxDepth:    q.MaxDepth,
+		MaxChildren: q.MaxChildren,
+		SortBy:      q.SortBy,
+		SortOrder:   q.SortOrder,
+		ChildSort:   q.ChildSort,
+		ChildOrder:  q.ChildOrder,
+	}
+}
+
+func (c repliesCursor) query() domain.RepliesQuery {
+	return domain.RepliesQuery{
+		ParentID:    c.ParentID,
+		Offset:      c.Offset,
+		MaxDepth:    c.MaxDepth,
+		MaxChildren: c.MaxChildren,
+		SortBy:      c.SortBy,
+		SortOrder:   c.SortOrder,
+		ChildSort:   c.ChildSort,
+		ChildOrder:  c.ChildOrder,
+	}
+}
+
+// keysetCursor points at a root comment in a listing. The sort it was made
+// for is kept so it is not applied to a listing ordered differently.
+type keysetCursor struct {
+	SortBy    string `json:"s"`
+	SortOrder string `json:"o"`
+	Value     string `json:"v"`
+	ID        int    `json:"i"`
+}
+
+func encodeKeyset(key *domain.Keyset, sortBy, sortOrder string) string {
+	return encodeCursor(keysetCursor{
+		SortBy:    sortBy,
+		SortOrder: sortOrder,
+		Value:     key.Value,
+		ID:        key.ID,
+	})
+}
+
+func decodeKeyset(s, sortBy, sortOrder string) (*domain.Keyset, error) {
+	var c keysetCursor
+	if err := decodeCursor(s, &c); err != nil {
+		return nil, err
+	}
+	if c.SortBy != sortBy || c.SortOrder != sortOrder {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidCursor)
+	}
+	return &domain.Keyset{Value: c.Value, ID: c.ID}, nil
+}
+
+func encodeCursor(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return nil
+}
+
+// setRepliesCursors gives every comment with unloaded replies a cursor to
+// the next slice of them, loaded with the limits and sort of q.
+func setRepliesCursors(comments []domain.Comment, q domain.RepliesQuery) {
+	for i := range comments {
+		c := &comments[i]
+		if c.RemainingChildren > 0 {
+			next := q
+			next.ParentID = c.ID
+			next.Offset = c.ReplyCount - c.RemainingChildren
+			c.RepliesCursor = encodeCursor(newRepliesCursor(next))
+		}
+		setRepliesCursors(c.Children, q)
+	}
+}
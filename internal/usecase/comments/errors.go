@@ -1,13 +1,39 @@
 package comments_usecase
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrInvalidCommentID = errors.New("invalid comment ID")
-	ErrCommentNotFound  = errors.New("comment not found")
-	ErrInvalidParentID  = errors.New("invalid parent ID")
-	ErrContentRequired  = errors.New("content is required")
-	ErrAuthorRequired   = errors.New("author is required")
-	ErrContentTooLong   = errors.New("content is too long")
-	ErrAuthorTooLong    = errors.New("author is too long")
+	ErrInvalidCommentID  = errors.New("invalid comment ID")
+	ErrCommentNotFound   = errors.New("comment not found")
+	ErrInvalidParentID   = errors.New("invalid parent ID")
+	ErrContentRequired   = errors.New("content is required")
+	ErrAuthorRequired    = errors.New("author is required")
+	ErrContentTooLong    = errors.New("content is too long")
+	ErrAuthorTooLong     = errors.New("author is too long")
+	ErrCommentDeleted    = errors.New("comment is deleted")
+	ErrCommentNotDeleted = errors.New("comment is not deleted")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrSearchRequired    = errors.New("search query or filter is required")
+	ErrVoterRequired     = errors.New("voter is required")
+	ErrInvalidVote       = errors.New("vote must be -1, 0 or 1")
+	ErrInvalidThreadKey  = errors.New("invalid thread key")
+	ErrInvalidStatus     = errors.New("invalid comment status")
+	ErrNoCommentIDs      = errors.New("comment IDs are required")
+	ErrTooManyCommentIDs = errors.New("too many comment IDs")
+	ErrReporterRequired  = errors.New("reporter is required")
+	ErrInvalidFlagReason = errors.New("flag reason must be spam, abuse, off_topic or other")
+	ErrFlagNoteTooLong   = errors.New("flag note is too long")
+	ErrAlreadyFlagged    = errors.New("comment is already flagged by this reporter")
+	ErrSpamDetected      = errors.New("comment looks like spam")
+	ErrRateLimited       = errors.New("rate limit exceeded")
+	ErrNotRootComment    = errors.New("comment is not a thread root")
+
+	ErrAuthenticationRequired = errors.New("authentication required")
+	ErrForbidden              = errors.New("not allowed to change this comment")
+	// ErrEditWindowExpired is an ErrForbidden for authors whose edit window
+	// has passed.
+	ErrEditWindowExpired = fmt.Errorf("%w: edit window has expired", ErrForbidden)
 )
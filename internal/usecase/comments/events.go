@@ -0,0 +1,71 @@
+package comments_usecase
+
+import (
+	"context"
+
+	"comments-system/internal/domain"
+)
+
+// StreamFilter returns the filter of a live stream of comment events: the
+// events of the site of ctx that the caller may see, of the thread under the
+// root comment rootID and of the thread key threadKey when they are given.
+// An update or a moderation decision that hides a comment from the caller,
+// e.g. a rejection, reaches them as a deletion.
+func (u *CommentsUsecase) StreamFilter(ctx context.Context, rootID int, threadKey string) (func(domain.CommentEvent) (domain.CommentEvent, bool), error) {
+	if rootID < 0 {
+		return nil, ErrInvalidCommentID
+	}
+	if threadKey != "" && !threadKeyPattern.MatchString(threadKey) {
+		return nil, ErrInvalidThreadKey
+	}
+	if rootID > 0 {
+		root, err := u.getExisting(ctx, rootID)
+		if err != nil {
+			return nil, err
+		}
+		if root.RootID != root.ID {
+			return nil, ErrNotRootComment
+		}
+	}
+
+	var site int
+	if s, ok := domain.SiteFromContext(ctx); ok {
+		site = s.ID
+	}
+
+	return func(event domain.CommentEvent) (domain.CommentEvent, bool) {
+		c := event.Comment
+		if event.SiteID != site ||
+			rootID > 0 && c.RootID != rootID ||
+			threadKey != "" && c.ThreadKey != threadKey {
+			return event, false
+		}
+
+		if u.policy.CanView(ctx, c) {
+			return event, true
+		}
+		if event.Type != domain.EventCommentUpdated && event.Type != domain.EventCommentModerated {
+			return event, false
+		}
+
+		event.Type = domain.EventCommentDeleted
+		event.Comment = domain.Comment{
+			ID:        c.ID,
+			ParentID:  c.ParentID,
+			RootID:    c.RootID,
+			ThreadKey: c.ThreadKey,
+			Status:    c.Status,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+			DeletedAt: &c.UpdatedAt,
+		}
+		return event, true
+	}, nil
+}
+
+// wakeRelay asks the relay to publish the events of a change just stored.
+func (u *CommentsUsecase) wakeRelay() {
+	if u.relay != nil {
+		u.relay.Wake()
+	}
+}
@@ -0,0 +1,120 @@
+package comments_usecase
+
+import (
+	"context"
+
+	"comments-system/internal/domain"
+)
+
+// FlagAction selects what happens to a comment that collects too many flags.
+type FlagAction string
+
+const (
+	// FlagActionQueue moves the comment to the moderation queue as pending;
+	// its author still sees it.
+	FlagActionQueue FlagAction = "queue"
+	// FlagActionHide rejects the comment until a moderator reviews it.
+	FlagActionHide FlagAction = "hide"
+)
+
+const maxFlagNoteLength = 500
+
+// FlagSettings configures automatic hiding of flagged comments.
+type FlagSettings struct {
+	// Threshold is the number of open flags that hides a comment, 0 never
+	// hides it.
+	Threshold int
+	Action    FlagAction
+}
+
+func (s FlagSettings) hideAs() domain.CommentStatus {
+	if s.Action == FlagActionHide {
+		return domain.StatusRejected
+	}
+	return domain.StatusPending
+}
+
+// FlagComment reports a comment. Authenticated users report under their
+// account, guests under their IP address; either may flag a comment once.
+func (u *CommentsUsecase) FlagComment(ctx context.Context, id int, flag domain.Flag) (domain.FlagResult, error) {
+	if id <= 0 {
+		return domain.FlagResult{}, ErrInvalidCommentID
+	}
+	if !flag.Reason.IsValid() {
+		return domain.FlagResult{}, ErrInvalidFlagReason
+	}
+	if len(flag.Note) > maxFlagNoteLength {
+		return domain.FlagResult{}, ErrFlagNoteTooLong
+	}
+
+	flag.Reporter = callerKey(ctx)
+	if flag.Reporter == "" {
+		return domain.FlagResult{}, ErrReporterRequired
+	}
+	flag.CommentID = id
+
+	comment, err := u.getExisting(ctx, id)
+	if err != nil {
+		return domain.FlagResult{}, err
+	}
+	if comment.IsDeleted() {
+		return domain.FlagResult{}, ErrCommentDeleted
+	}
+
+	result, added, err := u.repo.AddFlag(ctx, flag, u.flags.Threshold, u.flags.hideAs())
+	if err != nil {
+		return domain.FlagResult{}, err
+	}
+	if !added {
+		return domain.FlagResult{}, ErrAlreadyFlagged
+	}
+
+	if result.Hidden {
+		u.wakeRelay()
+	}
+
+	return result, nil
+}
+
+// ListFlaggedComments returns a page of the comments of the site with open
+// flags, the most flagged first.
+func (u *CommentsUsecase) ListFlaggedComments(ctx context.Context, page, pageSize int) (domain.FlagInbox, error) {
+	if err := requireModerator(ctx); err != nil {
+		return domain.FlagInbox{}, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	comments, total, err := u.repo.ListFlagged(ctx, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return domain.FlagInbox{}, err
+	}
+
+	return domain.FlagInbox{
+		Comments: comments,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// DismissFlags closes the open flags of the given comments without changing
+// them and returns how many flags were closed.
+func (u *CommentsUsecase) DismissFlags(ctx context.Context, ids []int) (int, error) {
+	if err := requireModerator(ctx); err != nil {
+		return 0, err
+	}
+	if err := validateBatch(ids); err != nil {
+		return 0, err
+	}
+
+	return u.repo.ResolveFlags(ctx, ids)
+}
@@ -0,0 +1,126 @@
+package comments_usecase
+
+import (
+	"context"
+	"fmt"
+
+	"comments-system/internal/domain"
+)
+
+// maxModerationBatch is how many comments ModerateComments accepts at once.
+const maxModerationBatch = 100
+
+// ListModerationQueue returns a page of the comments of the site with the
+// given status, oldest first. An empty status lists the pending comments.
+func (u *CommentsUsecase) ListModerationQueue(ctx context.Context, status domain.CommentStatus, page, pageSize int) (domain.ModerationQueue, error) {
+	if err := requireModerator(ctx); err != nil {
+		return domain.ModerationQueue{}, err
+	}
+
+	if status == "" {
+		status = domain.StatusPending
+	}
+	if !status.IsValid() {
+		return domain.ModerationQueue{}, ErrInvalidStatus
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	comments, total, err := u.repo.ListByStatus(ctx, status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return domain.ModerationQueue{}, err
+	}
+
+	return domain.ModerationQueue{
+		Comments: comments,
+		Status:   status,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// ModerateComments moves the given comments to status and returns how many
+// of them were found on the site.
+func (u *CommentsUsecase) ModerateComments(ctx context.Context, ids []int, status domain.CommentStatus) (int, error) {
+	if err := requireModerator(ctx); err != nil {
+		return 0, err
+	}
+
+	if !status.IsValid() {
+		return 0, ErrInvalidStatus
+	}
+	if err := validateBatch(ids); err != nil {
+		return 0, err
+	}
+
+	updated, err := u.repo.SetStatus(ctx, ids, status)
+	if err != nil {
+		return 0, err
+	}
+	if updated == 0 {
+		return updated, nil
+	}
+	u.wakeRelay()
+
+	if u.spam == nil || status != domain.StatusApproved && status != domain.StatusSpam {
+		return updated, nil
+	}
+
+	// The decision itself is already stored, so what follows from it only
+	// logs its failures.
+	comments, err := u.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		u.logger.Warn().Err(err).Ints("comment_ids", ids).Msg("Failed to load moderated comments")
+		return updated, nil
+	}
+	u.learnSpam(ctx, comments, status == domain.StatusSpam)
+
+	return updated, nil
+}
+
+// learnSpam trains the spam filter on a moderator's decision.
+func (u *CommentsUsecase) learnSpam(ctx context.Context, comments []domain.Comment, isSpam bool) {
+	if u.spam == nil {
+		return
+	}
+
+	if err := u.spam.Learn(ctx, comments, isSpam); err != nil {
+		u.logger.Warn().Err(err).Int("comments", len(comments)).Bool("spam", isSpam).Msg("Failed to train spam filter")
+	}
+}
+
+// validateBatch checks the comment IDs of a bulk moderation request.
+func validateBatch(ids []int) error {
+	if len(ids) == 0 {
+		return ErrNoCommentIDs
+	}
+	if len(ids) > maxModerationBatch {
+		return fmt.Errorf("%w: at most %d per request", ErrTooManyCommentIDs, maxModerationBatch)
+	}
+	for _, id := range ids {
+		if id <= 0 {
+			return ErrInvalidCommentID
+		}
+	}
+	return nil
+}
+
+// requireModerator returns nil if the caller of ctx is a moderator or admin.
+func requireModerator(ctx context.Context) error {
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return ErrAuthenticationRequired
+	}
+	if !user.CanModerate() {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package comments_usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+// Action is a change to an existing comment that needs permission.
+type Action string
+
+const (
+	ActionEdit    Action = "edit"
+	ActionDelete  Action = "delete"
+	ActionRestore Action = "restore"
+	// ActionRevokeToken disables the management token of a guest comment.
+	ActionRevokeToken Action = "revoke_token"
+)
+
+const (
+	manageTokenPrefix = "ct_"
+	manageTokenSize   = 24
+)
+
+// Policy decides who may change a comment. Moderators and admins may do
+// anything to comments of their site. Authors may edit and delete their own
+// comments within the edit window, and so may guests presenting the
+// management token of their comment; restoring is left to moderators.
+type Policy struct {
+	editWindow time.Duration
+	now        func() time.Time
+}
+
+// NewPolicy creates a policy. An editWindow of zero lets authors change their
+// comments at any time.
+func NewPolicy(editWindow time.Duration) *Policy {
+	return &Policy{
+		editWindow: editWindow,
+		now:        time.Now,
+	}
+}
+
+// Authorize returns nil if the caller of ctx may perform action on comment,
+// ErrAuthenticationRequired for guests without a valid token and
+// ErrForbidden otherwise.
+func (p *Policy) Authorize(ctx context.Context, action Action, comment domain.Comment) error {
+	var user *domain.User
+	if u, ok := domain.UserFromContext(ctx); ok {
+		user = &u
+	}
+	return p.authorize(user, domain.CommentTokenFromContext(ctx), action, comment)
+}
+
+func (p *Policy) authorize(user *domain.User, token string, action Action, comment domain.Comment) error {
+	if user != nil && user.CanModerate() {
+		return nil
+	}
+
+	if action != ActionEdit && action != ActionDelete {
+		if user == nil {
+			return ErrAuthenticationRequired
+		}
+		return ErrForbidden
+	}
+
+	isAuthor := user != nil && comment.UserID != nil && *comment.UserID == user.ID
+	if !isAuthor && !tokenMatches(token, comment.ManageTokenHash) {
+		if user == nil {
+			return ErrAuthenticationRequired
+		}
+		return ErrForbidden
+	}
+
+	if p.editWindow > 0 && p.now().Sub(comment.CreatedAt) > p.editWindow {
+		return ErrEditWindowExpired
+	}
+	return nil
+}
+
+// CanView reports whether the caller of ctx may see comment. Approved
+// comments are public, pending ones are shown to their author, and
+// moderators see every comment of their site.
+func (p *Policy) CanView(ctx context.Context, comment domain.Comment) bool {
+	if comment.Status == domain.StatusApproved {
+		return true
+	}
+
+	user, ok := domain.UserFromContext(ctx)
+	if ok && user.CanModerate() {
+		return true
+	}
+	if comment.Status != domain.StatusPending {
+		return false
+	}
+	if ok {
+		return comment.UserID != nil && *comment.UserID == user.ID
+	}
+	return tokenMatches(domain.CommentTokenFromContext(ctx), comment.ManageTokenHash)
+}
+
+// newManageToken returns a management token for a guest comment and the hash
+// to store.
+func newManageToken() (string, string, error) {
+	buf := make([]byte, manageTokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate manage token: %w", err)
+	}
+	token := manageTokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return token, domain.HashCommentToken(token), nil
+}
+
+func tokenMatches(token, hash string) bool {
+	if token == "" || hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(domain.HashCommentToken(token)), []byte(hash)) == 1
+}
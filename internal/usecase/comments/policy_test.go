@@ -0,0 +1,145 @@
+package comments_usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+var policyNow = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestPolicy(editWindow time.Duration) *Policy {
+	p := NewPolicy(editWindow)
+	p.now = func() time.Time { return policyNow }
+	return p
+}
+
+// callerContext returns the context of a request by user, nil for a guest,
+// presenting a comment management token.
+func callerContext(user *domain.User, token string) context.Context {
+	ctx := context.Background()
+	if user != nil {
+		ctx = domain.WithUser(ctx, *user)
+	}
+	if token != "" {
+		ctx = domain.WithCommentToken(ctx, token)
+	}
+	return ctx
+}
+
+func TestPolicyAuthorize(t *testing.T) {
+	const token = "ct_valid"
+	authorID := 1
+
+	admin := &domain.User{ID: 10, Role: domain.RoleAdmin}
+	moderator := &domain.User{ID: 11, Role: domain.RoleModerator}
+	author := &domain.User{ID: authorID, Role: domain.RoleUser}
+	other := &domain.User{ID: 2, Role: domain.RoleUser}
+
+	recent := domain.Comment{ID: 1, UserID: &authorID, CreatedAt: policyNow.Add(-5 * time.Minute)}
+	old := domain.Comment{ID: 2, UserID: &authorID, CreatedAt: policyNow.Add(-time.Hour)}
+	guestRecent := domain.Comment{ID: 3, ManageTokenHash: domain.HashCommentToken(token), CreatedAt: policyNow.Add(-5 * time.Minute)}
+	guestOld := domain.Comment{ID: 4, ManageTokenHash: domain.HashCommentToken(token), CreatedAt: policyNow.Add(-time.Hour)}
+	guestRevoked := domain.Comment{ID: 5, CreatedAt: policyNow.Add(-5 * time.Minute)}
+
+	tests := []struct {
+		name    string
+		user    *domain.User
+		token   string
+		action  Action
+		comment domain.Comment
+		want    error
+	}{
+		{"admin edits old comment", admin, "", ActionEdit, old, nil},
+		{"admin restores", admin, "", ActionRestore, old, nil},
+		{"moderator deletes old comment", moderator, "", ActionDelete, old, nil},
+		{"moderator revokes token", moderator, "", ActionRevokeToken, guestRecent, nil},
+		{"author edits within window", author, "", ActionEdit, recent, nil},
+		{"author deletes within window", author, "", ActionDelete, recent, nil},
+		{"author edits after window", author, "", ActionEdit, old, ErrEditWindowExpired},
+		{"author restores", author, "", ActionRestore, recent, ErrForbidden},
+		{"other user edits", other, "", ActionEdit, recent, ErrForbidden},
+		{"other user deletes", other, "", ActionDelete, recent, ErrForbidden},
+		{"guest edits user comment", nil, "", ActionEdit, recent, ErrAuthenticationRequired},
+		{"guest without token", nil, "", ActionEdit, guestRecent, ErrAuthenticationRequired},
+		{"guest with wrong token", nil, "ct_wrong", ActionEdit, guestRecent, ErrAuthenticationRequired},
+		{"guest with valid token", nil, token, ActionEdit, guestRecent, nil},
+		{"guest with valid token deletes", nil, token, ActionDelete, guestRecent, nil},
+		{"guest with valid token after window", nil, token, ActionEdit, guestOld, ErrEditWindowExpired},
+		{"guest with revoked token", nil, token, ActionEdit, guestRevoked, ErrAuthenticationRequired},
+		{"guest with valid token restores", nil, token, ActionRestore, guestRecent, ErrAuthenticationRequired},
+		{"other user with valid token", other, token, ActionEdit, guestRecent, nil},
+	}
+
+	p := newTestPolicy(15 * time.Minute)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Authorize(callerContext(tt.user, tt.token), tt.action, tt.comment)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Authorize() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyAuthorizeWithoutEditWindow(t *testing.T) {
+	authorID := 1
+	author := &domain.User{ID: authorID, Role: domain.RoleUser}
+	comment := domain.Comment{ID: 1, UserID: &authorID, CreatedAt: policyNow.AddDate(-1, 0, 0)}
+
+	p := newTestPolicy(0)
+	if err := p.Authorize(callerContext(author, ""), ActionEdit, comment); err != nil {
+		t.Errorf("Authorize() = %v, want nil", err)
+	}
+}
+
+func TestPolicyCanView(t *testing.T) {
+	const token = "ct_valid"
+	authorID := 1
+
+	admin := &domain.User{ID: 10, Role: domain.RoleAdmin}
+	moderator := &domain.User{ID: 11, Role: domain.RoleModerator}
+	author := &domain.User{ID: authorID, Role: domain.RoleUser}
+	other := &domain.User{ID: 2, Role: domain.RoleUser}
+
+	approved := domain.Comment{ID: 1, Status: domain.StatusApproved}
+	pending := domain.Comment{ID: 2, UserID: &authorID, Status: domain.StatusPending}
+	rejected := domain.Comment{ID: 3, UserID: &authorID, Status: domain.StatusRejected}
+	guestPending := domain.Comment{ID: 4, Status: domain.StatusPending, ManageTokenHash: domain.HashCommentToken(token)}
+	guestRejected := domain.Comment{ID: 5, Status: domain.StatusRejected, ManageTokenHash: domain.HashCommentToken(token)}
+	guestRevoked := domain.Comment{ID: 6, Status: domain.StatusPending}
+
+	tests := []struct {
+		name    string
+		user    *domain.User
+		token   string
+		comment domain.Comment
+		want    bool
+	}{
+		{"guest sees approved", nil, "", approved, true},
+		{"guest does not see pending", nil, "", pending, false},
+		{"admin sees rejected", admin, "", rejected, true},
+		{"moderator sees pending", moderator, "", pending, true},
+		{"moderator sees rejected", moderator, "", rejected, true},
+		{"author sees own pending", author, "", pending, true},
+		{"author does not see own rejected", author, "", rejected, false},
+		{"other user does not see pending", other, "", pending, false},
+		{"other user sees approved", other, "", approved, true},
+		{"guest with valid token sees pending", nil, token, guestPending, true},
+		{"guest with wrong token", nil, "ct_wrong", guestPending, false},
+		{"guest with valid token does not see rejected", nil, token, guestRejected, false},
+		{"guest with revoked token", nil, token, guestRevoked, false},
+	}
+
+	p := newTestPolicy(15 * time.Minute)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.CanView(callerContext(tt.user, tt.token), tt.comment); got != tt.want {
+				t.Errorf("CanView() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
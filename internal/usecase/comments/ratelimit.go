@@ -0,0 +1,76 @@
+package comments_usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+// RateLimits limits how fast comments are posted. A nil Store or a zero
+// limit switches the respective check off.
+type RateLimits struct {
+	Store limiter
+	// PerUser limits the comments of an authenticated user on a site.
+	PerUser domain.RateLimit
+	// PerThread limits the comments of everyone in a thread.
+	PerThread domain.RateLimit
+}
+
+// RateLimitError is an ErrRateLimited that carries the state of the limit
+// that was hit.
+type RateLimitError struct {
+	Scope  string
+	Status domain.RateLimitStatus
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: too many comments per %s, retry in %s", ErrRateLimited, e.Scope, e.Status.RetryAfter.Round(time.Second))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// checkRateLimits counts a new comment against the limits of its author and
+// thread. Comments are let through when the store fails, so an outage of the
+// store does not stop the comments.
+func (u *CommentsUsecase) checkRateLimits(ctx context.Context, comment domain.Comment) error {
+	if u.limits.Store == nil {
+		return nil
+	}
+
+	site := 0
+	if s, ok := domain.SiteFromContext(ctx); ok {
+		site = s.ID
+	}
+
+	if comment.UserID != nil {
+		key := "comments:user:" + strconv.Itoa(*comment.UserID)
+		if err := u.takeRateLimit(ctx, "user", key, u.limits.PerUser); err != nil {
+			return err
+		}
+	}
+
+	key := "comments:thread:" + strconv.Itoa(site) + ":" + comment.ThreadKey
+	return u.takeRateLimit(ctx, "thread", key, u.limits.PerThread)
+}
+
+func (u *CommentsUsecase) takeRateLimit(ctx context.Context, scope, key string, limit domain.RateLimit) error {
+	if limit.IsZero() {
+		return nil
+	}
+
+	status, err := u.limits.Store.Take(ctx, key, limit)
+	if err != nil {
+		u.logger.Warn().Err(err).Str("key", key).Msg("Rate limit store failed, letting the comment through")
+		return nil
+	}
+	if !status.Allowed {
+		return &RateLimitError{Scope: scope, Status: status}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package notifications_usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"comments-system/internal/domain"
+)
+
+const emailTokenSize = 24
+
+// newEmailToken returns a token confirming an email address and the hash to
+// store.
+func newEmailToken() (string, string, error) {
+	buf := make([]byte, emailTokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate email token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashEmailToken(token), nil
+}
+
+func hashEmailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sendEmailToken emails token to the address to confirm it.
+func (u *NotificationsUsecase) sendEmailToken(ctx context.Context, to, token string) error {
+	subject := "Подтвердите адрес для уведомлений"
+	if site, ok := domain.SiteFromContext(ctx); ok && site.Name != "" {
+		subject = "[" + site.Name + "] " + subject
+	}
+
+	var b strings.Builder
+	if user, ok := domain.UserFromContext(ctx); ok {
+		fmt.Fprintf(&b, "Здравствуйте, %s!\n\n", user.Username)
+	}
+	b.WriteString("Этот адрес указан для уведомлений о комментариях. Чтобы получать их по почте, подтвердите адрес кодом:\n\n")
+	b.WriteString(token + "\n\n")
+	fmt.Fprintf(&b, "Код действует %d часа. Если вы не указывали этот адрес, просто проигнорируйте письмо.\n", int(emailTokenTTL.Hours()))
+
+	return u.mailer.Send(ctx, to, subject, b.String())
+}
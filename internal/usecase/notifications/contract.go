@@ -0,0 +1,20 @@
+package notifications_usecase
+
+import (
+	"context"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+type notificationsRepo interface {
+	List(ctx context.Context, userID int, unreadOnly bool, limit, offset int) ([]domain.Notification, int, int, error)
+	MarkRead(ctx context.Context, userID int, ids []int64, all bool) (int, error)
+	GetPreferences(ctx context.Context, userID int) (domain.NotificationPreferences, bool, error)
+	SavePreferences(ctx context.Context, prefs domain.NotificationPreferences) (domain.NotificationPreferences, error)
+	ConfirmEmail(ctx context.Context, userID int, tokenHash string, validFor time.Duration) (domain.NotificationPreferences, bool, error)
+}
+
+type mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
@@ -0,0 +1,18 @@
+package notifications_usecase
+
+import "errors"
+
+var (
+	ErrAuthenticationRequired = errors.New("authentication required")
+	ErrInvalidNotificationID  = errors.New("invalid notification ID")
+	ErrNothingToMark          = errors.New("give notification ids or all")
+	ErrTooManyIDs             = errors.New("too many notification ids")
+	ErrInvalidEmail           = errors.New("invalid email address")
+	ErrInvalidEmailMode       = errors.New("email mode must be off, instant or digest")
+	ErrEmailRequired          = errors.New("email address is required to get notifications by email")
+	ErrEmailUnavailable       = errors.New("notifications by email are not available")
+	ErrEmailNotConfirmed      = errors.New("email address must be confirmed to get notifications by email")
+	ErrInvalidEmailToken      = errors.New("invalid or expired confirmation token")
+	ErrEmailTokenTooSoon      = errors.New("confirmation email was sent recently, try again later")
+	ErrEmailTokenNotSent      = errors.New("failed to send confirmation email")
+)
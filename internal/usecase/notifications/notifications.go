@@ -0,0 +1,235 @@
+package notifications_usecase
+
+import (
+	"context"
+	"net/mail"
+	"strings"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+const (
+	maxEmailLength = 254
+	maxMarkBatch   = 100
+	// emailTokenTTL is how long a confirmation token sent to an address
+	// stays valid.
+	emailTokenTTL = 24 * time.Hour
+	// emailTokenInterval is how long to wait before sending another token.
+	emailTokenInterval = time.Minute
+)
+
+// NotificationsUsecase serves the notification inbox of the authenticated
+// user and their preferences.
+type NotificationsUsecase struct {
+	repo         notificationsRepo
+	mailer       mailer
+	emailEnabled bool
+	logger       *zlog.Zerolog
+}
+
+// NewNotificationsUsecase creates the usecase. emailEnabled tells whether an
+// email channel is configured; without one users cannot ask for email.
+// mailer sends the tokens confirming email addresses.
+func NewNotificationsUsecase(repo notificationsRepo, mailer mailer, emailEnabled bool, logger *zlog.Zerolog) *NotificationsUsecase {
+	return &NotificationsUsecase{
+		repo:         repo,
+		mailer:       mailer,
+		emailEnabled: emailEnabled,
+		logger:       logger,
+	}
+}
+
+// ListNotifications returns a page of the notifications of the user of ctx,
+// newest first, or only the unread ones.
+func (u *NotificationsUsecase) ListNotifications(ctx context.Context, unreadOnly bool, page, pageSize int) (domain.NotificationInbox, error) {
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return domain.NotificationInbox{}, ErrAuthenticationRequired
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	notifications, total, unread, err := u.repo.List(ctx, user.ID, unreadOnly, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return domain.NotificationInbox{}, err
+	}
+
+	return domain.NotificationInbox{
+		Notifications: notifications,
+		UnreadOnly:    unreadOnly,
+		Unread:        unread,
+		Total:         total,
+		Page:          page,
+		PageSize:      pageSize,
+	}, nil
+}
+
+// MarkRead marks the given notifications of the user of ctx read, or all of
+// them, and returns how many were unread.
+func (u *NotificationsUsecase) MarkRead(ctx context.Context, ids []int64, all bool) (int, error) {
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return 0, ErrAuthenticationRequired
+	}
+
+	if !all {
+		if len(ids) == 0 {
+			return 0, ErrNothingToMark
+		}
+		if len(ids) > maxMarkBatch {
+			return 0, ErrTooManyIDs
+		}
+		for _, id := range ids {
+			if id <= 0 {
+				return 0, ErrInvalidNotificationID
+			}
+		}
+	}
+
+	return u.repo.MarkRead(ctx, user.ID, ids, all)
+}
+
+// GetPreferences returns the notification preferences of the user of ctx.
+func (u *NotificationsUsecase) GetPreferences(ctx context.Context) (domain.NotificationPreferences, error) {
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return domain.NotificationPreferences{}, ErrAuthenticationRequired
+	}
+
+	prefs, found, err := u.repo.GetPreferences(ctx, user.ID)
+	if err != nil {
+		return domain.NotificationPreferences{}, err
+	}
+	if !found {
+		return domain.DefaultNotificationPreferences(user.ID), nil
+	}
+
+	return prefs, nil
+}
+
+// UpdatePreferences changes the notification preferences of the user of
+// ctx. Email needs a confirmed address and a configured email channel. A new
+// address, or an unconfirmed one given again, is sent a confirmation token;
+// changing the address turns email off unless the mode is given too.
+func (u *NotificationsUsecase) UpdatePreferences(ctx context.Context, upd domain.NotificationPreferencesUpdate) (domain.NotificationPreferences, error) {
+	prefs, err := u.GetPreferences(ctx)
+	if err != nil {
+		return domain.NotificationPreferences{}, err
+	}
+
+	if upd.Replies != nil {
+		prefs.Replies = *upd.Replies
+	}
+	if upd.Mentions != nil {
+		prefs.Mentions = *upd.Mentions
+	}
+	sendToken := false
+	if upd.Email != nil {
+		email := strings.TrimSpace(*upd.Email)
+		if email != prefs.Email {
+			prefs.Email = email
+			prefs.EmailConfirmed = false
+			prefs.EmailTokenHash = ""
+			prefs.EmailMode = domain.EmailOff
+		}
+		sendToken = prefs.Email != "" && !prefs.EmailConfirmed
+	}
+	if upd.EmailMode != nil {
+		prefs.EmailMode = *upd.EmailMode
+	}
+
+	if err := u.validatePreferences(prefs); err != nil {
+		return domain.NotificationPreferences{}, err
+	}
+	if sendToken && !u.emailEnabled {
+		return domain.NotificationPreferences{}, ErrEmailUnavailable
+	}
+
+	var token string
+	if sendToken {
+		// The time of the last token is kept across addresses, so changing
+		// them cannot send tokens any faster.
+		if prefs.EmailTokenSentAt != nil && time.Since(*prefs.EmailTokenSentAt) < emailTokenInterval {
+			return domain.NotificationPreferences{}, ErrEmailTokenTooSoon
+		}
+		token, prefs.EmailTokenHash, err = newEmailToken()
+		if err != nil {
+			return domain.NotificationPreferences{}, err
+		}
+		now := time.Now()
+		prefs.EmailTokenSentAt = &now
+	}
+
+	saved, err := u.repo.SavePreferences(ctx, prefs)
+	if err != nil {
+		return domain.NotificationPreferences{}, err
+	}
+
+	if sendToken {
+		if err := u.sendEmailToken(ctx, saved.Email, token); err != nil {
+			u.logger.Warn().Err(err).Int("user_id", saved.UserID).Msg("Failed to send email confirmation")
+			return domain.NotificationPreferences{}, ErrEmailTokenNotSent
+		}
+	}
+
+	return saved, nil
+}
+
+// ConfirmEmail confirms the email address of the user of ctx with the token
+// sent to it.
+func (u *NotificationsUsecase) ConfirmEmail(ctx context.Context, token string) (domain.NotificationPreferences, error) {
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return domain.NotificationPreferences{}, ErrAuthenticationRequired
+	}
+	if token == "" {
+		return domain.NotificationPreferences{}, ErrInvalidEmailToken
+	}
+
+	prefs, ok, err := u.repo.ConfirmEmail(ctx, user.ID, hashEmailToken(token), emailTokenTTL)
+	if err != nil {
+		return domain.NotificationPreferences{}, err
+	}
+	if !ok {
+		return domain.NotificationPreferences{}, ErrInvalidEmailToken
+	}
+
+	return prefs, nil
+}
+
+func (u *NotificationsUsecase) validatePreferences(prefs domain.NotificationPreferences) error {
+	if !prefs.EmailMode.IsValid() {
+		return ErrInvalidEmailMode
+	}
+	if prefs.Email != "" {
+		// Only a bare address is taken, so it cannot carry a name or
+		// anything else into the headers of an email.
+		addr, err := mail.ParseAddress(prefs.Email)
+		if err != nil || addr.Address != prefs.Email || len(prefs.Email) > maxEmailLength {
+			return ErrInvalidEmail
+		}
+	}
+	if prefs.EmailMode != domain.EmailOff {
+		if !u.emailEnabled {
+			return ErrEmailUnavailable
+		}
+		if prefs.Email == "" {
+			return ErrEmailRequired
+		}
+		if !prefs.EmailConfirmed {
+			return ErrEmailNotConfirmed
+		}
+	}
+	return nil
+}
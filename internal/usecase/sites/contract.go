@@ -0,0 +1,18 @@
+package sites_usecase
+
+import (
+	"context"
+
+	"comments-system/internal/domain"
+)
+
+type sitesRepo interface {
+	Create(ctx context.Context, site domain.Site, apiKeyHash string) (domain.Site, error)
+	Exists(ctx context.Context, id int) (bool, error)
+	GetByID(ctx context.Context, id int) (domain.Site, error)
+	GetByAPIKeyHash(ctx context.Context, apiKeyHash string) (domain.Site, bool, error)
+	List(ctx context.Context) ([]domain.Site, error)
+	Update(ctx context.Context, id int, upd domain.SiteUpdate) (domain.Site, error)
+	SetAPIKeyHash(ctx context.Context, id int, apiKeyHash string) error
+	Delete(ctx context.Context, id int) error
+}
@@ -0,0 +1,16 @@
+package sites_usecase
+
+import "errors"
+
+var (
+	ErrInvalidSiteID         = errors.New("invalid site ID")
+	ErrSiteNotFound          = errors.New("site not found")
+	ErrInvalidAPIKey         = errors.New("invalid API key")
+	ErrNameRequired          = errors.New("site name is required")
+	ErrNameTooLong           = errors.New("site name is too long")
+	ErrInvalidOrigin         = errors.New("invalid origin")
+	ErrInvalidContentLimit   = errors.New("max content length must be between 1 and 10000")
+	ErrInvalidAuthorLimit    = errors.New("max author length must be between 1 and 50")
+	ErrInvalidModerationMode = errors.New("moderation mode must be none or pre")
+	ErrDefaultSite           = errors.New("the default site cannot be deleted")
+)
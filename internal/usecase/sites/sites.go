@@ -0,0 +1,232 @@
+package sites_usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+
+	"comments-system/internal/domain"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+const (
+	maxNameLength    = 100
+	maxContentLimit  = 10000
+	maxAuthorLimit   = 50
+	apiKeyPrefix     = "sk_"
+	apiKeyRandomSize = 32
+)
+
+type SitesUsecase struct {
+	repo   sitesRepo
+	logger *zlog.Zerolog
+}
+
+func NewSitesUsecase(repo sitesRepo, logger *zlog.Zerolog) *SitesUsecase {
+	return &SitesUsecase{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateSite registers a new site and returns it with its API key. Only the
+// hash of the key is stored, so this is the only time the key is shown.
+func (u *SitesUsecase) CreateSite(ctx context.Context, site domain.Site) (domain.Site, string, error) {
+	if site.MaxContentLength == 0 {
+		site.MaxContentLength = domain.DefaultMaxContentLength
+	}
+	if site.MaxAuthorLength == 0 {
+		site.MaxAuthorLength = domain.DefaultMaxAuthorLength
+	}
+	if site.ModerationMode == "" {
+		site.ModerationMode = domain.ModerationNone
+	}
+	if site.AllowedOrigins == nil {
+		site.AllowedOrigins = []string{}
+	}
+
+	if err := validateSite(site); err != nil {
+		return domain.Site{}, "", err
+	}
+
+	apiKey, err := generateAPIKey()
+	if err != nil {
+		return domain.Site{}, "", err
+	}
+
+	createdSite, err := u.repo.Create(ctx, site, hashAPIKey(apiKey))
+	if err != nil {
+		return domain.Site{}, "", err
+	}
+
+	return createdSite, apiKey, nil
+}
+
+func (u *SitesUsecase) ListSites(ctx context.Context) ([]domain.Site, error) {
+	return u.repo.List(ctx)
+}
+
+func (u *SitesUsecase) GetSite(ctx context.Context, id int) (domain.Site, error) {
+	if id <= 0 {
+		return domain.Site{}, ErrInvalidSiteID
+	}
+
+	if err := u.checkExists(ctx, id); err != nil {
+		return domain.Site{}, err
+	}
+
+	return u.repo.GetByID(ctx, id)
+}
+
+func (u *SitesUsecase) UpdateSite(ctx context.Context, id int, upd domain.SiteUpdate) (domain.Site, error) {
+	if id <= 0 {
+		return domain.Site{}, ErrInvalidSiteID
+	}
+
+	site, err := u.GetSite(ctx, id)
+	if err != nil {
+		return domain.Site{}, err
+	}
+
+	if upd.Name != nil {
+		site.Name = *upd.Name
+	}
+	if upd.AllowedOrigins != nil {
+		site.AllowedOrigins = *upd.AllowedOrigins
+	}
+	if upd.MaxContentLength != nil {
+		site.MaxContentLength = *upd.MaxContentLength
+	}
+	if upd.MaxAuthorLength != nil {
+		site.MaxAuthorLength = *upd.MaxAuthorLength
+	}
+	if upd.ModerationMode != nil {
+		site.ModerationMode = *upd.ModerationMode
+	}
+
+	if err := validateSite(site); err != nil {
+		return domain.Site{}, err
+	}
+
+	return u.repo.Update(ctx, id, upd)
+}
+
+// RotateAPIKey gives a site a new API key. The old key stops working at once.
+func (u *SitesUsecase) RotateAPIKey(ctx context.Context, id int) (string, error) {
+	if id <= 0 {
+		return "", ErrInvalidSiteID
+	}
+
+	if err := u.checkExists(ctx, id); err != nil {
+		return "", err
+	}
+
+	apiKey, err := generateAPIKey()
+	if err != nil {
+		return "", err
+	}
+
+	if err := u.repo.SetAPIKeyHash(ctx, id, hashAPIKey(apiKey)); err != nil {
+		return "", err
+	}
+
+	return apiKey, nil
+}
+
+// DeleteSite removes a site with all its comments. The default site stays.
+func (u *SitesUsecase) DeleteSite(ctx context.Context, id int) error {
+	if id <= 0 {
+		return ErrInvalidSiteID
+	}
+	if id == domain.DefaultSiteID {
+		return ErrDefaultSite
+	}
+
+	if err := u.checkExists(ctx, id); err != nil {
+		return err
+	}
+
+	return u.repo.Delete(ctx, id)
+}
+
+// ResolveSite returns the site an API key belongs to. Requests without a key
+// belong to the default site.
+func (u *SitesUsecase) ResolveSite(ctx context.Context, apiKey string) (domain.Site, error) {
+	if apiKey == "" {
+		return u.repo.GetByID(ctx, domain.DefaultSiteID)
+	}
+
+	site, found, err := u.repo.GetByAPIKeyHash(ctx, hashAPIKey(apiKey))
+	if err != nil {
+		return domain.Site{}, err
+	}
+	if !found {
+		return domain.Site{}, ErrInvalidAPIKey
+	}
+
+	return site, nil
+}
+
+func (u *SitesUsecase) checkExists(ctx context.Context, id int) error {
+	exists, err := u.repo.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrSiteNotFound
+	}
+	return nil
+}
+
+func validateSite(site domain.Site) error {
+	if site.Name == "" {
+		return ErrNameRequired
+	}
+	if len(site.Name) > maxNameLength {
+		return ErrNameTooLong
+	}
+	if site.MaxContentLength < 1 || site.MaxContentLength > maxContentLimit {
+		return ErrInvalidContentLimit
+	}
+	if site.MaxAuthorLength < 1 || site.MaxAuthorLength > maxAuthorLimit {
+		return ErrInvalidAuthorLimit
+	}
+	if site.ModerationMode != domain.ModerationNone && site.ModerationMode != domain.ModerationPre {
+		return ErrInvalidModerationMode
+	}
+	for _, origin := range site.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateOrigin accepts origins the way browsers send them in the Origin
+// header: a scheme and a host with an optional port, nothing else.
+func validateOrigin(origin string) error {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidOrigin, origin)
+	}
+	return nil
+}
+
+func generateAPIKey() (string, error) {
+	buf := make([]byte, apiKeyRandomSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,19 @@
+package users_usecase
+
+import (
+	"context"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+type usersRepo interface {
+	Create(ctx context.Context, username, passwordHash string) (domain.User, bool, error)
+	GetByID(ctx context.Context, id int) (domain.User, bool, error)
+	GetCredentials(ctx context.Context, username string) (domain.User, string, bool, error)
+	CreateSession(ctx context.Context, tokenHash string, userID int, expiresAt time.Time) error
+	GetSessionUser(ctx context.Context, tokenHash string) (domain.User, bool, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+	List(ctx context.Context) ([]domain.User, error)
+	SetRole(ctx context.Context, id int, role domain.Role) (domain.User, bool, error)
+}
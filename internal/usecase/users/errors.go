@@ -0,0 +1,16 @@
+package users_usecase
+
+import "errors"
+
+var (
+	ErrInvalidUsername    = errors.New("username must be 2 or more letters, digits or . _ - and fit the site author length")
+	ErrPasswordTooShort   = errors.New("password must be at least 8 characters")
+	ErrPasswordTooLong    = errors.New("password must be at most 72 bytes")
+	ErrUsernameTaken      = errors.New("username is already taken")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidSession     = errors.New("invalid or expired session")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrInvalidUserID      = errors.New("invalid user ID")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidRole        = errors.New("role must be user, moderator or admin")
+)
@@ -0,0 +1,233 @@
+package users_usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/wb-go/wbf/zlog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minUsernameLength = 2
+	minPasswordLength = 8
+	// maxPasswordLength is the most bcrypt takes into account.
+	maxPasswordLength = 72
+	sessionTokenSize  = 32
+)
+
+var usernamePattern = regexp.MustCompile(`^[\p{L}\p{N}._-]+$`)
+
+// dummyHash is compared against on logins with an unknown username so they
+// take as long as logins with a wrong password.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+type UsersUsecase struct {
+	repo       usersRepo
+	jwtSecret  []byte
+	tokenTTL   time.Duration
+	sessionTTL time.Duration
+	logger     *zlog.Zerolog
+}
+
+// NewUsersUsecase creates the usecase. jwtSecret signs the access tokens of
+// API clients; tokenTTL and sessionTTL are the lifetimes of access tokens
+// and of web interface sessions.
+func NewUsersUsecase(repo usersRepo, jwtSecret []byte, tokenTTL, sessionTTL time.Duration, logger *zlog.Zerolog) *UsersUsecase {
+	return &UsersUsecase{
+		repo:       repo,
+		jwtSecret:  jwtSecret,
+		tokenTTL:   tokenTTL,
+		sessionTTL: sessionTTL,
+		logger:     logger,
+	}
+}
+
+// Register creates a user on the site of ctx. The username becomes the
+// author name of the user's comments, so it obeys the author length limit
+// of the site.
+func (u *UsersUsecase) Register(ctx context.Context, username, password string) (domain.User, error) {
+	maxUsernameLength := domain.DefaultMaxAuthorLength
+	if site, ok := domain.SiteFromContext(ctx); ok {
+		maxUsernameLength = site.MaxAuthorLength
+	}
+
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength || !usernamePattern.MatchString(username) {
+		return domain.User{}, ErrInvalidUsername
+	}
+	if len(password) < minPasswordLength {
+		return domain.User{}, ErrPasswordTooShort
+	}
+	if len(password) > maxPasswordLength {
+		return domain.User{}, ErrPasswordTooLong
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user, created, err := u.repo.Create(ctx, username, string(passwordHash))
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !created {
+		return domain.User{}, ErrUsernameTaken
+	}
+
+	return user, nil
+}
+
+// Login checks the password of a user of the site of ctx.
+func (u *UsersUsecase) Login(ctx context.Context, username, password string) (domain.User, error) {
+	user, passwordHash, found, err := u.repo.GetCredentials(ctx, username)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !found {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return domain.User{}, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
+		return domain.User{}, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// CreateSession starts a web interface session of a user and returns its
+// token. Only the hash of the token is stored.
+func (u *UsersUsecase) CreateSession(ctx context.Context, user domain.User) (string, time.Time, error) {
+	buf := make([]byte, sessionTokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	expiresAt := time.Now().Add(u.sessionTTL)
+
+	if err := u.repo.CreateSession(ctx, hashToken(token), user.ID, expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// AuthenticateSession returns the user of a session token.
+func (u *UsersUsecase) AuthenticateSession(ctx context.Context, token string) (domain.User, error) {
+	user, found, err := u.repo.GetSessionUser(ctx, hashToken(token))
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !found {
+		return domain.User{}, ErrInvalidSession
+	}
+
+	return user, nil
+}
+
+// Logout ends the session of a token.
+func (u *UsersUsecase) Logout(ctx context.Context, token string) error {
+	return u.repo.DeleteSession(ctx, hashToken(token))
+}
+
+// IssueToken returns a signed access token of a user for API clients. The
+// token is bound to the site of the user.
+func (u *UsersUsecase) IssueToken(user domain.User) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(u.tokenTTL)
+
+	claims := jwt.RegisteredClaims{
+		Subject:   strconv.Itoa(user.ID),
+		Audience:  jwt.ClaimStrings{siteAudience(user.SiteID)},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(u.jwtSecret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return token, expiresAt, nil
+}
+
+// AuthenticateToken returns the user of an access token. Tokens issued for
+// another site are rejected.
+func (u *UsersUsecase) AuthenticateToken(ctx context.Context, token string) (domain.User, error) {
+	siteID := domain.DefaultSiteID
+	if site, ok := domain.SiteFromContext(ctx); ok {
+		siteID = site.ID
+	}
+
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return u.jwtSecret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(siteAudience(siteID)),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return domain.User{}, ErrInvalidToken
+	}
+
+	user, found, err := u.repo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !found {
+		return domain.User{}, ErrInvalidToken
+	}
+
+	return user, nil
+}
+
+// ListUsers returns the users of the site of ctx.
+func (u *UsersUsecase) ListUsers(ctx context.Context) ([]domain.User, error) {
+	return u.repo.List(ctx)
+}
+
+// SetRole changes the role of a user of the site of ctx.
+func (u *UsersUsecase) SetRole(ctx context.Context, id int, role domain.Role) (domain.User, error) {
+	if id <= 0 {
+		return domain.User{}, ErrInvalidUserID
+	}
+	if !role.IsValid() {
+		return domain.User{}, ErrInvalidRole
+	}
+
+	user, found, err := u.repo.SetRole(ctx, id, role)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !found {
+		return domain.User{}, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func siteAudience(siteID int) string {
+	return "site:" + strconv.Itoa(siteID)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,19 @@
+package webhooks_usecase
+
+import (
+	"context"
+
+	"comments-system/internal/domain"
+)
+
+type webhooksRepo interface {
+	Create(ctx context.Context, hook domain.Webhook) (domain.Webhook, error)
+	Exists(ctx context.Context, id int) (bool, error)
+	GetByID(ctx context.Context, id int) (domain.Webhook, error)
+	List(ctx context.Context) ([]domain.Webhook, error)
+	Update(ctx context.Context, id int, upd domain.WebhookUpdate) (domain.Webhook, error)
+	SetSecret(ctx context.Context, id int, secret string) error
+	Delete(ctx context.Context, id int) error
+	ListDeliveries(ctx context.Context, webhookID int, status domain.DeliveryStatus, limit, offset int) ([]domain.WebhookDelivery, int, error)
+	Redeliver(ctx context.Context, webhookID int, deliveryID int64) (bool, error)
+}
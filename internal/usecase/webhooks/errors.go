@@ -0,0 +1,15 @@
+package webhooks_usecase
+
+import "errors"
+
+var (
+	ErrInvalidWebhookID      = errors.New("invalid webhook ID")
+	ErrWebhookNotFound       = errors.New("webhook not found")
+	ErrInvalidURL            = errors.New("webhook URL must be an absolute http or https URL")
+	ErrURLTooLong            = errors.New("webhook URL is too long")
+	ErrInvalidEvent          = errors.New("invalid event type")
+	ErrInvalidSecret         = errors.New("webhook secret must be between 16 and 100 characters")
+	ErrInvalidDeliveryID     = errors.New("invalid delivery ID")
+	ErrDeliveryNotFound      = errors.New("delivery not found")
+	ErrInvalidDeliveryStatus = errors.New("delivery status must be pending, delivered or dead")
+)
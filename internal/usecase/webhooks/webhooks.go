@@ -0,0 +1,233 @@
+package webhooks_usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+
+	"comments-system/internal/domain"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+const (
+	maxURLLength     = 2000
+	minSecretLength  = 16
+	maxSecretLength  = 100
+	secretPrefix     = "whsec_"
+	secretRandomSize = 32
+)
+
+type WebhooksUsecase struct {
+	repo   webhooksRepo
+	logger *zlog.Zerolog
+}
+
+func NewWebhooksUsecase(repo webhooksRepo, logger *zlog.Zerolog) *WebhooksUsecase {
+	return &WebhooksUsecase{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateWebhook subscribes an endpoint to the comment events of the site of
+// ctx. A secret is generated unless one is given; the returned webhook
+// carries it so it can be handed to the receiver.
+func (u *WebhooksUsecase) CreateWebhook(ctx context.Context, hook domain.Webhook) (domain.Webhook, error) {
+	if hook.Events == nil {
+		hook.Events = []domain.EventType{}
+	}
+
+	if err := validateWebhook(hook); err != nil {
+		return domain.Webhook{}, err
+	}
+
+	if hook.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return domain.Webhook{}, err
+		}
+		hook.Secret = secret
+	} else if err := validateSecret(hook.Secret); err != nil {
+		return domain.Webhook{}, err
+	}
+
+	return u.repo.Create(ctx, hook)
+}
+
+func (u *WebhooksUsecase) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	return u.repo.List(ctx)
+}
+
+func (u *WebhooksUsecase) GetWebhook(ctx context.Context, id int) (domain.Webhook, error) {
+	if id <= 0 {
+		return domain.Webhook{}, ErrInvalidWebhookID
+	}
+
+	if err := u.checkExists(ctx, id); err != nil {
+		return domain.Webhook{}, err
+	}
+
+	return u.repo.GetByID(ctx, id)
+}
+
+func (u *WebhooksUsecase) UpdateWebhook(ctx context.Context, id int, upd domain.WebhookUpdate) (domain.Webhook, error) {
+	hook, err := u.GetWebhook(ctx, id)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+
+	if upd.URL != nil {
+		hook.URL = *upd.URL
+	}
+	if upd.Events != nil {
+		hook.Events = *upd.Events
+	}
+
+	if err := validateWebhook(hook); err != nil {
+		return domain.Webhook{}, err
+	}
+
+	return u.repo.Update(ctx, id, upd)
+}
+
+// RotateSecret gives a webhook a new generated secret and returns it.
+// Deliveries are signed with the new secret from then on, including retries
+// of earlier ones.
+func (u *WebhooksUsecase) RotateSecret(ctx context.Context, id int) (string, error) {
+	if id <= 0 {
+		return "", ErrInvalidWebhookID
+	}
+
+	if err := u.checkExists(ctx, id); err != nil {
+		return "", err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	if err := u.repo.SetSecret(ctx, id, secret); err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// DeleteWebhook removes a webhook; its pending deliveries are dropped.
+func (u *WebhooksUsecase) DeleteWebhook(ctx context.Context, id int) error {
+	if id <= 0 {
+		return ErrInvalidWebhookID
+	}
+
+	if err := u.checkExists(ctx, id); err != nil {
+		return err
+	}
+
+	return u.repo.Delete(ctx, id)
+}
+
+// ListDeliveries returns a page of the delivery log of a webhook, newest
+// first. An empty status lists deliveries in any status.
+func (u *WebhooksUsecase) ListDeliveries(ctx context.Context, id int, status domain.DeliveryStatus, page, pageSize int) (domain.DeliveryLog, error) {
+	if id <= 0 {
+		return domain.DeliveryLog{}, ErrInvalidWebhookID
+	}
+	if status != "" && !status.IsValid() {
+		return domain.DeliveryLog{}, ErrInvalidDeliveryStatus
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	if err := u.checkExists(ctx, id); err != nil {
+		return domain.DeliveryLog{}, err
+	}
+
+	deliveries, total, err := u.repo.ListDeliveries(ctx, id, status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return domain.DeliveryLog{}, err
+	}
+
+	return domain.DeliveryLog{
+		Deliveries: deliveries,
+		Status:     status,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+	}, nil
+}
+
+// Redeliver schedules a delivery of a webhook to be sent again right away,
+// with all its attempts, e.g. a dead one after the receiver is fixed.
+func (u *WebhooksUsecase) Redeliver(ctx context.Context, id int, deliveryID int64) error {
+	if id <= 0 {
+		return ErrInvalidWebhookID
+	}
+	if deliveryID <= 0 {
+		return ErrInvalidDeliveryID
+	}
+
+	found, err := u.repo.Redeliver(ctx, id, deliveryID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrDeliveryNotFound
+	}
+
+	return nil
+}
+
+func (u *WebhooksUsecase) checkExists(ctx context.Context, id int) error {
+	exists, err := u.repo.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func validateWebhook(hook domain.Webhook) error {
+	if len(hook.URL) > maxURLLength {
+		return ErrURLTooLong
+	}
+	// Credentials in the URL would end up in logs; receivers check the
+	// signature instead.
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+	for _, event := range hook.Events {
+		if !event.IsValid() {
+			return fmt.Errorf("%w: %s", ErrInvalidEvent, event)
+		}
+	}
+	return nil
+}
+
+func validateSecret(secret string) error {
+	if len(secret) < minSecretLength || len(secret) > maxSecretLength {
+		return ErrInvalidSecret
+	}
+	return nil
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, secretRandomSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
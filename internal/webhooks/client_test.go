@@ -0,0 +1,63 @@
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		{"8.8.8.8", true},
+		{"93.184.216.34", true},
+		{"2606:4700:4700::1111", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := isPublic(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("isPublic(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	reached := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(time.Second, false).Get(srv.URL)
+	if !errors.Is(err, errPrivateAddress) {
+		t.Errorf("Get() error = %v, want %v", err, errPrivateAddress)
+	}
+	if reached {
+		t.Error("the request reached a loopback server")
+	}
+
+	resp, err := NewClient(time.Second, true).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() with private addresses allowed: %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,19 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"comments-system/internal/domain"
+)
+
+type enqueuer interface {
+	Enqueue(ctx context.Context, siteID int, eventType domain.EventType, eventKey string, payload []byte) (int, error)
+}
+
+type deliveryRepo interface {
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]domain.DueDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, statusCode int) error
+	MarkFailed(ctx context.Context, id int64, statusCode int, errText string, retryIn time.Duration) error
+	MarkDead(ctx context.Context, id int64, statusCode int, errText string) error
+}
@@ -0,0 +1,35 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"comments-system/internal/domain"
+	"comments-system/internal/events/message"
+)
+
+// Dispatcher turns comment events into deliveries of the webhooks that
+// want them.
+type Dispatcher struct {
+	repo enqueuer
+}
+
+func NewDispatcher(repo enqueuer) *Dispatcher {
+	return &Dispatcher{repo: repo}
+}
+
+// Publish schedules the deliveries of an event. An event published again is
+// not delivered twice.
+func (d *Dispatcher) Publish(ctx context.Context, event domain.CommentEvent) error {
+	body, err := json.Marshal(message.FromDomainEvent(event))
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	if _, err := d.repo.Enqueue(ctx, event.SiteID, event.Type, event.Key, body); err != nil {
+		return err
+	}
+
+	return nil
+}
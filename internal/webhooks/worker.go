@@ -0,0 +1,177 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/wb-go/wbf/retry"
+	"github.com/wb-go/wbf/zlog"
+)
+
+const (
+	// leaseMargin is how much longer than the request timeout a claimed
+	// delivery is kept from other workers.
+	leaseMargin = 30 * time.Second
+	// maxRetryDelay caps the exponential delay between attempts.
+	maxRetryDelay = 24 * time.Hour
+	// maxErrorBody is how much of an error response is kept in the log.
+	maxErrorBody = 512
+)
+
+// Worker sends the due deliveries of webhooks. A delivery is retried with
+// growing delays following retries until it is accepted with a 2xx response
+// or runs out of attempts and is left dead.
+type Worker struct {
+	repo     deliveryRepo
+	client   *http.Client
+	retries  retry.Strategy
+	interval time.Duration
+	batch    int
+	logger   *zlog.Zerolog
+}
+
+// NewWorker creates a worker that looks for due deliveries every interval
+// and sends up to batch of them at once.
+func NewWorker(repo deliveryRepo, client *http.Client, retries retry.Strategy, interval time.Duration, batch int, logger *zlog.Zerolog) *Worker {
+	return &Worker{
+		repo:     repo,
+		client:   client,
+		retries:  retries,
+		interval: interval,
+		batch:    batch,
+		logger:   logger,
+	}
+}
+
+// Run sends deliveries until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		// A full batch means more deliveries may be due right away.
+		if w.sendDue(ctx) == w.batch && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDue claims a batch of due deliveries, sends them and returns how many
+// there were.
+func (w *Worker) sendDue(ctx context.Context) int {
+	due, err := w.repo.ClaimDue(ctx, w.batch, w.client.Timeout+leaseMargin)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Error().Err(err).Msg("Failed to claim webhook deliveries")
+		}
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.deliver(ctx, d)
+		}()
+	}
+	wg.Wait()
+
+	return len(due)
+}
+
+func (w *Worker) deliver(ctx context.Context, d domain.DueDelivery) {
+	statusCode, err := w.send(ctx, d)
+	if ctx.Err() != nil {
+		// The attempt was cut short by shutdown; the delivery is picked up
+		// again when its lease runs out.
+		return
+	}
+
+	// The outcome is recorded even if ctx ends meanwhile.
+	markCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	log := w.logger.With().
+		Int64("delivery_id", d.Delivery.ID).
+		Int("webhook_id", d.Delivery.WebhookID).
+		Str("event", string(d.Delivery.EventType)).
+		Logger()
+
+	if err == nil {
+		if err := w.repo.MarkDelivered(markCtx, d.Delivery.ID, statusCode); err != nil {
+			log.Error().Err(err).Msg("Failed to record webhook delivery")
+		}
+		return
+	}
+
+	attempt := d.Delivery.Attempts + 1
+	if attempt >= w.retries.Attempts {
+		log.Warn().Err(err).Int("attempts", attempt).Msg("Webhook delivery failed for the last time")
+		if err := w.repo.MarkDead(markCtx, d.Delivery.ID, statusCode, err.Error()); err != nil {
+			log.Error().Err(err).Msg("Failed to record webhook delivery")
+		}
+		return
+	}
+
+	retryIn := w.retryDelay(attempt)
+	log.Info().Err(err).Int("attempts", attempt).Dur("retry_in", retryIn).Msg("Webhook delivery failed")
+	if err := w.repo.MarkFailed(markCtx, d.Delivery.ID, statusCode, err.Error(), retryIn); err != nil {
+		log.Error().Err(err).Msg("Failed to record webhook delivery")
+	}
+}
+
+// send posts a delivery to its webhook and returns the response status, 0 if
+// there was none. Any status but 2xx is an error.
+func (w *Worker) send(ctx context.Context, d domain.DueDelivery) (int, error) {
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "comments-system-webhooks")
+	req.Header.Set(HeaderEvent, string(d.Delivery.EventType))
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.Delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(d.Secret, timestamp, d.Delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return resp.StatusCode, nil
+}
+
+// retryDelay is the delay after the given failed attempt: the delay of the
+// strategy, multiplied by its backoff for every attempt before.
+func (w *Worker) retryDelay(attempt int) time.Duration {
+	delay := float64(w.retries.Delay) * math.Pow(w.retries.Backoff, float64(attempt-1))
+	if delay > float64(maxRetryDelay) {
+		return maxRetryDelay
+	}
+	return time.Duration(delay)
+}
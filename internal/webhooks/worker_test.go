@@ -0,0 +1,212 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"comments-system/internal/domain"
+
+	"github.com/rs/zerolog"
+	"github.com/wb-go/wbf/retry"
+)
+
+const testSecret = "whsec_test"
+
+// fakeDeliveries hands out its due deliveries once and records the outcome
+// of each.
+type fakeDeliveries struct {
+	mu       sync.Mutex
+	due      []domain.DueDelivery
+	outcomes map[int64]outcome
+}
+
+type outcome struct {
+	status     string
+	statusCode int
+	errText    string
+	retryIn    time.Duration
+}
+
+func (f *fakeDeliveries) ClaimDue(_ context.Context, limit int, _ time.Duration) ([]domain.DueDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := min(limit, len(f.due))
+	due := f.due[:n]
+	f.due = f.due[n:]
+	return due, nil
+}
+
+func (f *fakeDeliveries) MarkDelivered(_ context.Context, id int64, statusCode int) error {
+	f.record(id, outcome{status: "delivered", statusCode: statusCode})
+	return nil
+}
+
+func (f *fakeDeliveries) MarkFailed(_ context.Context, id int64, statusCode int, errText string, retryIn time.Duration) error {
+	f.record(id, outcome{status: "failed", statusCode: statusCode, errText: errText, retryIn: retryIn})
+	return nil
+}
+
+func (f *fakeDeliveries) MarkDead(_ context.Context, id int64, statusCode int, errText string) error {
+	f.record(id, outcome{status: "dead", statusCode: statusCode, errText: errText})
+	return nil
+}
+
+func (f *fakeDeliveries) record(id int64, o outcome) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.outcomes == nil {
+		f.outcomes = make(map[int64]outcome)
+	}
+	f.outcomes[id] = o
+}
+
+var testRetries = retry.Strategy{Attempts: 5, Delay: time.Second, Backoff: 2}
+
+func newTestWorker(repo deliveryRepo) *Worker {
+	logger := zerolog.Nop()
+	return NewWorker(repo, NewClient(5*time.Second, true), testRetries, time.Minute, 10, &logger)
+}
+
+func dueDelivery(id int64, attempts int, url string) domain.DueDelivery {
+	return domain.DueDelivery{
+		Delivery: domain.WebhookDelivery{
+			ID:        id,
+			WebhookID: 1,
+			EventType: domain.EventCommentCreated,
+			Payload:   []byte(`{"id":"evt","type":"comment.created"}`),
+			Attempts:  attempts,
+		},
+		URL:    url,
+		Secret: testSecret,
+	}
+}
+
+func TestWorkerSignsDelivery(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d := dueDelivery(7, 0, srv.URL)
+	repo := &fakeDeliveries{due: []domain.DueDelivery{d}}
+	if n := newTestWorker(repo).sendDue(context.Background()); n != 1 {
+		t.Fatalf("sendDue() = %d, want 1", n)
+	}
+
+	if got == nil {
+		t.Fatal("the receiver got no request")
+	}
+	if string(body) != string(d.Delivery.Payload) {
+		t.Errorf("body = %s, want %s", body, d.Delivery.Payload)
+	}
+	if ct := got.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	if ev := got.Header.Get(HeaderEvent); ev != string(domain.EventCommentCreated) {
+		t.Errorf("%s = %q, want %q", HeaderEvent, ev, domain.EventCommentCreated)
+	}
+	if id := got.Header.Get(HeaderDelivery); id != "7" {
+		t.Errorf("%s = %q, want 7", HeaderDelivery, id)
+	}
+	timestamp, err := strconv.ParseInt(got.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("%s: %v", HeaderTimestamp, err)
+	}
+	if !Verify(testSecret, timestamp, body, got.Header.Get(HeaderSignature)) {
+		t.Errorf("signature %q does not verify", got.Header.Get(HeaderSignature))
+	}
+	if Verify("other secret", timestamp, body, got.Header.Get(HeaderSignature)) {
+		t.Error("signature verifies with another secret")
+	}
+	if Verify(testSecret, timestamp+1, body, got.Header.Get(HeaderSignature)) {
+		t.Error("signature verifies with another timestamp")
+	}
+
+	if o := repo.outcomes[7]; o.status != "delivered" || o.statusCode != http.StatusNoContent {
+		t.Errorf("outcome = %+v, want delivered with 204", o)
+	}
+}
+
+func TestWorkerRecordsFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "try later", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		attempts int
+		want     outcome
+	}{
+		{"first attempt", 0, outcome{status: "failed", statusCode: 503, retryIn: time.Second}},
+		{"third attempt", 2, outcome{status: "failed", statusCode: 503, retryIn: 4 * time.Second}},
+		{"last attempt", testRetries.Attempts - 1, outcome{status: "dead", statusCode: 503}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeDeliveries{due: []domain.DueDelivery{dueDelivery(1, tt.attempts, srv.URL)}}
+			newTestWorker(repo).sendDue(context.Background())
+
+			got := repo.outcomes[1]
+			if got.status != tt.want.status || got.statusCode != tt.want.statusCode || got.retryIn != tt.want.retryIn {
+				t.Errorf("outcome = %+v, want %+v", got, tt.want)
+			}
+			if !strings.Contains(got.errText, "try later") {
+				t.Errorf("error %q does not quote the response", got.errText)
+			}
+		})
+	}
+}
+
+func TestWorkerDoesNotFollowRedirects(t *testing.T) {
+	redirected := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	})
+	mux.HandleFunc("/elsewhere", func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	repo := &fakeDeliveries{due: []domain.DueDelivery{dueDelivery(1, 0, srv.URL+"/hook")}}
+	newTestWorker(repo).sendDue(context.Background())
+
+	if redirected {
+		t.Error("the redirect was followed")
+	}
+	if got := repo.outcomes[1]; got.status != "failed" || got.statusCode != http.StatusFound {
+		t.Errorf("outcome = %+v, want failed with 302", got)
+	}
+}
+
+func TestWorkerRetryDelay(t *testing.T) {
+	w := newTestWorker(&fakeDeliveries{})
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{40, maxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := w.retryDelay(tt.attempt); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
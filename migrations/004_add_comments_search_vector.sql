@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The russian configuration stems Cyrillic words with the Russian stemmer and
+-- Latin words with the English one; the simple part keeps exact word forms so
+-- queries parsed with any of the supported configurations can match.
+ALTER TABLE comments ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (
+        to_tsvector('russian', content) || to_tsvector('simple', content)
+    ) STORED;
+
+CREATE INDEX idx_comments_search_vector ON comments USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_comments_search_vector;
+ALTER TABLE comments DROP COLUMN IF EXISTS search_vector;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- root_id points every reply at the root of its thread. Roots keep NULL.
+ALTER TABLE comments ADD COLUMN root_id INTEGER REFERENCES comments(id) ON DELETE CASCADE;
+
+WITH RECURSIVE threads AS (
+    SELECT id, id AS root_id FROM comments WHERE parent_id IS NULL
+
+    UNION ALL
+
+    SELECT c.id, t.root_id FROM comments c
+    INNER JOIN threads t ON c.parent_id = t.id
+)
+UPDATE comments c SET root_id = t.root_id
+FROM threads t
+WHERE c.id = t.id AND c.parent_id IS NOT NULL;
+
+CREATE INDEX idx_comments_root_id ON comments(root_id);
+CREATE INDEX idx_comments_author ON comments(author text_pattern_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_comments_author;
+DROP INDEX IF EXISTS idx_comments_root_id;
+ALTER TABLE comments DROP COLUMN IF EXISTS root_id;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE comment_votes (
+    comment_id INTEGER NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    voter VARCHAR(100) NOT NULL,
+    value SMALLINT NOT NULL CHECK (value IN (-1, 1)),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (comment_id, voter)
+);
+
+-- Vote totals are kept on the comment so listings can sort by them without
+-- aggregating comment_votes.
+ALTER TABLE comments
+    ADD COLUMN upvotes INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN downvotes INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_comments_score ON comments ((upvotes - downvotes));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_comments_score;
+ALTER TABLE comments DROP COLUMN IF EXISTS downvotes, DROP COLUMN IF EXISTS upvotes;
+DROP TABLE IF EXISTS comment_votes;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A thread groups the comments attached to one resource (an article, a
+-- product, a ticket). Threads are created on the first post.
+CREATE TABLE threads (
+    key VARCHAR(200) PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Comments written before threads existed stay in the default thread.
+INSERT INTO threads (key) VALUES ('default');
+
+ALTER TABLE comments ADD COLUMN thread_key VARCHAR(200) NOT NULL DEFAULT 'default'
+    REFERENCES threads(key) ON DELETE CASCADE;
+
+CREATE INDEX idx_comments_thread_key_parent_id ON comments(thread_key, parent_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_comments_thread_key_parent_id;
+ALTER TABLE comments DROP COLUMN IF EXISTS thread_key;
+DROP TABLE IF EXISTS threads;
+-- +goose StatementEnd
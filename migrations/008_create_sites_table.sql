@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE sites (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    api_key_hash CHAR(64) UNIQUE,
+    allowed_origins TEXT[] NOT NULL DEFAULT '{}',
+    max_content_length INTEGER NOT NULL DEFAULT 1000 CHECK (max_content_length BETWEEN 1 AND 10000),
+    max_author_length INTEGER NOT NULL DEFAULT 50 CHECK (max_author_length BETWEEN 1 AND 50),
+    moderation_mode VARCHAR(20) NOT NULL DEFAULT 'none' CHECK (moderation_mode IN ('none', 'pre')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The default site serves requests without an API key and owns everything
+-- written before sites existed.
+INSERT INTO sites (id, name) VALUES (1, 'default');
+SELECT setval('sites_id_seq', 1);
+
+ALTER TABLE comments ADD COLUMN site_id INTEGER NOT NULL DEFAULT 1 REFERENCES sites(id) ON DELETE CASCADE;
+ALTER TABLE threads ADD COLUMN site_id INTEGER NOT NULL DEFAULT 1 REFERENCES sites(id) ON DELETE CASCADE;
+
+-- Thread keys are unique within a site.
+ALTER TABLE comments DROP CONSTRAINT comments_thread_key_fkey;
+ALTER TABLE threads DROP CONSTRAINT threads_pkey;
+ALTER TABLE threads ADD PRIMARY KEY (site_id, key);
+ALTER TABLE comments ADD CONSTRAINT comments_thread_fkey
+    FOREIGN KEY (site_id, thread_key) REFERENCES threads(site_id, key) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS idx_comments_thread_key_parent_id;
+CREATE INDEX idx_comments_site_thread_parent ON comments(site_id, thread_key, parent_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_comments_site_thread_parent;
+ALTER TABLE comments DROP CONSTRAINT IF EXISTS comments_thread_fkey;
+DELETE FROM comments WHERE site_id <> 1;
+DELETE FROM threads WHERE site_id <> 1;
+ALTER TABLE threads DROP CONSTRAINT threads_pkey;
+ALTER TABLE threads ADD PRIMARY KEY (key);
+ALTER TABLE comments ADD CONSTRAINT comments_thread_key_fkey
+    FOREIGN KEY (thread_key) REFERENCES threads(key) ON DELETE CASCADE;
+CREATE INDEX idx_comments_thread_key_parent_id ON comments(thread_key, parent_id);
+ALTER TABLE threads DROP COLUMN IF EXISTS site_id;
+ALTER TABLE comments DROP COLUMN IF EXISTS site_id;
+DROP TABLE IF EXISTS sites;
+-- +goose StatementEnd
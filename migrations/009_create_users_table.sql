@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    site_id INTEGER NOT NULL REFERENCES sites(id) ON DELETE CASCADE,
+    username VARCHAR(50) NOT NULL,
+    password_hash VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Usernames are unique within a site regardless of case.
+CREATE UNIQUE INDEX idx_users_site_username ON users(site_id, LOWER(username));
+
+-- Sessions of the web interface. Only the hash of the session token is kept.
+CREATE TABLE sessions (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
+
+-- Comments of registered users point to their author. Guest comments keep
+-- only the free-text author name.
+ALTER TABLE comments ADD COLUMN user_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_comments_user_id ON comments(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_comments_user_id;
+ALTER TABLE comments DROP COLUMN IF EXISTS user_id;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
+-- +goose StatementEnd
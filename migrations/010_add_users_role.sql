@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Moderators act on any comment of their site, admins also manage the site
+-- and its users.
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator', 'admin'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+-- +goose StatementEnd
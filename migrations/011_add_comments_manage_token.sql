@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Guests manage their comments with a secret token returned on creation.
+-- Only its hash is kept; NULL means there is no token or it was revoked.
+ALTER TABLE comments ADD COLUMN manage_token_hash CHAR(64);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE comments DROP COLUMN IF EXISTS manage_token_hash;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Comments of sites with pre-moderation start as pending and are shown to
+-- everyone only once a moderator approves them.
+ALTER TABLE comments ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'approved'
+    CHECK (status IN ('pending', 'approved', 'rejected', 'spam'));
+ALTER TABLE comments ADD COLUMN moderated_at TIMESTAMP;
+
+CREATE INDEX idx_comments_site_status_created ON comments(site_id, status, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_comments_site_status_created;
+ALTER TABLE comments DROP COLUMN IF EXISTS moderated_at;
+ALTER TABLE comments DROP COLUMN IF EXISTS status;
+-- +goose StatementEnd
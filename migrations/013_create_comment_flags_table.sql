@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Each reporter flags a comment at most once. Flags stay open until a
+-- moderator decides on the comment or dismisses them.
+CREATE TABLE comment_flags (
+    id SERIAL PRIMARY KEY,
+    comment_id INTEGER NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    reporter VARCHAR(120) NOT NULL,
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('spam', 'abuse', 'off_topic', 'other')),
+    note VARCHAR(500) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP,
+    UNIQUE (comment_id, reporter)
+);
+
+CREATE INDEX idx_comment_flags_open ON comment_flags(comment_id) WHERE resolved_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS comment_flags;
+-- +goose StatementEnd
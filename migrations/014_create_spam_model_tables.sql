@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The naive Bayes spam model of each site: in how many spam and ham
+-- comments every token was seen, and how many comments of each class it was
+-- trained on.
+CREATE TABLE spam_tokens (
+    site_id INTEGER NOT NULL REFERENCES sites(id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL,
+    spam INTEGER NOT NULL DEFAULT 0,
+    ham INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (site_id, token)
+);
+
+CREATE TABLE spam_docs (
+    site_id INTEGER PRIMARY KEY REFERENCES sites(id) ON DELETE CASCADE,
+    spam INTEGER NOT NULL DEFAULT 0,
+    ham INTEGER NOT NULL DEFAULT 0
+);
+
+-- The label and tokens each comment was trained with, so a later decision
+-- on the same comment replaces the earlier one instead of counting twice.
+CREATE TABLE spam_training (
+    comment_id INTEGER PRIMARY KEY REFERENCES comments(id) ON DELETE CASCADE,
+    site_id INTEGER NOT NULL REFERENCES sites(id) ON DELETE CASCADE,
+    is_spam BOOLEAN NOT NULL,
+    tokens TEXT[] NOT NULL,
+    trained_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Duplicate detection looks up the recent comments of an author.
+CREATE INDEX idx_comments_site_user_created ON comments(site_id, user_id, created_at) WHERE user_id IS NOT NULL;
+CREATE INDEX idx_comments_site_author_created ON comments(site_id, author, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_comments_site_author_created;
+DROP INDEX IF EXISTS idx_comments_site_user_created;
+DROP TABLE IF EXISTS spam_training;
+DROP TABLE IF EXISTS spam_docs;
+DROP TABLE IF EXISTS spam_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Comment events are written in the transaction of the change and published
+-- from here afterwards, so an event is never lost nor sent for a change that
+-- was rolled back.
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    site_id INTEGER NOT NULL REFERENCES sites(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_unpublished ON outbox(id) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_published_at ON outbox(published_at) WHERE published_at IS NOT NULL;
+
+-- An event published again is delivered to each webhook only once.
+ALTER TABLE webhook_deliveries ADD COLUMN event_key VARCHAR(100);
+CREATE UNIQUE INDEX idx_webhook_deliveries_event ON webhook_deliveries(webhook_id, event_key);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_webhook_deliveries_event;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS event_key;
+DROP TABLE IF EXISTS outbox;
+-- +goose StatementEnd
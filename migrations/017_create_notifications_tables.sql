@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Notifications of registered users about replies to their comments and
+-- mentions of their username. A comment notifies each user once, so an
+-- event handled again adds nothing.
+CREATE TABLE notifications (
+    id BIGSERIAL PRIMARY KEY,
+    site_id INTEGER NOT NULL REFERENCES sites(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('reply', 'mention')),
+    comment_id INTEGER NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMP,
+    emailed_at TIMESTAMP,
+    UNIQUE (user_id, comment_id)
+);
+
+CREATE INDEX idx_notifications_user ON notifications(user_id, id DESC);
+CREATE INDEX idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+-- Users without a row get the defaults: replies and mentions in the inbox
+-- and no email.
+CREATE TABLE notification_preferences (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    replies BOOLEAN NOT NULL DEFAULT TRUE,
+    mentions BOOLEAN NOT NULL DEFAULT TRUE,
+    email VARCHAR(254) NOT NULL DEFAULT '',
+    email_mode VARCHAR(20) NOT NULL DEFAULT 'off' CHECK (email_mode IN ('off', 'instant', 'digest')),
+    last_emailed_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_notification_preferences_email ON notification_preferences(last_emailed_at) WHERE email_mode <> 'off';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
+-- +goose StatementEnd
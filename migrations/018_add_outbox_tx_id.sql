@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Every instance follows the outbox to feed its live streams. Events are
+-- read in the order their transactions are known to have ended, which IDs
+-- alone do not give: a transaction may commit after a later one.
+ALTER TABLE outbox ADD COLUMN tx_id BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint;
+
+CREATE INDEX idx_outbox_tx_id ON outbox(tx_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_outbox_tx_id;
+ALTER TABLE outbox DROP COLUMN IF EXISTS tx_id;
+-- +goose StatementEnd
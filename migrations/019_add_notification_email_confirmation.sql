@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Notifications are emailed only to an address its user confirmed with the
+-- token sent there, so nobody can have them sent to someone else.
+ALTER TABLE notification_preferences ADD COLUMN email_confirmed BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE notification_preferences ADD COLUMN email_token_hash VARCHAR(64);
+ALTER TABLE notification_preferences ADD COLUMN email_token_sent_at TIMESTAMP;
+
+-- Addresses given before were never confirmed.
+UPDATE notification_preferences SET email_mode = 'off' WHERE email_mode <> 'off';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS email_token_sent_at;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS email_token_hash;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS email_confirmed;
+-- +goose StatementEnd
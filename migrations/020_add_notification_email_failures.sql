@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A failed email is retried with growing delays; email is turned off for a
+-- user whose emails keep failing.
+ALTER TABLE notification_preferences ADD COLUMN email_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE notification_preferences ADD COLUMN email_retry_at TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS email_retry_at;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS email_failures;
+-- +goose StatementEnd
@@ -9,7 +9,8 @@ let state = {
     currentSort: 'created_at',
     currentSortOrder: 'desc',
     selectedParentId: null,
-    commentToDelete: null
+    commentToDelete: null,
+    currentUser: null
 };
 
 const elements = {
@@ -29,13 +30,46 @@ const elements = {
     confirmDeleteBtn: document.getElementById('confirmDeleteBtn'),
     cancelDeleteBtn: document.getElementById('cancelDeleteBtn'),
     authorCounter: document.getElementById('authorCounter'),
-    contentCounter: document.getElementById('contentCounter')
+    contentCounter: document.getElementById('contentCounter'),
+    authForm: document.getElementById('authForm'),
+    authUser: document.getElementById('authUser'),
+    authorGroup: document.getElementById('authorGroup'),
+    usernameInput: document.getElementById('usernameInput'),
+    passwordInput: document.getElementById('passwordInput'),
+    loginBtn: document.getElementById('loginBtn'),
+    registerBtn: document.getElementById('registerBtn'),
+    logoutBtn: document.getElementById('logoutBtn'),
+    currentUsername: document.getElementById('currentUsername')
 };
 
 function init() {
-    loadComments();
+    loadCurrentUser();
     setupEventListeners();
     setupCharCounters();
+    subscribeToComments();
+}
+
+// subscribeToComments reloads the comments when someone else posts, edits or
+// deletes one. The browser reconnects and resumes the stream on its own.
+function subscribeToComments() {
+    if (!window.EventSource) {
+        return;
+    }
+
+    let reloadTimer = null;
+    const reload = () => {
+        // Search results are left alone until the search changes.
+        if (state.searchQuery) {
+            return;
+        }
+        clearTimeout(reloadTimer);
+        reloadTimer = setTimeout(loadComments, 300);
+    };
+
+    const stream = new EventSource(`${API_BASE_URL}/comments/stream`);
+    ['comment.created', 'comment.updated', 'comment.moderated', 'comment.deleted', 'stream.reset'].forEach(type => {
+        stream.addEventListener(type, reload);
+    });
 }
 
 function setupCharCounters() {
@@ -71,6 +105,10 @@ function setupEventListeners() {
     });
     
     elements.submitCommentBtn.addEventListener('click', submitComment);
+
+    elements.loginBtn.addEventListener('click', login);
+    elements.registerBtn.addEventListener('click', register);
+    elements.logoutBtn.addEventListener('click', logout);
     
     elements.prevPageBtn.addEventListener('click', () => {
         if (state.currentPage > 1) {
@@ -175,6 +213,7 @@ function renderComments(comments) {
                         <span><i class="far fa-clock"></i> ${date}</span>
                         <span><i class="fas fa-hashtag"></i> ID: ${comment.id}</span>
                         ${comment.parent_id ? `<span><i class="fas fa-reply"></i> Ответ на #${comment.parent_id}</span>` : ''}
+                        ${comment.status === 'pending' ? `<span class="comment-pending"><i class="fas fa-hourglass-half"></i> На модерации</span>` : ''}
                     </div>
                 </div>
                 <div class="comment-content">
@@ -184,9 +223,12 @@ function renderComments(comments) {
                     <button class="comment-reply" onclick="replyToComment(${comment.id}, '${escapeHtml(comment.author)}')">
                         <i class="fas fa-reply"></i> Ответить
                     </button>
-                    <button class="comment-delete" onclick="showDeleteModal(${comment.id})">
+                    ${!comment.deleted ? `<button class="comment-flag" onclick="flagComment(${comment.id})">
+                        <i class="fas fa-flag"></i> Пожаловаться
+                    </button>` : ''}
+                    ${canDelete(comment) ? `<button class="comment-delete" onclick="showDeleteModal(${comment.id})">
                         <i class="fas fa-trash"></i> Удалить
-                    </button>
+                    </button>` : ''}
                 </div>
         `;
         
@@ -203,8 +245,112 @@ function renderComments(comments) {
     elements.commentsTree.innerHTML = html;
 }
 
+async function loadCurrentUser() {
+    try {
+        const response = await fetch(`${API_BASE_URL}/auth/me`);
+        setCurrentUser(response.ok ? await response.json() : null);
+    } catch (error) {
+        console.error('Error loading current user:', error);
+        setCurrentUser(null);
+    }
+}
+
+// canDelete mirrors the server policy: moderators delete anything, users
+// their own comments and guests the comments they hold a token for. The edit
+// window is left for the server to check.
+function canDelete(comment) {
+    if (comment.deleted) {
+        return false;
+    }
+    if (getManageTokens()[comment.id]) {
+        return true;
+    }
+    const user = state.currentUser;
+    if (!user) {
+        return false;
+    }
+    return user.role === 'moderator' || user.role === 'admin' || comment.user_id === user.id;
+}
+
+// Guest comments are managed with the tokens returned on creation, kept in
+// the browser by comment ID.
+function getManageTokens() {
+    try {
+        return JSON.parse(localStorage.getItem('manageTokens')) || {};
+    } catch (e) {
+        return {};
+    }
+}
+
+function saveManageToken(commentId, token) {
+    const tokens = getManageTokens();
+    tokens[commentId] = token;
+    localStorage.setItem('manageTokens', JSON.stringify(tokens));
+}
+
+function setCurrentUser(user) {
+    state.currentUser = user;
+
+    elements.authForm.style.display = user ? 'none' : 'flex';
+    elements.authUser.style.display = user ? 'flex' : 'none';
+    elements.authorGroup.style.display = user ? 'none' : 'block';
+    elements.currentUsername.textContent = user ? user.username : '';
+
+    loadComments();
+}
+
+async function sendCredentials(path) {
+    const username = elements.usernameInput.value.trim();
+    const password = elements.passwordInput.value;
+
+    if (!username || !password) {
+        throw new Error('Введите имя пользователя и пароль');
+    }
+
+    const response = await fetch(`${API_BASE_URL}/auth/${path}`, {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ username, password })
+    });
+
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+
+    return response.json();
+}
+
+async function login() {
+    try {
+        setCurrentUser(await sendCredentials('login'));
+        elements.passwordInput.value = '';
+        showSuccess(`Добро пожаловать, ${state.currentUser.username}!`);
+    } catch (error) {
+        showError(`Ошибка входа: ${error.message}`);
+    }
+}
+
+async function register() {
+    try {
+        await sendCredentials('register');
+        await login();
+    } catch (error) {
+        showError(`Ошибка регистрации: ${error.message}`);
+    }
+}
+
+async function logout() {
+    try {
+        await fetch(`${API_BASE_URL}/auth/logout`, { method: 'POST' });
+    } finally {
+        setCurrentUser(null);
+    }
+}
+
 async function submitComment() {
-    const author = elements.authorInput.value.trim();
+    const author = state.currentUser ? state.currentUser.username : elements.authorInput.value.trim();
     const content = elements.contentInput.value.trim();
     const parentId = elements.parentIdInput.value.trim();
     
@@ -257,6 +403,11 @@ async function submitComment() {
             }
             throw new Error(errorMessage);
         }
+
+        const createdComment = await response.json();
+        if (createdComment.manage_token) {
+            saveManageToken(createdComment.id, createdComment.manage_token);
+        }
         
         elements.authorInput.value = '';
         elements.contentInput.value = '';
@@ -269,7 +420,9 @@ async function submitComment() {
         state.currentPage = 1;
         await loadComments();
         
-        showSuccess('Комментарий успешно добавлен!');
+        showSuccess(createdComment.status === 'pending'
+            ? 'Комментарий отправлен на модерацию'
+            : 'Комментарий успешно добавлен!');
         
     } catch (error) {
         console.error('Error submitting comment:', error);
@@ -290,6 +443,35 @@ function replyToComment(commentId, authorName) {
     showSuccess(`Вы отвечаете на комментарий #${commentId}`);
 }
 
+async function flagComment(commentId) {
+    if (!confirm(`Пожаловаться на комментарий #${commentId}?`)) return;
+
+    try {
+        const response = await fetch(`${API_BASE_URL}/comments/${commentId}/flags`, {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ reason: 'abuse' })
+        });
+
+        if (response.status === 409) {
+            showSuccess('Вы уже жаловались на этот комментарий');
+            return;
+        }
+        if (!response.ok) {
+            throw new Error(await response.text() || `HTTP error! status: ${response.status}`);
+        }
+
+        showSuccess('Жалоба отправлена модераторам');
+        await loadComments();
+
+    } catch (error) {
+        console.error('Error flagging comment:', error);
+        showError(`Ошибка при отправке жалобы: ${error.message}`);
+    }
+}
+
 function showDeleteModal(commentId) {
     state.commentToDelete = commentId;
     elements.deleteModal.style.display = 'flex';
@@ -302,12 +484,19 @@ async function confirmDelete() {
         elements.confirmDeleteBtn.disabled = true;
         elements.confirmDeleteBtn.innerHTML = '<i class="fas fa-spinner fa-spin"></i> Удаление...';
         
+        const headers = {};
+        const manageToken = getManageTokens()[state.commentToDelete];
+        if (manageToken) {
+            headers['X-Comment-Token'] = manageToken;
+        }
+
         const response = await fetch(`${API_BASE_URL}/comments/${state.commentToDelete}`, {
-            method: 'DELETE'
+            method: 'DELETE',
+            headers: headers
         });
         
         if (!response.ok) {
-            throw new Error(`HTTP error! status: ${response.status}`);
+            throw new Error(await response.text() || `HTTP error! status: ${response.status}`);
         }
         
         elements.deleteModal.style.display = 'none';
@@ -318,7 +507,7 @@ async function confirmDelete() {
         
     } catch (error) {
         console.error('Error deleting comment:', error);
-        showError('Ошибка при удалении комментария');
+        showError(`Ошибка при удалении комментария: ${error.message}`);
     } finally {
         elements.confirmDeleteBtn.disabled = false;
         elements.confirmDeleteBtn.innerHTML = 'Удалить';